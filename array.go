@@ -0,0 +1,144 @@
+package godc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// MaxArrayIndex is the largest index that can be stored into an
+// array. Arrays only hold the entries that have been stored, so a
+// large index costs no more than a small one.
+const MaxArrayIndex = math.MaxInt32
+
+// ErrArrayIndexOutOfRange is returned when an array index is negative
+// or larger than MaxArrayIndex.
+var ErrArrayIndexOutOfRange = fmt.Errorf(`array index out of range`)
+
+// Array is an indexed collection of *Value that belongs to a register,
+// alongside the register's stack. It's sparse: only the entries that
+// have been stored take up any room.
+type Array struct {
+	values map[int]*Value
+}
+
+// Get returns the value stored at index, or nil if nothing has been
+// stored there.
+func (a *Array) Get(index int) *Value {
+	return a.values[index]
+}
+
+// Len returns the number of entries stored in the array.
+func (a *Array) Len() int {
+	return len(a.values)
+}
+
+// Set stores a value at index. Storing nil removes the entry.
+func (a *Array) Set(index int, val *Value) {
+	if val == nil {
+		delete(a.values, index)
+		return
+	}
+	if a.values == nil {
+		a.values = make(map[int]*Value)
+	}
+	a.values[index] = val
+}
+
+// Indexes returns the indexes of the entries stored in the array, in
+// order.
+func (a *Array) Indexes() []int {
+	indexes := make([]int, 0, len(a.values))
+	for index := range a.values {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// ReadArray replaces the named register's array with the numbers read
+// from r, separated by white space, stored from index 0, and returns
+// how many there were. The numbers are read in the input radix, and
+// may start with - as well as _. If one can't be read, the array is
+// left as it was.
+func (i *Interpreter) ReadArray(register rune, r io.Reader) (int, error) {
+	arr := new(Array)
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for index := 0; scanner.Scan(); index++ {
+		if index > MaxArrayIndex {
+			return 0, ErrArrayIndexOutOfRange
+		}
+		if err := parseNumber(i, scanner.Text()); err != nil {
+			return 0, fmt.Errorf(`number %d: %w`, index+1, err)
+		}
+		v := i.Stack.Pop()
+		if err := i.checkValue(v); err != nil {
+			return 0, fmt.Errorf(`number %d: %w`, index+1, err)
+		}
+		arr.Set(index, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	i.Arrays[register] = arr
+	return arr.Len(), nil
+}
+
+// pushArray puts the register's array aside when S adds a level to
+// it, so that, as in GNU dc, the new level starts with an empty array
+// of its own.
+func (i *Interpreter) pushArray(r rune) {
+	if i.arrayLevels == nil {
+		i.arrayLevels = make(map[rune][]*Array)
+	}
+	i.arrayLevels[r] = append(i.arrayLevels[r], i.Arrays[r])
+	delete(i.Arrays, r)
+}
+
+// popArray brings back the array pushArray put aside when L removes
+// the level S added. The array of a level that wasn't added by S goes
+// with it.
+func (i *Interpreter) popArray(r rune) {
+	levels := i.arrayLevels[r]
+	if len(levels) == 0 {
+		delete(i.Arrays, r)
+		return
+	}
+	if arr := levels[len(levels)-1]; arr != nil {
+		i.Arrays[r] = arr
+	} else {
+		delete(i.Arrays, r)
+	}
+	if len(levels) == 1 {
+		delete(i.arrayLevels, r)
+	} else {
+		i.arrayLevels[r] = levels[:len(levels)-1]
+	}
+}
+
+// dropArrayLevels forgets the arrays pushArray put aside, when s
+// leaves the register with only one level.
+func (i *Interpreter) dropArrayLevels(r rune) {
+	delete(i.arrayLevels, r)
+}
+
+// arrayIndex converts a Value to an array index, discarding any
+// fractional part.
+func arrayIndex(val *Value) (int, error) {
+	if err := ensureNumeric(val); err != nil {
+		return 0, err
+	}
+	index := val.Dup()
+	index.IntVal()
+	if !index.numval.Num().IsInt64() {
+		return 0, ErrArrayIndexOutOfRange
+	}
+	n := index.numval.Num().Int64()
+	if n < 0 || n > MaxArrayIndex {
+		return 0, ErrArrayIndexOutOfRange
+	}
+	return int(n), nil
+}
@@ -0,0 +1,66 @@
+package godc
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchResult is what one of EvalAll's programs left behind.
+type BatchResult struct {
+	Stack  []*Value // what was left on the stack, from the bottom up
+	Output string   // what the program printed
+	Err    error    // the error it stopped at, if any
+}
+
+// EvalAll evaluates each program with an interpreter of its own, as
+// many at once as there are CPUs to run them, and returns what each
+// one left, in the same order as the programs. The interpreters are
+// made by NewInterpreter, with nothing to read with ?, and passed to
+// each of the setup functions before their program is evaluated, to
+// set limits or load extensions, say. Once ctx is cancelled, programs
+// that are running stop with its error, and the rest aren't started.
+func EvalAll(ctx context.Context, programs []string, setup ...func(*Interpreter)) []BatchResult {
+	results := make([]BatchResult, len(programs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(programs) {
+		workers = len(programs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range next {
+				results[n] = evalOne(ctx, programs[n], setup)
+			}
+		}()
+	}
+	for n := range programs {
+		if ctx.Err() != nil {
+			results[n].Err = ctx.Err()
+			continue
+		}
+		next <- n
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+func evalOne(ctx context.Context, program string, setup []func(*Interpreter)) BatchResult {
+	i := NewInterpreter()
+	out := new(strings.Builder)
+	i.SetOutput(out)
+	i.SetErrorOutput(io.Discard)
+	i.SetInput(strings.NewReader(``))
+	for _, f := range setup {
+		f(i)
+	}
+	defer i.withContext(ctx)()
+	stack, err := i.EvalString(program)
+	return BatchResult{Stack: stack, Output: out.String(), Err: err}
+}
@@ -0,0 +1,68 @@
+package godc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEvalAll(t *testing.T) {
+	programs := []string{`2 3+`, `[hi]p`, `1 0/`, `?`}
+	for n := 0; n < 100; n++ {
+		// Each uses the hungry commands, which would trip each other
+		// up if interpreters shared them.
+		programs = append(programs, fmt.Sprintf(`%d sa [la 1+ d sa 10>m]sm lmx la{clearreg}a`, n))
+	}
+	results := EvalAll(context.Background(), programs)
+	if len(results) != len(programs) {
+		t.Fatalf(`expected a result for each program; found %d`, len(results))
+	}
+	if r := results[0]; r.Err != nil || len(r.Stack) != 1 || r.Stack[0].String() != `5` {
+		t.Fatalf(`expected 2 3+ to leave 5; found %v, %v`, r.Stack, r.Err)
+	}
+	if r := results[1]; r.Err != nil || r.Output != "hi\n" {
+		t.Fatalf(`expected [hi]p to print hi; found %q, %v`, r.Output, r.Err)
+	}
+	if r := results[2]; !errors.Is(r.Err, ErrDivideByZero) || len(r.Stack) != 2 {
+		t.Fatalf(`expected 1 0/ to fail and leave its values; found %v, %v`, r.Stack, r.Err)
+	}
+	if r := results[3]; r.Err != nil || len(r.Stack) != 0 {
+		t.Fatalf(`expected ? to have nothing to read; found %v, %v`, r.Stack, r.Err)
+	}
+	for n, r := range results[4:] {
+		expected := `10`
+		if n > 9 {
+			expected = fmt.Sprint(n + 1)
+		}
+		if r.Err != nil || len(r.Stack) != 1 || r.Stack[0].String() != expected {
+			t.Fatalf(`expected program %d to leave %s; found %v, %v`, n, expected, r.Stack, r.Err)
+		}
+	}
+
+	t.Run(`the scale is set up`, func(t *testing.T) {
+		results := EvalAll(context.Background(), []string{`1 3/`}, func(i *Interpreter) {
+			i.Scale = 2
+		})
+		if v := results[0].Stack[0]; v.Text(10, 2) != `0.33` {
+			t.Fatalf(`expected 0.33; found %s`, v.Text(10, 2))
+		}
+	})
+
+	t.Run(`cancelled`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := EvalAll(ctx, []string{`[lax]salax`, `1`})
+		for n, r := range results {
+			if !errors.Is(r.Err, context.Canceled) {
+				t.Fatalf(`expected program %d to be stopped; found %v`, n, r.Err)
+			}
+		}
+	})
+
+	t.Run(`no programs`, func(t *testing.T) {
+		if results := EvalAll(context.Background(), nil); len(results) != 0 {
+			t.Fatalf(`expected no results; found %v`, results)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package godc
+
+import (
+	"math/big"
+)
+
+// The bitwise extensions work on whole numbers of any size. Negative
+// numbers behave as if they were written in two's complement with an
+// infinite number of leading ones, as big.Int does.
+
+func makeBitwiseOperation(op func(z, x, y *big.Int) *big.Int) Operation {
+	return makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
+		err := ensureInteger(left, right)
+		if err != nil {
+			return nil, err
+		}
+		result := op(new(big.Int), left.numval.Num(), right.numval.Num())
+		return []*Value{{numval: new(big.Rat).SetInt(result)}}, nil
+	})
+}
+
+// NotOperation implements the {not} extension, which flips every bit.
+var NotOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+	err := ensureInteger(val)
+	if err != nil {
+		return nil, err
+	}
+	result := new(big.Int).Not(val.numval.Num())
+	return []*Value{{numval: new(big.Rat).SetInt(result)}}, nil
+})
+
+// makeShiftOperation builds {shl} and {shr}. They pop a bit count and
+// a number, and push the number shifted by that many bits. A negative
+// count shifts the other way. Shifting right rounds towards negative
+// infinity.
+func makeShiftOperation(left bool) Operation {
+	return makeBinaryOperation(func(i *Interpreter, val, count *Value) ([]*Value, error) {
+		err := ensureInteger(val, count)
+		if err != nil {
+			return nil, err
+		}
+		if !count.numval.Num().IsInt64() {
+			return nil, ErrIntegersOnly
+		}
+		n, shiftLeft := count.numval.Num().Int64(), left
+		if n < 0 {
+			n, shiftLeft = -n, !shiftLeft
+		}
+		if shiftLeft {
+			if err := i.checkGrowth(float64(bits(val)) + float64(n)); err != nil {
+				return nil, err
+			}
+		}
+		result := new(big.Int)
+		if shiftLeft {
+			result.Lsh(val.numval.Num(), uint(n))
+		} else {
+			result.Rsh(val.numval.Num(), uint(n))
+		}
+		return []*Value{{numval: new(big.Rat).SetInt(result)}}, nil
+	})
+}
+
+// BitwiseExtensions do bitwise arithmetic on whole numbers.
+var BitwiseExtensions = ExtensionSet{
+	`and`: makeBitwiseOperation((*big.Int).And),
+	`or`:  makeBitwiseOperation((*big.Int).Or),
+	`xor`: makeBitwiseOperation((*big.Int).Xor),
+	`not`: NotOperation,
+	`shl`: makeShiftOperation(true),
+	`shr`: makeShiftOperation(false),
+}
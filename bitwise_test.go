@@ -0,0 +1,77 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBitwiseExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`and, or and xor`, func(t *testing.T) {
+		test(`12 10{and}`)
+		expect(`8`)
+
+		test(`12 10{or}`)
+		expect(`14`)
+
+		test(`12 10{xor}`)
+		expect(`6`)
+
+		test(`_1 255{and}`)
+		expect(`255`)
+	})
+
+	t.Run(`not`, func(t *testing.T) {
+		test(`0{not}`)
+		expect(`-1`)
+
+		test(`5{not}`)
+		expect(`-6`)
+	})
+
+	t.Run(`shifts`, func(t *testing.T) {
+		test(`1 10{shl}`)
+		expect(`1024`)
+
+		test(`1024 3{shr}`)
+		expect(`128`)
+
+		test(`1024 _3{shl}`)
+		expect(`128`)
+
+		test(`_5 1{shr}`)
+		expect(`-3`)
+
+		test(`3 1{shl}3 1{shr}`)
+		expect(`1`, `6`)
+	})
+
+	t.Run(`fractions are rejected`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `1.5 1{and}`)
+		if !errors.Is(err, ErrIntegersOnly) {
+			t.Fatalf(`expected a whole number error; got %v`, err)
+		}
+		if interpreter.Stack.Len() != 2 {
+			t.Fatalf(`expected the operands to be left on the stack; found %d values`, interpreter.Stack.Len())
+		}
+		buff.Reset()
+	})
+}
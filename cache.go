@@ -0,0 +1,104 @@
+package godc
+
+import (
+	"container/list"
+)
+
+// DefaultMacroCacheSize is the number of compiled macros an
+// interpreter keeps, unless it's changed with SetMacroCacheSize.
+const DefaultMacroCacheSize = 256
+
+// MacroCacheStats describes an interpreter's cache of compiled
+// macros.
+type MacroCacheStats struct {
+	Macros   int   // the number of macros in the cache
+	Capacity int   // the most macros the cache will hold
+	Hits     int64 // the times a macro was found in the cache
+	Misses   int64 // the times a macro had to be compiled
+}
+
+// macroCache keeps the programs of the macros an interpreter has
+// compiled most recently, keyed by their text, so the same macro
+// pushed afresh, rather than copied from a register, isn't compiled
+// again.
+type macroCache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // of *cacheEntry, most recently used first
+	hits     int64
+	misses   int64
+}
+
+type cacheEntry struct {
+	macro string
+	prog  *program
+}
+
+func newMacroCache(capacity int) *macroCache {
+	return &macroCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the program compiled for macro, if it's in the cache.
+func (c *macroCache) get(macro string) *program {
+	e, ok := c.entries[macro]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).prog
+}
+
+// put adds a program to the cache, making room for it by dropping
+// the macro that was used longest ago.
+func (c *macroCache) put(macro string, prog *program) {
+	if e, ok := c.entries[macro]; ok {
+		e.Value.(*cacheEntry).prog = prog
+		c.order.MoveToFront(e)
+		return
+	}
+	if c.capacity <= 0 {
+		return
+	}
+	c.entries[macro] = c.order.PushFront(&cacheEntry{macro: macro, prog: prog})
+	c.trim()
+}
+
+// trim drops the macros used longest ago until the cache fits its
+// capacity.
+func (c *macroCache) trim() {
+	for c.order.Len() > c.capacity && c.order.Len() > 0 {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).macro)
+	}
+}
+
+// MacroCacheStats reports how many compiled macros the interpreter is
+// keeping, and how well the cache is working.
+func (i *Interpreter) MacroCacheStats() MacroCacheStats {
+	return MacroCacheStats{
+		Macros:   i.macros.order.Len(),
+		Capacity: i.macros.capacity,
+		Hits:     i.macros.hits,
+		Misses:   i.macros.misses,
+	}
+}
+
+// SetMacroCacheSize changes the most compiled macros the interpreter
+// keeps, dropping the ones used longest ago if there are too many. A
+// size of 0 turns the cache off, though a macro is still compiled
+// only once while it's copied from register to register.
+func (i *Interpreter) SetMacroCacheSize(n int) {
+	i.macros.capacity = n
+	i.macros.trim()
+}
+
+// FlushMacroCache empties the cache of compiled macros and resets its
+// counts.
+func (i *Interpreter) FlushMacroCache() {
+	i.macros = newMacroCache(i.macros.capacity)
+}
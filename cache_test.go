@@ -0,0 +1,48 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMacroCache(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	stats := func(macros int, hits, misses int64) {
+		actual := interpreter.MacroCacheStats()
+		if actual.Macros != macros || actual.Hits != hits || actual.Misses != misses {
+			t.Fatalf(`expected %d macros, %d hits and %d misses; was %+v`, macros, hits, misses, actual)
+		}
+	}
+
+	stats(0, 0, 0)
+	if interpreter.MacroCacheStats().Capacity != DefaultMacroCacheSize {
+		t.Fatalf(`expected the cache to hold %d macros`, DefaultMacroCacheSize)
+	}
+
+	// The same macro pushed afresh each time is only compiled once.
+	for n := 0; n < 3; n++ {
+		interpreter.EvalString(`[1+]x`)
+	}
+	stats(1, 2, 1)
+
+	// Copies from a register share the program without the cache.
+	interpreter.EvalString(`[2+]sa lax lax`)
+	stats(2, 2, 2)
+
+	interpreter.SetMacroCacheSize(2)
+	interpreter.EvalString(`[3+]x`)
+	stats(2, 2, 3)
+	interpreter.EvalString(`[1+]x`)
+	stats(2, 2, 4)
+
+	interpreter.FlushMacroCache()
+	stats(0, 0, 0)
+	if interpreter.MacroCacheStats().Capacity != 2 {
+		t.Fatalf(`expected flushing the cache to keep its size`)
+	}
+
+	interpreter.SetMacroCacheSize(0)
+	interpreter.EvalString(`[1+]x [1+]x`)
+	stats(0, 0, 2)
+}
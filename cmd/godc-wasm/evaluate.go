@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// evaluate runs source with interpreter, and returns its stack
+// afterwards, as text and top first, and the message of the error that
+// stopped it, or "" if nothing did. Errors that don't stop it go to the
+// interpreter's error output, as usual.
+func evaluate(interpreter *godc.Interpreter, source string) ([]string, string) {
+	var message string
+	if err := interpreter.Run(strings.NewReader(source)); err != nil {
+		message = err.Error()
+	}
+	return stackText(interpreter), message
+}
+
+// stackText returns interpreter's stack, as text and top first.
+func stackText(interpreter *godc.Interpreter) []string {
+	values := interpreter.Stack.Values()
+	texts := make([]string, len(values))
+	for n, v := range values {
+		texts[n] = interpreter.Format(v)
+	}
+	return texts
+}
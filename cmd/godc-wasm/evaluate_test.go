@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestEvaluate(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	errorOutput := new(strings.Builder)
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(errorOutput)
+
+	stack, message := evaluate(interpreter, `2 3+p 7`)
+	if !reflect.DeepEqual(stack, []string{`7`, `5`}) || message != `` {
+		t.Fatalf(`expected the stack, top first, and no error; found %q and %q`, stack, message)
+	}
+	if output.String() != "5\n" {
+		t.Fatalf(`expected 5 to be printed; found %q`, output.String())
+	}
+
+	// The stack is kept from one call to the next, and errors that don't
+	// stop the program are only reported.
+	stack, message = evaluate(interpreter, `+ 1-`)
+	if !reflect.DeepEqual(stack, []string{`11`}) || message != `` {
+		t.Fatalf(`expected the stack to be kept; found %q and %q`, stack, message)
+	}
+	interpreter.Stack.Clear()
+	stack, message = evaluate(interpreter, `+`)
+	if len(stack) != 0 || message != `` || errorOutput.Len() == 0 {
+		t.Fatalf(`expected the error to be reported and not returned; found %q, %q and %q`, stack, message, errorOutput.String())
+	}
+
+	// Errors that stop it are returned.
+	interpreter.StepLimit = interpreter.Steps + 2
+	stack, message = evaluate(interpreter, `1 2 3`)
+	if !reflect.DeepEqual(stack, []string{`2`, `1`}) || message != godc.ErrStepLimitExceeded.Error() {
+		t.Fatalf(`expected the step limit to be returned; found %q and %q`, stack, message)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build js && wasm
+
+// Command godc-wasm runs godc in a browser. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o godc.wasm ./cmd/godc-wasm
+//
+// and load it with the wasm_exec.js that comes with Go. It sets a
+// global godc object, whose newInterpreter function takes callbacks
+// for what's printed and for errors, and returns an interpreter:
+//
+//	const calc = godc.newInterpreter({
+//		output: text => console.log(text),
+//		error: text => console.error(text),
+//	})
+//	calc.eval('2 3+p')   // prints 5, and returns {stack: ['5'], error: null}
+//	calc.stack()         // the stack, top first, without running anything
+//
+// eval returns the stack, top first, and the message of the error that
+// stopped the program, such as a step limit, or null. Errors that don't
+// stop it go to the error callback. Each interpreter has its own stack
+// and registers, kept from one call of eval to the next.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/Unquabain/godc"
+)
+
+// callbackWriter passes what's written to a JavaScript function, as a
+// string.
+type callbackWriter struct {
+	callback js.Value
+}
+
+func (w callbackWriter) Write(p []byte) (int, error) {
+	if w.callback.Type() == js.TypeFunction {
+		w.callback.Invoke(string(p))
+	}
+	return len(p), nil
+}
+
+// newInterpreter implements godc.newInterpreter.
+func newInterpreter(_ js.Value, args []js.Value) interface{} {
+	interpreter := godc.NewInterpreter()
+	interpreter.SetOutput(callbackWriter{})
+	interpreter.SetErrorOutput(callbackWriter{})
+	if len(args) > 0 && args[0].Type() == js.TypeObject {
+		interpreter.SetOutput(callbackWriter{args[0].Get(`output`)})
+		interpreter.SetErrorOutput(callbackWriter{args[0].Get(`error`)})
+	}
+	return js.ValueOf(map[string]interface{}{
+		`eval`: js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+			var source string
+			if len(args) > 0 {
+				source = args[0].String()
+			}
+			stack, message := evaluate(interpreter, source)
+			var err interface{}
+			if message != `` {
+				err = message
+			}
+			return map[string]interface{}{`stack`: jsStrings(stack), `error`: err}
+		}),
+		`stack`: js.FuncOf(func(js.Value, []js.Value) interface{} {
+			return jsStrings(stackText(interpreter))
+		}),
+	})
+}
+
+// jsStrings converts texts to something js.ValueOf takes.
+func jsStrings(texts []string) []interface{} {
+	values := make([]interface{}, len(texts))
+	for n, text := range texts {
+		values[n] = text
+	}
+	return values
+}
+
+func main() {
+	js.Global().Set(`godc`, js.ValueOf(map[string]interface{}{
+		`newInterpreter`: js.FuncOf(newInterpreter),
+	}))
+	// Keep running, so the functions can be called.
+	select {}
+}
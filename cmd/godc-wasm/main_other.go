@@ -0,0 +1,15 @@
+//go:build !js || !wasm
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main only says how to build the command: it needs a browser to run
+// in.
+func main() {
+	fmt.Fprintln(os.Stderr, `godc-wasm only runs in a browser: build it with GOOS=js GOARCH=wasm`)
+	os.Exit(2)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// completer completes what's being typed at the cursor: the name of a
+// register that holds something after a command that takes one, or
+// an extension's name after {. Anywhere else a command could go, it
+// lists the commands. It returns the runes to insert, and the choices
+// to show if there's more than one.
+func completer(interpreter *godc.Interpreter) func(line []rune, pos int) (string, []string) {
+	return func(line []rune, pos int) (string, []string) {
+		takesRegister := make(map[rune]bool)
+		for _, c := range interpreter.Commands() {
+			takesRegister[c.Rune] = c.Register
+		}
+
+		// Read the line up to the cursor, to see what's expected there.
+		const (
+			command = iota
+			register
+			extension
+			str
+			comment
+		)
+		state, level, name := command, 0, []rune(nil)
+		for _, r := range line[:pos] {
+			switch state {
+			case register:
+				state = command
+			case extension:
+				if r == '}' {
+					state = command
+				} else {
+					name = append(name, r)
+				}
+			case str:
+				if r == '[' {
+					level++
+				} else if r == ']' {
+					if level--; level == 0 {
+						state = command
+					}
+				}
+			case comment:
+				if r == '\n' {
+					state = command
+				}
+			case command:
+				switch {
+				case r == '[':
+					state, level = str, 1
+				case r == '#':
+					state = comment
+				case r == '{':
+					state, name = extension, nil
+				case takesRegister[r]:
+					state = register
+				}
+			}
+		}
+
+		switch state {
+		case register:
+			return completeRegister(interpreter)
+		case extension:
+			return completeExtension(interpreter, string(name))
+		case command:
+			var choices []string
+			for _, c := range interpreter.Commands() {
+				choices = append(choices, fmt.Sprintf(`%c  %s`, c.Rune, c.Description))
+			}
+			return ``, choices
+		}
+		return ``, nil
+	}
+}
+
+func completeRegister(interpreter *godc.Interpreter) (string, []string) {
+	names := interpreter.UsedRegisters()
+	if len(names) == 1 {
+		return string(names[0]), nil
+	}
+	var choices []string
+	for _, r := range names {
+		top := `(array)`
+		if v := interpreter.Register(r).Peek(); v != nil {
+			top = abbreviate(v.String(), 40)
+			if v.IsString() {
+				top = `[` + top + `]`
+			}
+		}
+		choices = append(choices, fmt.Sprintf(`%c  %s`, r, top))
+	}
+	return ``, choices
+}
+
+func completeExtension(interpreter *godc.Interpreter, prefix string) (string, []string) {
+	var matches []string
+	for _, name := range interpreter.ExtensionNames() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ``, nil
+	case 1:
+		return matches[0][len(prefix):] + `}`, nil
+	}
+	// Insert as much as all the matches have in common.
+	common := matches[0]
+	for _, name := range matches[1:] {
+		for !strings.HasPrefix(name, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	choices := make([]string, len(matches))
+	for n, name := range matches {
+		choices[n] = `{` + name + `}`
+	}
+	return common[len(prefix):], choices
+}
+
+// abbreviate cuts s short at n runes, and puts it on one line.
+func abbreviate(s string, n int) string {
+	runes := []rune(strings.ReplaceAll(s, "\n", ` `))
+	if len(runes) > n {
+		runes = append(runes[:n-1], '…')
+	}
+	return string(runes)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestCompleter(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	complete := completer(interpreter)
+	expect := func(line, insert string, choices ...string) {
+		actualInsert, actualChoices := complete([]rune(line), len([]rune(line)))
+		if actualInsert != insert {
+			t.Fatalf(`expected %q to complete with %q; found %q`, line, insert, actualInsert)
+		}
+		if len(choices) > 0 && strings.Join(actualChoices, "\n") != strings.Join(choices, "\n") {
+			t.Fatalf(`expected the choices for %q to be %q; found %q`, line, choices, actualChoices)
+		}
+	}
+
+	_, choices := complete(nil, 0)
+	if !strings.Contains(strings.Join(choices, "\n"), "/  divide\n") {
+		t.Fatalf(`expected the commands to be listed; found %q`, choices)
+	}
+
+	expect(`1 [la`, ``, ``)
+	if _, choices := complete([]rune(`[x]sa [1 [2] `), 12); choices != nil {
+		t.Fatalf(`expected no choices inside a string; found %q`, choices)
+	}
+	expect(`{gc`, `d}`)
+	expect(`{fi`, `xed}`)
+	expect(`{ex`, `p`, `{exp}`, `{explain}`)
+	expect(`1 2 {ba`, ``)
+
+	interpreter.EvalString(`[d1-d0<a]sa`)
+	expect(`l`, `a`)
+	expect(`[x]sl 1 2 !<`, `a`)
+	interpreter.EvalString(`12sb 3 0:c`)
+	expect(`l`, ``, `a  [d1-d0<a]`, `b  12`, `c  (array)`)
+	expect(`# note l`, ``, ``)
+	expect("# note\nl", ``, `a  [d1-d0<a]`, `b  12`, `c  (array)`)
+}
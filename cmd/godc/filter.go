@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// filter runs macro once for each line of in, as awk runs its program.
+// The line is split into fields, at runs of white space or at each
+// separator if there is one, and they're pushed onto an empty stack in
+// order, as numbers if they are numbers and as strings if not. Whatever
+// the macro leaves on the stack is then written to out on a line of
+// its own, bottom first, with the separator, or a space, between the
+// values. The registers are kept from line to line, so a macro can
+// keep totals in them. A q in the macro stops at the end of the line.
+func filter(interpreter *godc.Interpreter, macro, separator string, in io.Reader, out io.Writer) error {
+	join := separator
+	if join == `` {
+		join = ` `
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		interpreter.Stack.Clear()
+		var fields []string
+		if separator == `` {
+			fields = strings.Fields(scanner.Text())
+		} else {
+			fields = strings.Split(scanner.Text(), separator)
+		}
+		for _, field := range fields {
+			v, err := godc.NewValueFromDecimalString(field)
+			if err != nil {
+				v = godc.NewValueFromString(field)
+			}
+			interpreter.Stack.Push(v)
+		}
+		if err := interpreter.Run(strings.NewReader(macro)); err != nil {
+			return err
+		}
+		values := interpreter.Stack.Values()
+		if len(values) > 0 {
+			texts := make([]string, len(values))
+			for n, v := range values {
+				texts[len(values)-1-n] = interpreter.Format(v)
+			}
+			fmt.Fprintln(out, strings.Join(texts, join))
+		}
+		if interpreter.Quit() {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestFilter(t *testing.T) {
+	expect := func(setup, macro, separator, input, expected, expectedErrors string) {
+		interpreter := godc.NewInterpreter()
+		errors := new(strings.Builder)
+		interpreter.SetErrorOutput(errors)
+		if _, err := interpreter.EvalString(setup); err != nil {
+			t.Fatal(err)
+		}
+		out := new(strings.Builder)
+		if err := filter(interpreter, macro, separator, strings.NewReader(input), out); err != nil {
+			t.Fatalf(`could not filter %q with %q: %v`, input, macro, err)
+		}
+		if out.String() != expected {
+			t.Fatalf(`expected %q filtered with %q to be %q; found %q`, input, macro, expected, out.String())
+		}
+		if errors.String() != expectedErrors {
+			t.Fatalf(`expected %q filtered with %q to report %q; found %q`, input, macro, expectedErrors, errors.String())
+		}
+	}
+
+	expect(``, `+`, ``, "1 2 3\n  10\t-2\n", "1 5\n8\n", ``)
+	expect(``, `r`, ``, "1 two\n\n", "two 1\n", "error processing command: line 1, column 1: stack too short\n")
+	expect(`0st`, `+ d lt+d st`, `,`, "1,2\n3,4\n", "3,3\n7,10\n", ``)
+	expect(`[q]sq`, `d2=q`, ``, "1\n2\n3\n", "1\n2\n", ``)
+	expect(`2k`, `/`, ``, "1 3\n", "0.33\n", ``)
+}
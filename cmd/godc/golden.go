@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// goldenScripts finds the scripts to test: the .dc files named, and
+// those directly in the directories named.
+func goldenScripts(paths []string) ([]string, error) {
+	var scripts []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			scripts = append(scripts, path)
+			continue
+		}
+		found, err := filepath.Glob(filepath.Join(path, `*.dc`))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		scripts = append(scripts, found...)
+	}
+	return scripts, nil
+}
+
+// expectedFile names the file holding what a script should print.
+func expectedFile(script string) string {
+	return strings.TrimSuffix(script, `.dc`) + `.expected`
+}
+
+// runGolden runs a script with an interpreter of its own, and returns
+// what it printed, with any errors it reported where they happened.
+func runGolden(script string) ([]byte, error) {
+	f, err := os.Open(script)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	interpreter := godc.NewInterpreter()
+	output := new(bytes.Buffer)
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(output)
+	in := bufio.NewReader(f)
+	interpreter.SetInput(in)
+	if err := interpreter.Run(in); err != nil {
+		fmt.Fprintln(output, `error reading command:`, err)
+	}
+	return output.Bytes(), nil
+}
+
+// checkGolden runs a script, and compares what it prints with its
+// .expected file, or replaces the file if update is set. It returns
+// a description of how they differ, if they do.
+func checkGolden(script string, update bool) (string, error) {
+	actual, err := runGolden(script)
+	if err != nil {
+		return ``, err
+	}
+	if update {
+		return ``, os.WriteFile(expectedFile(script), actual, 0666)
+	}
+	expected, err := os.ReadFile(expectedFile(script))
+	if err != nil {
+		return ``, err
+	}
+	if bytes.Equal(actual, expected) {
+		return ``, nil
+	}
+	return fmt.Sprintf("expected:\n%s\nprinted:\n%s", indent(expected), indent(actual)), nil
+}
+
+func indent(b []byte) string {
+	return `    ` + strings.ReplaceAll(strings.TrimSuffix(string(b), "\n"), "\n", "\n    ")
+}
+
+// testCommand implements godc test, which runs scripts and checks what
+// they print against their .expected files, and returns the status to
+// exit with.
+func testCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet(`godc test`, flag.ContinueOnError)
+	flags.SetOutput(out)
+	update := flags.Bool(`update`, false, `write what each script prints to its .expected file, instead of checking it`)
+	flags.Usage = func() {
+		fmt.Fprintln(out, `usage: godc test [-update] [file or directory ...]`)
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, `Runs each .dc script, by default those in testdata, and checks that it prints`)
+		fmt.Fprintln(out, `what the .expected file of the same name holds.`)
+		fmt.Fprintln(out)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{`testdata`}
+	}
+	scripts, err := goldenScripts(paths)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	failed := 0
+	for _, script := range scripts {
+		diff, err := checkGolden(script, *update)
+		switch {
+		case err != nil:
+			fmt.Fprintf(out, "FAIL %s: %v\n", script, err)
+			failed++
+		case diff != ``:
+			fmt.Fprintf(out, "FAIL %s\n%s\n", script, diff)
+			failed++
+		case *update:
+			fmt.Fprintf(out, "updated %s\n", script)
+		default:
+			fmt.Fprintf(out, "ok   %s\n", script)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(out, "%d of %d scripts failed\n", failed, len(scripts))
+		return 1
+	}
+	return 0
+}
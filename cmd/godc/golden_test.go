@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateFlag = flag.Bool(`update`, false, `write what each testdata script prints to its .expected file`)
+
+// TestGolden runs each testdata/*.dc script, and checks it prints what
+// its .expected file holds. Run it with -update to write them afresh.
+func TestGolden(t *testing.T) {
+	scripts, err := goldenScripts([]string{`testdata`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal(`expected scripts in testdata`)
+	}
+	for _, script := range scripts {
+		script := script
+		t.Run(script, func(t *testing.T) {
+			diff, err := checkGolden(script, *updateFlag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != `` {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestTestCommand(t *testing.T) {
+	out := new(strings.Builder)
+	if status := testCommand([]string{`testdata/arithmetic.dc`, `testdata`}, out); status != 0 {
+		t.Fatalf("expected the scripts to pass; found status %d:\n%s", status, out.String())
+	}
+	if !strings.HasPrefix(out.String(), "ok   testdata/arithmetic.dc\nok   testdata/arithmetic.dc\n") {
+		t.Fatalf(`expected each script to be reported; found %q`, out.String())
+	}
+
+	out.Reset()
+	if status := testCommand([]string{`testdata/missing.dc`}, out); status != 2 {
+		t.Fatalf(`expected a missing script to be an error; found status %d: %s`, status, out.String())
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, `sum.dc`)
+	os.WriteFile(script, []byte("2 3+p\n"), 0666)
+	os.WriteFile(filepath.Join(dir, `sum.expected`), []byte("6\n"), 0666)
+	out.Reset()
+	if status := testCommand([]string{dir}, out); status != 1 || !strings.Contains(out.String(), "FAIL "+script) {
+		t.Fatalf(`expected a script printing the wrong thing to fail; found status %d: %s`, status, out.String())
+	}
+	if status := testCommand([]string{`-update`, dir}, out); status != 0 {
+		t.Fatalf(`expected -update to succeed; found status %d: %s`, status, out.String())
+	}
+	if expected, _ := os.ReadFile(filepath.Join(dir, `sum.expected`)); string(expected) != "5\n" {
+		t.Fatalf(`expected -update to write what the script printed; found %q`, expected)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// metaReader passes lines through to the interpreter, except for the
+// meta commands typed at the prompt, such as :help, which it runs
+// itself. Those are whole lines that aren't dc, though dc could read
+// them: :help on its own would store into array h and go on.
+type metaReader struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interpreter *godc.Interpreter
+	history     undoHistory
+	pending     []byte // read, but not yet returned by Read
+}
+
+func newMetaReader(in io.Reader, out io.Writer, interpreter *godc.Interpreter) *metaReader {
+	return &metaReader{in: bufio.NewReader(in), out: out, interpreter: interpreter}
+}
+
+// metaCommands are run by the metaReader, by the line they're typed as.
+var metaCommands = map[string]func(*metaReader){
+	`:help`: (*metaReader).help,
+	`:undo`: (*metaReader).undo,
+	`:redo`: (*metaReader).redo,
+}
+
+// Read implements io.Reader.
+func (m *metaReader) Read(p []byte) (int, error) {
+	for len(m.pending) == 0 {
+		line, err := m.in.ReadString('\n')
+		if command, ok := metaCommands[strings.TrimSpace(line)]; ok {
+			command(m)
+			line = ``
+		} else if strings.TrimSpace(line) != `` {
+			// The interpreter only reads more once it's run the
+			// lines before, so this is how it was before this one.
+			m.history.record(m.interpreter)
+		}
+		m.pending = []byte(line)
+		if len(m.pending) == 0 && err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, m.pending)
+	m.pending = m.pending[n:]
+	return n, nil
+}
+
+func (m *metaReader) help() {
+	writeHelp(m.out, m.interpreter)
+}
+
+// writeHelp lists the commands the interpreter knows, and its
+// extensions.
+func writeHelp(out io.Writer, interpreter *godc.Interpreter) {
+	fmt.Fprintln(out, `commands:`)
+	for _, c := range interpreter.Commands() {
+		description := c.Description
+		if c.Register {
+			description += ` (takes a register)`
+		}
+		fmt.Fprintf(out, "  %c  %s\n", c.Rune, description)
+	}
+	fmt.Fprintln(out, `numbers are typed with the digits 0-9 and A-F, . and _ for a minus sign`)
+	fmt.Fprintln(out, `extensions, typed as {name}:`)
+	line := ``
+	for _, name := range interpreter.ExtensionNames() {
+		if len(line)+len(name)+1 > 76 {
+			fmt.Fprintln(out, ` `+line)
+			line = ``
+		}
+		line += ` ` + name
+	}
+	if line != `` {
+		fmt.Fprintln(out, ` `+line)
+	}
+}
+
+// usage explains how to run godc, for -help.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, `usage: godc [options] [file ...]`)
+	fmt.Fprintln(out, `       godc test [-update] [file or directory ...]`)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, `options:`)
+	flag.PrintDefaults()
+	fmt.Fprintln(out)
+	writeHelp(out, godc.NewInterpreter())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestMetaReader(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	out := new(strings.Builder)
+	m := newMetaReader(strings.NewReader("1 2+p\n :help \n3:help\n:help"), out, interpreter)
+	lines, err := io.ReadAll(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(lines) != "1 2+p\n3:help\n" {
+		t.Fatalf(`expected only :help lines to be taken out; found %q`, lines)
+	}
+	help := out.String()
+	for _, expected := range []string{"commands:\n", "  /  divide\n", "  s  store (takes a register)\n", " gcd ", "{name}"} {
+		if !strings.Contains(help, expected) {
+			t.Fatalf(`expected the help to contain %q; found %q`, expected, help)
+		}
+	}
+	if strings.Count(help, "commands:\n") != 2 {
+		t.Fatalf(`expected the help twice; found %q`, help)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	out := new(strings.Builder)
+	flag.CommandLine.SetOutput(out)
+	defer flag.CommandLine.SetOutput(nil)
+	usage()
+	for _, expected := range []string{"usage: godc [options] [file ...]\n", "  -e expression\n", "  -version\n", "  +  add\n", " gcd "} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf(`expected the usage to contain %q; found %q`, expected, out.String())
+		}
+	}
+	entry := out.String()[strings.Index(out.String(), `-line-length`):]
+	entry = entry[:strings.Index(entry, "\n  -")]
+	if strings.Count(entry, `default`) != 1 {
+		t.Fatalf(`expected -line-length to give its default once; found %q`, entry)
+	}
+
+	defer func(v string) { version = v }(version)
+	version = `v1.2.3`
+	if actual := versionString(); actual != `v1.2.3` {
+		t.Fatalf(`expected the version set when building; found %q`, actual)
+	}
+}
+
+func TestMetaReaderReadLine(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	in := bufio.NewReader(newMetaReader(strings.NewReader("?\n:help\n2 3+\np\n"), new(strings.Builder), interpreter))
+	interpreter.SetInput(in)
+	if err := interpreter.Run(in); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "5\n" {
+		t.Fatalf(`expected '?' to read through the meta commands; found %q`, output.String())
+	}
+}
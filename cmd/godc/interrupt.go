@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Unquabain/godc"
+)
+
+// runInterruptible runs the commands typed at a terminal. An interrupt
+// from signals, as ^C sends, stops whatever's running and goes back to
+// the prompt, leaving the stack and registers as the interrupted
+// command left them, and the rest of its line unread. A second
+// interrupt before the first has stopped it calls exit, so a command
+// that isn't listening can't keep godc from stopping.
+func runInterruptible(interpreter *godc.Interpreter, input io.Reader, signals <-chan os.Signal, exit func()) error {
+	br := bufio.NewReader(input)
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-signals:
+					if ctx.Err() != nil {
+						exit()
+						return
+					}
+					cancel()
+				case <-done:
+					return
+				}
+			}
+		}()
+		err := interpreter.RunContext(ctx, br)
+		close(done)
+		interrupted := ctx.Err() != nil
+		cancel()
+		if !interrupted || err != context.Canceled {
+			return err
+		}
+		fmt.Fprintln(interpreter.ErrorOutput(), `interrupted`)
+		for br.Buffered() > 0 {
+			if b, _ := br.ReadByte(); b == '\n' {
+				break
+			}
+		}
+	}
+}
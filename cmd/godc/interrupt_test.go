@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestRunInterruptible(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output, errors := new(notifyingBuilder), new(strings.Builder)
+	output.written = make(chan bool, 10)
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(errors)
+	r, w := io.Pipe()
+	signals := make(chan os.Signal, 1)
+	exited := make(chan bool, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- runInterruptible(interpreter, r, signals, func() { exited <- true })
+	}()
+
+	// The loop prints until it's filled the output's buffer, so the
+	// test knows it's running.
+	io.WriteString(w, "5sb [[looping]plax]salax 6p\n")
+	<-output.written
+	time.Sleep(10 * time.Millisecond)
+	signals <- os.Interrupt
+	io.WriteString(w, "lbp\n")
+	w.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if len(exited) > 0 {
+		t.Fatal(`expected one interrupt not to exit`)
+	}
+	if errors.String() != "interrupted\n" {
+		t.Fatalf(`expected the loop to be interrupted; found %q`, errors.String())
+	}
+	printed := output.String()
+	if !strings.HasPrefix(printed, "looping\n") || strings.Trim(strings.TrimSuffix(printed, "5\n"), "looping\n") != `` {
+		t.Fatalf(`expected the rest of the line to be dropped, and the stack kept; found %q`, printed)
+	}
+}
+
+// notifyingBuilder tells a test when something's been printed.
+type notifyingBuilder struct {
+	strings.Builder
+	written chan bool
+}
+
+func (b *notifyingBuilder) Write(p []byte) (int, error) {
+	defer func() {
+		select {
+		case b.written <- true:
+		default:
+		}
+	}()
+	return b.Builder.Write(p)
+}
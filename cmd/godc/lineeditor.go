@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the most lines the history file keeps.
+const maxHistory = 1000
+
+// Keys the line editor understands.
+const (
+	keyCtrlA     = 0x01
+	keyCtrlB     = 0x02
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlF     = 0x06
+	keyCtrlG     = 0x07
+	keyCtrlH     = 0x08
+	keyTab       = 0x09
+	keyCtrlK     = 0x0b
+	keyCtrlL     = 0x0c
+	keyEnter     = 0x0d
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlR     = 0x12
+	keyCtrlU     = 0x15
+	keyCtrlW     = 0x17
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+
+	// Keys sent as escape sequences are given runes no one types.
+	keyUp rune = 0x110000 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// lineEditor reads lines from a terminal, letting them be edited
+// before they're given to the interpreter, and keeps a history of
+// them that can be searched. It's an io.Reader of the lines it reads,
+// so the interpreter doesn't need to know about it.
+type lineEditor struct {
+	in          *bufio.Reader
+	out         io.Writer
+	raw         func() (restore func(), err error) // puts the terminal in raw mode
+	history     []string
+	historyFile string
+	pending     []byte // read, but not yet returned by Read
+	// complete returns what to insert at the cursor, and the choices
+	// to show, when tab is pressed.
+	complete func(line []rune, pos int) (string, []string)
+
+	// The line being edited.
+	line []rune
+	pos  int
+	// Where in the history the line came from, or len(history) for a
+	// new line, which is kept in draft while the history is browsed.
+	index int
+	draft []rune
+}
+
+// newLineEditor returns an editor that keeps its history in the file
+// named by $GODC_HISTORY, or ~/.godc_history. Lines are read from the
+// terminal in, which raw puts in raw mode.
+func newLineEditor(in io.Reader, out io.Writer, raw func() (func(), error)) *lineEditor {
+	e := &lineEditor{in: bufio.NewReader(in), out: out, raw: raw}
+	e.historyFile = os.Getenv(`GODC_HISTORY`)
+	if e.historyFile == `` {
+		if home, err := os.UserHomeDir(); err == nil {
+			e.historyFile = filepath.Join(home, `.godc_history`)
+		}
+	}
+	e.loadHistory()
+	return e
+}
+
+// Read implements io.Reader.
+func (e *lineEditor) Read(p []byte) (int, error) {
+	if len(e.pending) == 0 {
+		line, err := e.readLine()
+		if err != nil {
+			return 0, err
+		}
+		e.pending = []byte(line + "\n")
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// readLine reads and edits one line. It returns io.EOF if ^D is typed
+// on an empty line.
+func (e *lineEditor) readLine() (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return ``, err
+	}
+	defer restore()
+	e.line, e.pos, e.index, e.draft = nil, 0, len(e.history), nil
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(e.line) > 0 {
+				break
+			}
+			return ``, err
+		}
+		if key == keyCtrlR {
+			if key, err = e.search(); err != nil {
+				return ``, err
+			}
+		}
+		done, err := e.edit(key)
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			return ``, err
+		}
+		if done {
+			break
+		}
+	}
+	fmt.Fprint(e.out, "\r\n")
+	line := string(e.line)
+	e.remember(line)
+	return line, nil
+}
+
+// edit changes the line for a key. It reports whether the line is
+// finished.
+func (e *lineEditor) edit(key rune) (bool, error) {
+	switch key {
+	case keyEnter, '\n':
+		return true, nil
+	case keyCtrlD:
+		if len(e.line) == 0 {
+			return false, io.EOF
+		}
+		e.delete(e.pos, e.pos+1)
+	case keyCtrlC:
+		// Give up on the line, and start again.
+		fmt.Fprint(e.out, "^C\r\n")
+		e.line, e.pos, e.index = nil, 0, len(e.history)
+	case keyBackspace, keyCtrlH:
+		e.delete(e.pos-1, e.pos)
+	case keyDelete:
+		e.delete(e.pos, e.pos+1)
+	case keyLeft, keyCtrlB:
+		if e.pos > 0 {
+			e.pos--
+		}
+	case keyRight, keyCtrlF:
+		if e.pos < len(e.line) {
+			e.pos++
+		}
+	case keyHome, keyCtrlA:
+		e.pos = 0
+	case keyEnd, keyCtrlE:
+		e.pos = len(e.line)
+	case keyCtrlK:
+		e.delete(e.pos, len(e.line))
+	case keyCtrlU:
+		e.delete(0, e.pos)
+	case keyCtrlW:
+		start := e.pos
+		for start > 0 && e.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.line[start-1] != ' ' {
+			start--
+		}
+		e.delete(start, e.pos)
+	case keyUp, keyCtrlP:
+		e.browse(e.index - 1)
+	case keyDown, keyCtrlN:
+		e.browse(e.index + 1)
+	case keyCtrlL:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+	case keyTab:
+		if e.complete == nil {
+			return false, nil
+		}
+		insert, choices := e.complete(e.line, e.pos)
+		if len(choices) > 0 {
+			fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(choices, "\r\n"))
+		}
+		for _, r := range insert {
+			e.edit(r)
+		}
+	default:
+		if key < ' ' || key >= keyUp {
+			return false, nil
+		}
+		e.line = append(e.line[:e.pos], append([]rune{key}, e.line[e.pos:]...)...)
+		e.pos++
+	}
+	e.refresh()
+	return false, nil
+}
+
+// delete removes the runes from start up to end, as far as there are
+// any.
+func (e *lineEditor) delete(start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(e.line) {
+		end = len(e.line)
+	}
+	if start >= end {
+		return
+	}
+	e.line = append(e.line[:start], e.line[end:]...)
+	e.pos = start
+}
+
+// browse replaces the line with the one at index in the history.
+func (e *lineEditor) browse(index int) {
+	if index < 0 || index > len(e.history) {
+		return
+	}
+	if e.index == len(e.history) {
+		e.draft = e.line
+	}
+	e.index = index
+	if index == len(e.history) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(e.history[index])
+	}
+	e.pos = len(e.line)
+}
+
+// search looks back through the history for lines containing what's
+// typed, as ^R does in a shell. ^R again finds the next older match.
+// Enter, or any key that edits, takes the match as the line and is
+// then handled as usual; ^G or escape go back to the line as it was.
+// It returns the key that ended the search.
+func (e *lineEditor) search() (rune, error) {
+	var query []rune
+	match := e.index
+	show := func() {
+		found := ``
+		if match < len(e.history) {
+			found = e.history[match]
+		}
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), found)
+	}
+	find := func(from int) {
+		for n := from; n >= 0; n-- {
+			if n < len(e.history) && strings.Contains(e.history[n], string(query)) {
+				match = n
+				return
+			}
+		}
+	}
+	show()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == keyCtrlR:
+			find(match - 1)
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = e.index
+				find(match - 1)
+			}
+		case key == keyCtrlG || key == keyEscape:
+			e.refresh()
+			return keyUnknown, nil
+		case key >= ' ' && key < keyUp:
+			query = append(query, key)
+			find(match)
+		default:
+			if match < len(e.history) {
+				e.browse(match)
+			}
+			return key, nil
+		}
+		show()
+	}
+}
+
+// readKey reads a key, turning escape sequences into the keys they
+// stand for.
+func (e *lineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	if e.in.Buffered() == 0 {
+		return keyEscape, nil
+	}
+	if b, _ := e.in.ReadByte(); b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+	var param []byte
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b >= '0' && b <= '9' || b == ';' {
+			param = append(param, b)
+			continue
+		}
+		switch {
+		case b == 'A':
+			return keyUp, nil
+		case b == 'B':
+			return keyDown, nil
+		case b == 'C':
+			return keyRight, nil
+		case b == 'D':
+			return keyLeft, nil
+		case b == 'H', b == '~' && (string(param) == `1` || string(param) == `7`):
+			return keyHome, nil
+		case b == 'F', b == '~' && (string(param) == `4` || string(param) == `8`):
+			return keyEnd, nil
+		case b == '~' && string(param) == `3`:
+			return keyDelete, nil
+		}
+		return keyUnknown, nil
+	}
+}
+
+// refresh redraws the line, and puts the cursor where it belongs.
+func (e *lineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r%s\x1b[K", string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// remember adds a line to the history, unless it's blank or the same
+// as the line before, and appends it to the history file.
+func (e *lineEditor) remember(line string) {
+	if strings.TrimSpace(line) == `` || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+	if e.historyFile == `` {
+		return
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// loadHistory reads the history file, and trims it if it's grown too
+// long.
+func (e *lineEditor) loadHistory() {
+	if e.historyFile == `` {
+		return
+	}
+	f, err := os.Open(e.historyFile)
+	if err != nil {
+		return
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e.history = append(e.history, scanner.Text())
+	}
+	f.Close()
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		if err := os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600); err != nil {
+			debug(`could not trim the history file: `, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineEditor(t *testing.T) {
+	history := filepath.Join(t.TempDir(), `history`)
+	if err := os.WriteFile(history, []byte("1 2+p\n[hello]p\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(`GODC_HISTORY`, history)
+	noRaw := func() (func(), error) { return func() {}, nil }
+	read := func(keys string) string {
+		e := newLineEditor(strings.NewReader(keys), io.Discard, noRaw)
+		lines, err := io.ReadAll(e)
+		if err != nil {
+			t.Fatalf(`could not read %q: %v`, keys, err)
+		}
+		return string(lines)
+	}
+	expect := func(keys, expected string) {
+		if actual := read(keys); actual != expected {
+			t.Fatalf(`expected %q to read as %q; found %q`, keys, expected, actual)
+		}
+	}
+
+	expect("3 4*p\r", "3 4*p\n")
+	expect("34\x1b[D\x1b[D1\x1b[C\x1b[C5\x1b[H0\x1b[F6\r", "013456\n")
+	expect("abc\x7f\x7fd\x01\x1b[3~e\r", "ed\n")
+	expect("one two three\x17\x17\x01x\x05y\x0bz\r", "xone yz\n")
+	expect("abc\x02\x02\x15\x06\x04\r", "b\n")
+	expect("abc\x03def\r", "def\n")
+	expect("\x1b[A\r", "def\n") // the newest line
+	expect("\x10\x10\x10\x0e\r", "b\n")
+	expect("draft\x1b[A\x1b[B\r", "draft\n")
+	expect("\x12hel\r", "[hello]p\n")
+	expect("\x122+\x12\x05 q\r", "1 2+p q\n")
+	expect("keep\x12zzz\x07\r", "keep\n")
+	expect("partial", "partial\n")
+	expect("1p\r\x04", "1p\n")
+
+	saved, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(saved), "\n[hello]p\n3 4*p\n013456\ned\nxone yz\nb\ndef\nb\ndraft\n[hello]p\n1 2+p q\nkeep\npartial\n1p\n") {
+		t.Fatalf(`expected each line to be added to the history; found %q`, saved)
+	}
+}
+
+func TestLineEditorTab(t *testing.T) {
+	t.Setenv(`GODC_HISTORY`, filepath.Join(t.TempDir(), `history`))
+	var out strings.Builder
+	e := newLineEditor(strings.NewReader("1 {gc\t l\t\r"), &out, func() (func(), error) { return func() {}, nil })
+	e.complete = func(line []rune, pos int) (string, []string) {
+		if line[pos-1] == 'l' {
+			return ``, []string{`a  1`, `b  2`}
+		}
+		return `d}`, nil
+	}
+	lines, err := io.ReadAll(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(lines) != "1 {gcd} l\n" {
+		t.Fatalf(`expected tab to complete the line; found %q`, lines)
+	}
+	if !strings.Contains(out.String(), "\r\na  1\r\nb  2\r\n") {
+		t.Fatalf(`expected tab to show the choices; found %q`, out.String())
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"strings"
+)
+
+// listen serves sessions on address, a TCP address, or unix: and the
+// path of a socket. It only returns if the listener fails.
+func listen(address string) error {
+	network := `tcp`
+	if strings.HasPrefix(address, `unix:`) {
+		network, address = `unix`, strings.TrimPrefix(address, `unix:`)
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return serve(l)
+}
+
+// serve accepts connections on l, and runs a session for each, side by
+// side, until l is closed.
+func serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go session(conn)
+	}
+}
+
+// session runs what's sent over conn with an interpreter of its own,
+// set up as one for standard input would be, and sends back what's
+// printed and any errors, until the connection is closed or q is
+// sent.
+func session(conn net.Conn) {
+	defer conn.Close()
+	debug(`session started: `, conn.RemoteAddr())
+	defer debug(`session ended: `, conn.RemoteAddr())
+	interpreter, err := newInterpreter()
+	if err != nil {
+		return
+	}
+	// '?' reads the next line sent, not the server's standard input.
+	in := bufio.NewReader(conn)
+	interpreter.SetInput(in)
+	interpreter.SetOutput(conn)
+	interpreter.SetErrorOutput(conn)
+	if err := runRC(interpreter); err != nil {
+		debug(`error reading the rc file: `, err)
+		return
+	}
+	if err := interpreter.Run(in); err != nil {
+		debug(`session failed: `, err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	t.Setenv(`GODCRC`, ``)
+	t.Setenv(`HOME`, t.TempDir())
+	l, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Skip(`can't listen here: `, err)
+	}
+	defer l.Close()
+	go serve(l)
+
+	dial := func() (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial(`tcp`, l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, bufio.NewReader(conn)
+	}
+	expect := func(r *bufio.Reader, expected string) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != expected {
+			t.Fatalf(`expected %q; found %q`, expected, line)
+		}
+	}
+	a, ar := dial()
+	defer a.Close()
+	b, br := dial()
+	defer b.Close()
+
+	// The sessions run side by side, each with its own stack and
+	// registers.
+	fmt.Fprint(a, "1 2 3sx\n")
+	fmt.Fprint(b, "10sx lxp\n")
+	expect(br, "10\n")
+	fmt.Fprint(a, "+p lxp\n")
+	expect(ar, "3\n")
+	expect(ar, "3\n")
+	fmt.Fprint(b, "+\n")
+	expect(br, "error processing command: line 2, column 1: stack too short\n")
+	fmt.Fprint(a, "q\n")
+	if _, err := ar.ReadByte(); err == nil {
+		t.Fatal(`expected q to end the session`)
+	}
+}
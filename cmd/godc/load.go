@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"plugin"
+	"strings"
+
+	"github.com/Unquabain/godc"
+)
+
+// loadExtensions loads the extension packs named by -load, which are
+// compiled in, and the extensions of the Go plugins named by -plugin.
+// A plugin provides its extensions as an exported variable:
+//
+//	var Extensions = godc.ExtensionSet{`npv`: NPVOperation}
+//
+// or registers packs of them from its init function, to be named by
+// -load like the ones compiled in.
+func loadExtensions(interpreter *godc.Interpreter) error {
+	for _, name := range splitList(*pluginFlag) {
+		p, err := plugin.Open(name)
+		if err != nil {
+			return fmt.Errorf(`could not open the plugin %s: %w`, name, err)
+		}
+		sym, err := p.Lookup(`Extensions`)
+		if err != nil {
+			continue // it may only register packs
+		}
+		set, ok := sym.(*godc.ExtensionSet)
+		if !ok {
+			return fmt.Errorf(`%s: Extensions is a %T, not a godc.ExtensionSet`, name, sym)
+		}
+		interpreter.LoadExtensions(*set)
+	}
+	for _, name := range splitList(*loadFlag) {
+		set, ok := godc.ExtensionPack(name)
+		if !ok {
+			known := strings.Join(godc.ExtensionPackNames(), `, `)
+			if known == `` {
+				known = `none`
+			}
+			return fmt.Errorf(`no extension pack called %q; the packs are: %s`, name, known)
+		}
+		interpreter.LoadExtensions(set)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, leaving out blanks.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, `,`) {
+		if item = strings.TrimSpace(item); item != `` {
+			items = append(items, item)
+		}
+	}
+	return items
+}
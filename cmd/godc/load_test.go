@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestLoadExtensions(t *testing.T) {
+	godc.RegisterExtensionPack(`load-test`, godc.ExtensionSet{`answer`: godc.OperationAdapter(func(i *godc.Interpreter) error {
+		i.Stack.Push(godc.NewValueFromInt64(42))
+		return nil
+	})})
+	defer func(load string) { *loadFlag = load }(*loadFlag)
+
+	*loadFlag = ` load-test, `
+	interpreter := godc.NewInterpreter()
+	if err := loadExtensions(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if stack, err := interpreter.EvalString(`{answer}`); err != nil || len(stack) != 1 {
+		t.Fatalf(`expected the pack to be loaded; found %v, %v`, stack, err)
+	}
+
+	*loadFlag = `load-test,missing`
+	if err := loadExtensions(godc.NewInterpreter()); err == nil {
+		t.Fatal(`expected an error for a pack that isn't registered`)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Unquabain/godc"
+	"github.com/Unquabain/godc/infix"
+)
+
+var Debug *log.Logger = nil
+
+func debug(args ...interface{}) {
+	if Debug == nil {
+		return
+	}
+	Debug.Print(args...)
+}
+
+var (
+	debugFlag      = flag.Bool(`d`, false, `log debugging information to stderr`)
+	editFlag       = flag.Bool(`edit`, true, `edit lines, with a history ($GODC_HISTORY or ~/.godc_history), when standard input is a terminal`)
+	traceFlag      = flag.Bool(`t`, false, `trace each command run, and the stack it leaves, to stderr`)
+	profileFlag    = flag.Bool(`profile`, false, `count and time each command and macro, and print the profile to stderr on exit`)
+	lineLengthFlag = flag.Int(`line-length`, -1, `wrap printed numbers at this many columns, 0 not to wrap, or -1 for $DC_LINE_LENGTH or 70`)
+	roundFlag      = flag.String(`round`, godc.RoundTruncate.String(), `how to round digits past the scale: truncate, half-up or half-even`)
+	notationFlag   = flag.String(`notation`, godc.NotationFixed.String(), `how to print numbers: fixed, scientific or engineering`)
+	digitsFlag     = flag.Int64(`digits`, godc.DefaultSignificantDigits, `significant digits to print in scientific or engineering notation`)
+	bytesFlag      = flag.Bool(`bytes`, false, `read and print strings as raw bytes rather than UTF-8`)
+	mathLibFlag    = flag.Bool(`l`, false, `load the math library and set the scale to 20, like bc -l`)
+	seedFlag       = flag.String(`seed`, ``, `seed {rand} for a reproducible sequence, instead of using a secure source`)
+	restoreFlag    = flag.String(`restore`, ``, `restore the stack, registers and settings saved in this file at startup`)
+	saveFlag       = flag.String(`save`, ``, `save the stack, registers and settings to this file on exit`)
+	persistFlag    = flag.String(`persist`, ``, "keep these `registers`, such as abc, from one session to the next, in $GODC_REGISTERS or ~/.godc_registers")
+	stepFlag       = flag.Bool(`step`, false, `debug: stop before each command, reading debugger commands from the terminal`)
+	breakFlag      = flag.String(`break`, ``, `debug: stop before any of these commands`)
+	breakRegFlag   = flag.String(`break-register`, ``, `debug: stop before a command uses any of these registers`)
+	noColorFlag    = flag.Bool(`no-color`, false, `don't color numbers, strings and errors, even on a terminal (also $NO_COLOR)`)
+	filterFlag     = flag.String(`filter`, ``, "run this `macro` for each line of standard input, with the line's fields on the stack, and print what it leaves")
+	separatorFlag  = flag.String(`separator`, ``, `split lines into fields at this, rather than at white space, for -filter`)
+	loadFlag       = flag.String(`load`, ``, `load these extension packs, separated by commas, from those compiled in or registered by plugins`)
+	pluginFlag     = flag.String(`plugin`, ``, `load extensions from these Go plugins, separated by commas`)
+	listenFlag     = flag.String(`listen`, ``, "serve a session, with an interpreter of its own, to each connection to this `address`, e.g. localhost:7070")
+	transcriptFlag = flag.String(`transcript`, ``, "append what's run, with what it prints as comments, to this `file`, to be read or run again")
+	versionFlag    = flag.Bool(`version`, false, `print the version of godc and exit`)
+	maxBitsFlag    = flag.Int64(`max-bits`, 0, `refuse to make numbers with more than this many bits, or 0 for no limit`)
+	maxStringFlag  = flag.Int64(`max-string`, 0, `refuse to make strings longer than this, or 0 for no limit`)
+	maxDepthFlag   = flag.Int64(`max-depth`, 0, `refuse to let the stack, or a register, hold more than this many values, or 0 for no limit`)
+	infixFlag      = flag.Bool(`infix`, false, `read infix expressions and variables, like bc's, rather than dc commands`)
+	fixedPointFlag = flag.Int64(`fixed-point`, -1, "keep every number at this many `digits` after the point, rounding half to even, as for money")
+	groupFlag      = flag.String(`group`, ``, "write this `separator` between each three digits of whole numbers printed in decimal, and skip it in numbers read")
+	decimalFlag    = flag.String(`decimal`, ``, "print this `separator` for the point in numbers, and read it as one, e.g. a comma")
+	onErrorFlag    = flag.String(`on-error`, godc.ErrorPolicyAbort.String(), `what a failing command does to its macro: abort, or continue like GNU dc`)
+	strictFlag     = flag.Bool(`strict`, false, `accept only dc's own commands, not {name} extensions, and behave as GNU dc does`)
+	quitOneFlag    = flag.Bool(`quit-one-level`, false, `make q leave only the macro it's in, as older versions of godc did, rather than two like dc`)
+)
+
+// lineLength works out the width to wrap numbers to from the
+// command line, falling back to the DC_LINE_LENGTH environment
+// variable the same way GNU dc does.
+func lineLength() int {
+	if *lineLengthFlag >= 0 {
+		return *lineLengthFlag
+	}
+	env, err := strconv.Atoi(os.Getenv(`DC_LINE_LENGTH`))
+	if err != nil || env < 0 || env == 1 {
+		return godc.DefaultLineLength
+	}
+	return env
+}
+
+// envDefaults sets the scale and radixes from the GODC_SCALE,
+// GODC_IBASE and GODC_OBASE environment variables, if they're set.
+func envDefaults(interpreter *godc.Interpreter) error {
+	get := func(name string, min, max int64) (int64, bool, error) {
+		env := os.Getenv(name)
+		if env == `` {
+			return 0, false, nil
+		}
+		n, err := strconv.ParseInt(env, 10, 64)
+		if err != nil || n < min || n > max {
+			return 0, false, fmt.Errorf(`%s must be a whole number from %d to %d, not %q`, name, min, max, env)
+		}
+		return n, true, nil
+	}
+	scale, ok, err := get(`GODC_SCALE`, 0, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	if ok {
+		interpreter.Scale, interpreter.Precision = scale, scale
+	}
+	ibase, ok, err := get(`GODC_IBASE`, 2, godc.MaxInputRadix)
+	if err != nil {
+		return err
+	}
+	if ok {
+		interpreter.InputRadix = uint8(ibase)
+	}
+	obase, ok, err := get(`GODC_OBASE`, 2, godc.MaxOutputRadix)
+	if err != nil {
+		return err
+	}
+	if ok {
+		interpreter.OutputRadix = obase
+	}
+	return nil
+}
+
+// newInterpreter returns an interpreter with the settings given on
+// the command line and in the environment.
+func newInterpreter() (*godc.Interpreter, error) {
+	interpreter := godc.NewInterpreter()
+	interpreter.SetInput(stdin)
+	interpreter.LineLength = lineLength()
+	interpreter.ByteStrings = *bytesFlag
+	interpreter.MaxNumberBits = *maxBitsFlag
+	interpreter.MaxStringLength = *maxStringFlag
+	interpreter.MaxStackDepth = *maxDepthFlag
+	if err := envDefaults(interpreter); err != nil {
+		return nil, err
+	}
+	roundingMode, err := godc.ParseRoundingMode(*roundFlag)
+	if err != nil {
+		return nil, err
+	}
+	interpreter.RoundingMode = roundingMode
+	notation, err := godc.ParseNotation(*notationFlag)
+	if err != nil {
+		return nil, err
+	}
+	interpreter.Notation = notation
+	interpreter.SignificantDigits = *digitsFlag
+	if *fixedPointFlag >= 0 {
+		interpreter.Scale, interpreter.Precision = *fixedPointFlag, *fixedPointFlag
+		interpreter.FixedScale, interpreter.RoundingMode = true, godc.RoundHalfEven
+	}
+	interpreter.GroupSeparator, interpreter.DecimalSeparator = *groupFlag, *decimalFlag
+	errorPolicy, err := godc.ParseErrorPolicy(*onErrorFlag)
+	if err != nil {
+		return nil, err
+	}
+	interpreter.ErrorPolicy = errorPolicy
+	interpreter.QuitOneLevel = *quitOneFlag
+	interpreter.Strict = *strictFlag
+	if *seedFlag != `` {
+		seed, err := strconv.ParseInt(*seedFlag, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid seed: %w`, err)
+		}
+		interpreter.Random = godc.NewSeededRandom(seed)
+	}
+	if *mathLibFlag {
+		if err := interpreter.LoadMathLibrary(); err != nil {
+			return nil, fmt.Errorf(`error loading the math library: %w`, err)
+		}
+		interpreter.Scale, interpreter.Precision = 20, 20
+	}
+	if err := loadExtensions(interpreter); err != nil {
+		return nil, err
+	}
+	return interpreter, nil
+}
+
+func init() {
+	flag.Var(scriptFlag{}, `e`, "run this `expression`; may be repeated, and mixed with -f, to run in order instead of standard input")
+	flag.Var(scriptFlag{}, `expression`, "the same as -e `expression`")
+	flag.Var(scriptFlag{file: true}, `f`, "run the commands in this `file`, or - for standard input; may be repeated, and mixed with -e")
+	flag.Var(scriptFlag{file: true}, `file`, "the same as -f `file`")
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == `test` {
+		os.Exit(testCommand(os.Args[2:], os.Stdout))
+	}
+	flag.Usage = usage
+	flag.Parse()
+	if *versionFlag {
+		fmt.Println(`godc`, versionString())
+		return
+	}
+	if *debugFlag {
+		Debug = log.New(os.Stderr, `debug`, log.LstdFlags)
+	}
+	interpreter, err := newInterpreter()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	interpreter.Trace = *traceFlag
+	if !*noColorFlag && os.Getenv(`NO_COLOR`) == `` {
+		interpreter.Color = isTerminal(os.Stdout)
+		interpreter.ColorErrors = isTerminal(os.Stderr)
+	}
+	if *listenFlag != `` {
+		if err := listen(*listenFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	interpreter.LoadExtensions(fileExtensions())
+	if *transcriptFlag != `` {
+		finish, err := startTranscript(interpreter, *transcriptFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, `error starting the transcript:`, err)
+			os.Exit(2)
+		}
+		defer finish()
+	}
+	if *stepFlag || *breakFlag != `` || *breakRegFlag != `` {
+		tty, err := os.Open(`/dev/tty`)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, `the debugger needs a terminal:`, err)
+			os.Exit(2)
+		}
+		defer tty.Close()
+		debugger := godc.NewDebugger(tty, os.Stderr)
+		debugger.Stepping = *stepFlag
+		for _, r := range *breakFlag {
+			debugger.Breakpoints[r] = true
+		}
+		for _, r := range *breakRegFlag {
+			debugger.RegisterBreakpoints[r] = true
+		}
+		interpreter.Debugger = debugger
+	}
+	if err := runRC(interpreter); err != nil {
+		fmt.Fprintln(os.Stderr, `error reading the rc file:`, err)
+		os.Exit(2)
+	}
+	if *restoreFlag != `` {
+		if err := restore(interpreter, *restoreFlag); err != nil {
+			fmt.Fprintln(os.Stderr, `error restoring state:`, err)
+			os.Exit(2)
+		}
+	}
+	if *persistFlag != `` {
+		if err := loadPersisted(interpreter); err != nil {
+			fmt.Fprintln(os.Stderr, `error loading the registers kept by -persist:`, err)
+			os.Exit(2)
+		}
+	}
+
+	if *profileFlag {
+		interpreter.Profile = godc.NewProfile()
+	}
+	if *infixFlag {
+		infixCompiler = infix.NewCompiler()
+	}
+
+	for _, name := range flag.Args() {
+		scripts = append(scripts, script{text: name, file: true})
+	}
+	if len(scripts) == 0 && *filterFlag == `` {
+		scripts = append(scripts, script{text: `-`, file: true})
+	}
+	if err := runScripts(interpreter); err != nil {
+		if !errors.Is(err, godc.ErrDebuggerQuit) {
+			fmt.Fprintln(os.Stderr, `error reading command:`, err)
+		}
+	} else if *filterFlag != `` && !interpreter.Quit() {
+		if err := filter(interpreter, *filterFlag, *separatorFlag, stdin, os.Stdout); err != nil && !errors.Is(err, godc.ErrDebuggerQuit) {
+			fmt.Fprintln(os.Stderr, `error filtering:`, err)
+		}
+	}
+	if *profileFlag {
+		interpreter.PrintProfile(os.Stderr)
+	}
+	if *saveFlag != `` {
+		if err := save(interpreter, *saveFlag); err != nil {
+			fmt.Fprintln(os.Stderr, `error saving state:`, err)
+			os.Exit(1)
+		}
+	}
+	if *persistFlag != `` {
+		if err := savePersisted(interpreter, *persistFlag); err != nil {
+			fmt.Fprintln(os.Stderr, `error keeping registers for -persist:`, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// restore loads the state saved in a file, as JSON if its name ends
+// in .json. A file that doesn't exist yet is taken as a fresh start,
+// so the same file can be given to -restore and -save from the first
+// session on.
+func restore(interpreter *godc.Interpreter, name string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if isJSON(name) {
+		return interpreter.LoadJSON(bufio.NewReader(f))
+	}
+	return interpreter.Load(bufio.NewReader(f))
+}
+
+// save writes the interpreter's state to a file, as JSON if its name
+// ends in .json, replacing it only once the whole state has been
+// written.
+func save(interpreter *godc.Interpreter, name string) error {
+	write := interpreter.Save
+	if isJSON(name) {
+		write = interpreter.SaveJSON
+	}
+	return replaceFile(name, write)
+}
+
+// replaceFile replaces a file with what write writes, only once it has
+// all been written.
+func replaceFile(name string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+`.*`)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func isJSON(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), `.json`)
+}
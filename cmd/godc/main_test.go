@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	if err := envDefaults(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if interpreter.Scale != 0 || interpreter.InputRadix != 10 || interpreter.OutputRadix != 10 {
+		t.Fatalf(`expected nothing to change without the variables; found %d, %d, %d`, interpreter.Scale, interpreter.InputRadix, interpreter.OutputRadix)
+	}
+	t.Setenv(`GODC_SCALE`, `5`)
+	t.Setenv(`GODC_IBASE`, `16`)
+	t.Setenv(`GODC_OBASE`, `2`)
+	if err := envDefaults(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if interpreter.Scale != 5 || interpreter.Precision != 5 || interpreter.InputRadix != 16 || interpreter.OutputRadix != 2 {
+		t.Fatalf(`expected the scale and radixes to be set; found %d, %d, %d, %d`, interpreter.Scale, interpreter.Precision, interpreter.InputRadix, interpreter.OutputRadix)
+	}
+	for name, value := range map[string]string{`GODC_SCALE`: `-1`, `GODC_IBASE`: `37`, `GODC_OBASE`: `ten`} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if err := envDefaults(godc.NewInterpreter()); err == nil {
+				t.Fatalf(`expected %s=%s to be refused`, name, value)
+			}
+		})
+	}
+}
+
+func TestQuitOneLevel(t *testing.T) {
+	defer func(quitOne bool) { *quitOneFlag = quitOne }(*quitOneFlag)
+	*quitOneFlag = true
+	defer func(r *bufio.Reader) { stdin = r }(stdin)
+	stdin = bufio.NewReader(strings.NewReader("[1p q 2p]x 3p\n"))
+	interpreter, err := newInterpreter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+
+	// As with echo '[1p q 2p]x 3p' | godc -quit-one-level, q in a macro
+	// run from the top level quits, as it used to.
+	defer func() { scripts = nil }()
+	scripts = []script{{text: `-`, file: true}}
+	if err := runScripts(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "1\n"; output.String() != expected {
+		t.Fatalf(`expected %q; found %q`, expected, output.String())
+	}
+}
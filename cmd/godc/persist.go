@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Unquabain/godc"
+)
+
+// persistFile returns the file -persist keeps its registers in:
+// $GODC_REGISTERS, or ~/.godc_registers.
+func persistFile() (string, error) {
+	if name := os.Getenv(`GODC_REGISTERS`); name != `` {
+		return name, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ``, err
+	}
+	return filepath.Join(home, `.godc_registers`), nil
+}
+
+// loadPersisted loads the registers kept by -persist in the last
+// session, if there was one.
+func loadPersisted(interpreter *godc.Interpreter) error {
+	name, err := persistFile()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return interpreter.LoadRegisters(bufio.NewReader(f))
+}
+
+// savePersisted keeps the registers named by -persist for the next
+// session.
+func savePersisted(interpreter *godc.Interpreter, registers string) error {
+	name, err := persistFile()
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, func(w io.Writer) error {
+		return interpreter.SaveRegisters(w, []rune(registers))
+	})
+}
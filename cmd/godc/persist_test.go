@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestPersist(t *testing.T) {
+	t.Setenv(`GODC_REGISTERS`, filepath.Join(t.TempDir(), `registers`))
+	interpreter := godc.NewInterpreter()
+	if err := loadPersisted(interpreter); err != nil {
+		t.Fatalf(`expected a missing file to be a fresh start; found %v`, err)
+	}
+	if _, err := interpreter.EvalString(`[2*]sd 1.5sp 5sx`); err != nil {
+		t.Fatal(err)
+	}
+	if err := savePersisted(interpreter, `dp`); err != nil {
+		t.Fatal(err)
+	}
+
+	interpreter = godc.NewInterpreter()
+	if err := loadPersisted(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	stack, err := interpreter.EvalString(`lp ldx`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack) != 1 || stack[0].String() != `3.0` {
+		t.Fatalf(`expected registers d and p to be kept; found %v`, stack)
+	}
+	if interpreter.Register('x').Len() != 0 {
+		t.Fatal(`expected register x not to be kept`)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Unquabain/godc"
+)
+
+// readArrayOperation implements the {readarray} extension, which only
+// the command-line godc has, as it reads files: it pops a file name,
+// reads the numbers in the file into the array of the register named
+// after it, and pushes how many there were, as in [data.txt]{readarray}a.
+// Like s, it's hungry for the register's name, so each interpreter
+// needs one of its own.
+type readArrayOperation struct {
+	state godc.OperationState
+}
+
+// Operate implements the godc.Operation interface.
+func (ro *readArrayOperation) Operate(i *godc.Interpreter, register rune) (bool, error) {
+	if ro.state == godc.OSNotHungry {
+		ro.state = godc.OSHungry
+		return false, nil
+	}
+	ro.state = godc.OSNotHungry
+	if i.Stack.Len() < 1 {
+		return true, godc.ErrStackTooShort
+	}
+	name := i.Stack.Peek()
+	if name.Type != godc.VTString {
+		return true, godc.ErrValueNotString
+	}
+	f, err := os.Open(name.String())
+	if err != nil {
+		return true, err
+	}
+	defer f.Close()
+	n, err := i.ReadArray(register, f)
+	if err != nil {
+		return true, fmt.Errorf(`%s: %w`, name, err)
+	}
+	i.Stack.Pop()
+	i.Stack.Push(godc.NewValueFromInt64(int64(n)))
+	return true, nil
+}
+
+// fileExtensions are the extensions that use files, which a session
+// served by -listen mustn't have.
+func fileExtensions() godc.ExtensionSet {
+	return godc.ExtensionSet{
+		`readarray`: new(readArrayOperation),
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestReadArray(t *testing.T) {
+	data := filepath.Join(t.TempDir(), `data`)
+	if err := os.WriteFile(data, []byte("3 1.5\n -2 _4\n\n10\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	interpreter := godc.NewInterpreter()
+	interpreter.LoadExtensions(fileExtensions())
+	stack, err := interpreter.EvalString(`[` + data + `]{readarray}a 4;a 2;a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack) != 3 || stack[0].String() != `5` || stack[1].String() != `10` || stack[2].String() != `-2` {
+		t.Fatalf(`expected the count and then a[4] and a[2]; found %v`, stack)
+	}
+
+	interpreter.Stack.Clear()
+	missing := filepath.Join(t.TempDir(), `missing`)
+	if _, err := interpreter.EvalString(`[` + missing + `]{readarray}a`); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf(`expected an error for a missing file; found %v`, err)
+	}
+	if interpreter.Stack.Len() != 1 || interpreter.Array('a').Len() != 5 {
+		t.Fatal(`expected a failed {readarray} to leave the stack and array alone`)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unquabain/godc"
+	"github.com/Unquabain/godc/infix"
+)
+
+// script is something to run: an expression given with -e, or a file
+// given with -f, where - is standard input.
+type script struct {
+	text string
+	file bool
+}
+
+// scripts are the -e and -f arguments, in the order they were given,
+// and then the files named after the flags. As in GNU dc, standard
+// input is read only if there aren't any, or if it's given as -f -,
+// and a q command in one ends them all.
+var scripts []script
+
+// stdin is standard input, buffered once for all the scripts, so '?'
+// reads from it whichever script runs it, and a script read from
+// standard input shares what's been read of it with '?'.
+var stdin = bufio.NewReader(os.Stdin)
+
+// infixCompiler compiles the scripts to dc when they're written in
+// infix, with -infix. It's shared by them all, so a variable set in
+// one can be used in the next.
+var infixCompiler *infix.Compiler
+
+// source returns what's to be run from r: r itself, or the dc compiled
+// from it with -infix.
+func source(r io.Reader) io.Reader {
+	if infixCompiler == nil {
+		return r
+	}
+	return infix.NewReader(r, infixCompiler, os.Stderr)
+}
+
+// scriptFlag adds the -e or -f arguments to scripts as they're parsed,
+// so they keep their order when the two are mixed.
+type scriptFlag struct {
+	file bool
+}
+
+func (f scriptFlag) String() string {
+	return ``
+}
+
+func (f scriptFlag) Set(text string) error {
+	scripts = append(scripts, script{text: text, file: f.file})
+	return nil
+}
+
+// run runs the script. Standard input, when it's a terminal, is
+// edited, can be given meta commands such as :help, and can be
+// interrupted with ^C.
+func (s script) run(interpreter *godc.Interpreter) error {
+	if !s.file {
+		return interpreter.Run(record(source(strings.NewReader(s.text))))
+	}
+	if s.text != `-` {
+		f, err := os.Open(s.text)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return interpreter.Run(record(source(f)))
+	}
+	var input io.Reader = stdin
+	if isTerminal(os.Stdin) {
+		if *editFlag {
+			editor := newLineEditor(stdin, os.Stderr, rawMode(os.Stdin))
+			editor.complete = completer(interpreter)
+			input = editor
+		}
+		input = bufio.NewReader(record(source(newMetaReader(input, os.Stdout, interpreter))))
+		defer readFrom(interpreter, input.(*bufio.Reader))()
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		defer signal.Stop(signals)
+		return runInterruptible(interpreter, input, signals, func() { os.Exit(130) })
+	}
+	br := bufio.NewReader(record(source(input)))
+	defer readFrom(interpreter, br)()
+	return interpreter.Run(br)
+}
+
+// readFrom makes '?' read from br, the commands read from standard
+// input, so it reads the next line of them, and returns a function
+// that makes it read from stdin again.
+func readFrom(interpreter *godc.Interpreter, br *bufio.Reader) func() {
+	interpreter.SetInput(br)
+	return func() { interpreter.SetInput(stdin) }
+}
+
+// runRC runs the file named by $GODCRC, or ~/.godcrc, before anything
+// else, so it can set up the scale, the radixes and favorite macros.
+// It's fine for ~/.godcrc not to exist, but not the file named by
+// $GODCRC.
+func runRC(interpreter *godc.Interpreter) error {
+	name := os.Getenv(`GODCRC`)
+	if name == `` {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		name = filepath.Join(home, `.godcrc`)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	return script{text: name, file: true}.run(interpreter)
+}
+
+// runScripts runs the scripts in order, until one fails or runs q.
+func runScripts(interpreter *godc.Interpreter) error {
+	for _, s := range scripts {
+		if err := s.run(interpreter); err != nil || interpreter.Quit() {
+			return err
+		}
+	}
+	return nil
+}
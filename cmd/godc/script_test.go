@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+	"github.com/Unquabain/godc/infix"
+)
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	rc := filepath.Join(dir, `rc`)
+	if err := os.WriteFile(rc, []byte("3k [2*]sd\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, `file`)
+	if err := os.WriteFile(file, []byte("ldx p\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+
+	t.Setenv(`GODCRC`, rc)
+	if err := runRC(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	flags := []string{`-e`, `1 3/p`, `-f`, file, `-e`, `[done]p`}
+	for n := 0; n < len(flags); n += 2 {
+		if err := (scriptFlag{file: flags[n] == `-f`}).Set(flags[n+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { scripts = nil }()
+	for _, s := range scripts {
+		if err := s.run(interpreter); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := "0.333\n0.666\ndone\n"; output.String() != expected {
+		t.Fatalf(`expected the rc file and then the scripts in order to print %q; found %q`, expected, output.String())
+	}
+
+	t.Setenv(`GODCRC`, filepath.Join(dir, `missing`))
+	if err := runRC(interpreter); err == nil {
+		t.Fatal(`expected an error for a missing $GODCRC`)
+	}
+	t.Setenv(`GODCRC`, ``)
+	t.Setenv(`HOME`, dir)
+	if err := runRC(interpreter); err != nil {
+		t.Fatalf(`expected a missing ~/.godcrc to be ignored; found %v`, err)
+	}
+}
+
+func TestScriptsReadStandardInput(t *testing.T) {
+	defer func(r *bufio.Reader) { stdin = r }(stdin)
+	stdin = bufio.NewReader(strings.NewReader("5\n6\n?\n7\np\n"))
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	interpreter.SetInput(stdin)
+
+	// As with echo 5 | godc -e '?p' -f - -e '?p', '?' reads standard
+	// input in every script, and in the one read from standard input,
+	// reads its next line. That leaves nothing for the last '?'.
+	defer func() { scripts = nil }()
+	scripts = []script{{text: `?p`}, {text: `-`, file: true}, {text: `?p`}}
+	if err := runScripts(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "5\n7\n7\n"; output.String() != expected {
+		t.Fatalf(`expected %q; found %q`, expected, output.String())
+	}
+	if interpreter.Input() != stdin {
+		t.Fatalf(`expected '?' to read from standard input again after the script read from it`)
+	}
+}
+
+func TestInfixScripts(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	infixCompiler = infix.NewCompiler()
+	defer func() { infixCompiler = nil }()
+	for _, s := range []script{{text: `total = 2 * 21`}, {text: `total - 1`}} {
+		if err := s.run(interpreter); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if output.String() != "41\n" {
+		t.Fatalf(`expected variables to be kept from one script to the next; found %q`, output.String())
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal reports whether f is a terminal. Without a way to edit
+// lines on this system, it never is.
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+func rawMode(f *os.File) func() (func(), error) {
+	return func() (func(), error) {
+		return nil, errors.New(`line editing isn't supported on this system`)
+	}
+}
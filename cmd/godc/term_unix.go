@@ -0,0 +1,54 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	t := new(syscall.Termios)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return nil, errno
+	}
+	return t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	_, err := getTermios(f.Fd())
+	return err == nil
+}
+
+// rawMode puts the terminal f in raw mode, so keys are read as they're
+// typed and not echoed, and returns a function that puts it back.
+// Output is still translated, so \n starts a new line.
+func rawMode(f *os.File) func() (func(), error) {
+	return func() (func(), error) {
+		old, err := getTermios(f.Fd())
+		if err != nil {
+			return nil, err
+		}
+		raw := *old
+		raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+			syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+		raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+		raw.Cflag |= syscall.CS8
+		raw.Cc[syscall.VMIN] = 1
+		raw.Cc[syscall.VTIME] = 0
+		if err := setTermios(f.Fd(), &raw); err != nil {
+			return nil, err
+		}
+		return func() { setTermios(f.Fd(), old) }, nil
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/Unquabain/godc"
+)
+
+// sessionTranscript records the session, if -transcript is given.
+var sessionTranscript *transcript
+
+// transcript records what's run, and what it prints as comments, so
+// the record can be read as an audit trail or run again as a script.
+type transcript struct {
+	w       io.Writer
+	midLine bool   // whether the input recorded stops partway through a line
+	queued  []byte // comments waiting for the input to finish its line
+}
+
+func newTranscript(w io.Writer) *transcript {
+	t := &transcript{w: w}
+	fmt.Fprintf(w, "# session started %s\n", time.Now().Format(time.RFC3339))
+	return t
+}
+
+// recordInput adds input that's about to be run.
+func (t *transcript) recordInput(b []byte) {
+	t.w.Write(b)
+	t.midLine = b[len(b)-1] != '\n'
+	if !t.midLine {
+		t.w.Write(t.queued)
+		t.queued = nil
+	}
+}
+
+// endInput finishes the line of an input that's run out, so the next
+// can't run on from it.
+func (t *transcript) endInput() {
+	if t.midLine {
+		t.recordInput([]byte{'\n'})
+	}
+}
+
+// colors match the escape sequences that color output for a terminal.
+var colors = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// comment adds a line of output as a comment, once the input line
+// that printed it has been recorded.
+func (t *transcript) comment(line []byte) {
+	c := append([]byte(`# `), colors.ReplaceAll(line, nil)...)
+	c = append(c, '\n')
+	if t.midLine {
+		t.queued = append(t.queued, c...)
+		return
+	}
+	t.w.Write(c)
+}
+
+// reader returns a reader of r's input that records it a line at a
+// time, so each line's output follows it.
+func (t *transcript) reader(r io.Reader) io.Reader {
+	return &transcriptReader{t: t, in: bufio.NewReader(r)}
+}
+
+type transcriptReader struct {
+	t       *transcript
+	in      *bufio.Reader
+	pending []byte // recorded, but not yet returned by Read
+}
+
+// Read implements io.Reader.
+func (r *transcriptReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		line, err := r.in.ReadBytes('\n')
+		if len(line) == 0 {
+			if err == io.EOF {
+				r.t.endInput()
+			}
+			return 0, err
+		}
+		r.t.recordInput(line)
+		r.pending = line
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// writer returns a writer to w that records what's written to it in
+// the transcript as well.
+func (t *transcript) writer(w io.Writer) *transcriptWriter {
+	return &transcriptWriter{t: t, w: w}
+}
+
+type transcriptWriter struct {
+	t       *transcript
+	w       io.Writer
+	partial []byte // written, but not yet ended with a newline
+}
+
+// Write implements io.Writer.
+func (w *transcriptWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		n := bytes.IndexByte(w.partial, '\n')
+		if n < 0 {
+			break
+		}
+		w.t.comment(w.partial[:n])
+		w.partial = w.partial[n+1:]
+	}
+	return w.w.Write(p)
+}
+
+// Close records any output that hadn't ended its line.
+func (w *transcriptWriter) Close() error {
+	if len(w.partial) > 0 {
+		w.t.comment(w.partial)
+		w.partial = nil
+	}
+	w.t.endInput()
+	return nil
+}
+
+// record returns r, recording it in the transcript if there is one.
+func record(r io.Reader) io.Reader {
+	if sessionTranscript == nil {
+		return r
+	}
+	return sessionTranscript.reader(r)
+}
+
+// startTranscript appends a transcript of the session to the file
+// name, and returns a function to finish it.
+func startTranscript(interpreter *godc.Interpreter, name string) (func() error, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	sessionTranscript = newTranscript(f)
+	output := sessionTranscript.writer(interpreter.Output())
+	errors := sessionTranscript.writer(interpreter.ErrorOutput())
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(errors)
+	return func() error {
+		interpreter.FlushOutput()
+		output.Close()
+		errors.Close()
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestTranscript(t *testing.T) {
+	record := new(strings.Builder)
+	transcript := newTranscript(record)
+	interpreter := godc.NewInterpreter()
+	output, errors := transcript.writer(new(strings.Builder)), transcript.writer(new(strings.Builder))
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(errors)
+	interpreter.Color = true
+	for _, input := range []string{"1 2+p\n[a]n 5\n", `d*p`, `0/ 3n`} {
+		if err := interpreter.Run(transcript.reader(strings.NewReader(input))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output.Close()
+	errors.Close()
+
+	lines := strings.SplitN(record.String(), "\n", 2)
+	if !strings.HasPrefix(lines[0], `# session started `) {
+		t.Fatalf(`expected the transcript to start with when; found %q`, lines[0])
+	}
+	expected := "1 2+p\n# 3\n[a]n 5\nd*p\n# a25\n0/ 3n\n# error processing command: line 1, column 2: divide by zero\n# 3\n"
+	if lines[1] != expected {
+		t.Fatalf("expected the transcript\n%s\nfound\n%s", expected, lines[1])
+	}
+}
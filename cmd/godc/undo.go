@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Unquabain/godc"
+)
+
+// maxUndo is the most lines that :undo can go back through.
+const maxUndo = 100
+
+// undoHistory keeps snapshots of the interpreter from before each line
+// typed at the prompt, so :undo can put it back as it was before a
+// line, and :redo can put the line's work back again.
+type undoHistory struct {
+	undo, redo []*godc.Snapshot
+}
+
+// record takes a snapshot before a line is run. Running a line forgets
+// whatever could have been redone.
+func (h *undoHistory) record(interpreter *godc.Interpreter) {
+	if len(h.undo) == maxUndo {
+		h.undo = h.undo[1:]
+	}
+	h.undo = append(h.undo, interpreter.Snapshot())
+	h.redo = nil
+}
+
+// step pops a snapshot from one list to restore, and pushes one of the
+// interpreter as it is now to the other.
+func step(interpreter *godc.Interpreter, from, to *[]*godc.Snapshot) (bool, error) {
+	if len(*from) == 0 {
+		return false, nil
+	}
+	snapshot := (*from)[len(*from)-1]
+	*to = append(*to, interpreter.Snapshot())
+	*from = (*from)[:len(*from)-1]
+	return true, interpreter.RestoreSnapshot(snapshot)
+}
+
+func (m *metaReader) undo() {
+	if ok, err := step(m.interpreter, &m.history.undo, &m.history.redo); err != nil {
+		fmt.Fprintln(m.out, `error undoing:`, err)
+	} else if !ok {
+		fmt.Fprintln(m.out, `nothing to undo`)
+	}
+}
+
+func (m *metaReader) redo() {
+	if ok, err := step(m.interpreter, &m.history.redo, &m.history.undo); err != nil {
+		fmt.Fprintln(m.out, `error redoing:`, err)
+	} else if !ok {
+		fmt.Fprintln(m.out, `nothing to redo`)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestUndo(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output, messages := new(strings.Builder), new(strings.Builder)
+	interpreter.SetOutput(output)
+	in := newMetaReader(strings.NewReader(strings.Join([]string{
+		`:undo`, `1 2 3`, `4sa`, `c`, `:undo`, `:undo`, `:redo`, `f`, `la p`, `:redo`,
+		`5sa c`, `:undo`, `la p`, ``,
+	}, "\n")), messages, interpreter)
+	if err := interpreter.Run(in); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "3\n2\n1\n4\n4\n"; output.String() != expected {
+		t.Fatalf("expected :undo and :redo to step through the lines; printed\n%s", output.String())
+	}
+	if messages.String() != "nothing to undo\nnothing to redo\n" {
+		t.Fatalf(`expected to be told when there's nothing to undo or redo; found %q`, messages.String())
+	}
+}
+
+func TestUndoStacks(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	in := newMetaReader(strings.NewReader(strings.Join([]string{
+		`1 2[a]{moveto}`, `[a]{stack} c`, `:undo`, `{stackname}n [ ]n p`,
+		`3[a]{moveto} [a]{dropstack}`, `:undo`, `[a]{stack} f`, ``,
+	}, "\n")), new(strings.Builder), interpreter)
+	if err := interpreter.Run(in); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "default 1\n2\n"; output.String() != expected {
+		t.Fatalf("expected :undo to put back the named stacks; printed\n%s", output.String())
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+// runtime/debug is renamed, not to hide the debug logging function.
+import buildinfo "runtime/debug"
+
+// version is set when godc is built for a release, with
+// -ldflags "-X main.version=...". Otherwise it's the version of the
+// module godc was installed from, if it's known.
+var version = ``
+
+func versionString() string {
+	if version != `` {
+		return version
+	}
+	if info, ok := buildinfo.ReadBuildInfo(); ok && info.Main.Version != `` {
+		return info.Main.Version
+	}
+	return `(devel)`
+}
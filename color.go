@@ -0,0 +1,22 @@
+package godc
+
+// ANSI escapes for the colors printed values and errors are shown in.
+const (
+	colorNumber = "\x1b[36m" // cyan
+	colorString = "\x1b[32m" // green
+	colorError  = "\x1b[31m" // red
+	colorReset  = "\x1b[0m"
+)
+
+// colorText formats a value for printing, as text does, and colors
+// it by its type if the interpreter's Color is set.
+func (i *Interpreter) colorText(v *Value) string {
+	str := i.text(v)
+	if !i.Color {
+		return str
+	}
+	if v.Type == VTString {
+		return colorString + str + colorReset
+	}
+	return colorNumber + str + colorReset
+}
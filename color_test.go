@@ -0,0 +1,38 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	interpreter := NewInterpreter()
+	output, errors := new(strings.Builder), new(strings.Builder)
+	interpreter.SetOutput(output)
+	interpreter.SetErrorOutput(errors)
+	run := func(src string) {
+		output.Reset()
+		errors.Reset()
+		if err := interpreter.Run(strings.NewReader(src)); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+	}
+
+	run("1[two]f+")
+	if output.String() != "two\n1\n" || strings.Contains(errors.String(), "\x1b[") {
+		t.Fatalf(`expected no color; found %q and %q`, output.String(), errors.String())
+	}
+
+	interpreter.Color, interpreter.ColorErrors = true, true
+	run("f+")
+	if expected := "\x1b[32mtwo\x1b[0m\n\x1b[36m1\x1b[0m\n"; output.String() != expected {
+		t.Fatalf(`expected %q; found %q`, expected, output.String())
+	}
+	if !strings.HasPrefix(errors.String(), "\x1b[31merror processing command:") || !strings.HasSuffix(errors.String(), "\x1b[0m\n") {
+		t.Fatalf(`expected the error to be red; found %q`, errors.String())
+	}
+	run("n3P")
+	if expected := "\x1b[32mtwo\x1b[0m\x03"; output.String() != expected {
+		t.Fatalf(`expected P not to be colored; found %q`, output.String())
+	}
+}
@@ -0,0 +1,242 @@
+package godc
+
+import "unicode"
+
+// program is a macro compiled for one interpreter. Numbers and
+// strings are read once, when the macro is compiled, and commands are
+// looked up in advance, so a macro that runs many times, like the
+// body of a loop, doesn't have to be read rune by rune every time.
+//
+// How a macro reads depends on the input radix and the commands bound
+// when it was compiled. If either has changed, or a command is still
+// hungry for runes the program didn't expect it to take, the rest of
+// the macro is run rune by rune instead, just as if it had never been
+// compiled.
+type program struct {
+	code    []instruction
+	end     int // where the last instruction ends in the macro
+	owner   *Interpreter
+	radix   uint8
+	version int
+	// The separators numbers were read with.
+	decimal, group string
+	strict         bool // whether numbers were read without exponents
+}
+
+// instruction is one step of a program.
+type instruction struct {
+	// from is where to carry on reading the macro rune by rune if the
+	// instruction can't be used. It includes any runes before the
+	// instruction that aren't commands.
+	from  int
+	at    int // where the instruction is in the macro
+	r     rune
+	op    Operation // a command that takes no more runes
+	value *Value    // a number or string to push
+	// arg is set for a rune that's more input for the command before
+	// it, like the register name after s.
+	arg bool
+}
+
+// macroCode holds a string's compiled program. Copies of the string
+// share it, so a macro loaded from a register over and over is only
+// compiled once.
+type macroCode struct {
+	prog *program
+}
+
+// usable reports whether the program still reads the macro the way
+// the interpreter would.
+func (p *program) usable(i *Interpreter) bool {
+	return p.owner == i && p.radix == i.InputRadix && p.version == i.version &&
+		p.decimal == i.DecimalSeparator && p.group == i.GroupSeparator && p.strict == i.Strict
+}
+
+// run carries out the instruction.
+func (in *instruction) run(i *Interpreter) error {
+	switch {
+	case in.value != nil:
+		if err := i.begin(in.r); err != nil {
+			return err
+		}
+		i.Stack.Push(in.value.Dup())
+		if i.FixedScale {
+			i.fixScale()
+		}
+		if i.limited() {
+			if err := i.checkResult(1); err != nil {
+				return i.recordError(err)
+			}
+		}
+		if i.Trace {
+			i.trace()
+		}
+		if len(i.hooks) > 0 {
+			return i.afterHooks(in.r, nil)
+		}
+		return nil
+	case in.op != nil:
+		if err := i.begin(in.r); err != nil {
+			return err
+		}
+		return i.operate(in.op, in.r)
+	}
+	return i.interpret(in.r)
+}
+
+// compiled returns v's program, compiling it if it hasn't been
+// compiled for the interpreter as it is now, and can't be found in the
+// interpreter's cache.
+func (i *Interpreter) compiled(v *Value) *program {
+	if v.code == nil {
+		v.code = new(macroCode)
+	}
+	if p := v.code.prog; p != nil && p.usable(i) {
+		return p
+	}
+	macro := string(v.strval)
+	if p := i.macros.get(macro); p != nil && p.usable(i) {
+		i.macros.hits++
+		v.code.prog = p
+		return p
+	}
+	i.macros.misses++
+	v.code.prog = i.compile(v.strval)
+	i.macros.put(macro, v.code.prog)
+	return v.code.prog
+}
+
+// compile reads a macro into a program. Commands that take more runes
+// than their own are left as single runes to interpret, with the
+// runes they take marked as arguments.
+func (i *Interpreter) compile(macro []rune) *program {
+	p := &program{owner: i, radix: i.InputRadix, version: i.version, decimal: i.DecimalSeparator, group: i.GroupSeparator, strict: i.Strict}
+	pos, from := 0, 0
+	emit := func(in instruction) {
+		in.at = in.from
+		if !in.arg {
+			in.at, in.from = pos, from
+		}
+		p.code = append(p.code, in)
+	}
+	// args marks the runes from start up to end as more input for the
+	// command before them.
+	args := func(start, end int) {
+		for n := start; n < end && n < len(macro); n++ {
+			emit(instruction{from: n, r: macro[n], arg: true})
+		}
+	}
+	// through finds the end of the runes up to and including stop.
+	through := func(start int, stop rune) int {
+		for n := start; n < len(macro); n++ {
+			if macro[n] == stop {
+				return n + 1
+			}
+		}
+		return len(macro)
+	}
+
+	for pos < len(macro) {
+		r := macro[pos]
+		op, ok := i.commandFor(r)
+		if !ok {
+			if i.Strict && !unicode.IsSpace(r) {
+				emit(instruction{r: r}) // to be reported when it's run
+			}
+			pos++
+			continue
+		}
+		next := pos + 1
+		switch op.(type) {
+		case OperationAdapter:
+			emit(instruction{r: r, op: op})
+		case *NumberBuilder:
+			next = i.compileNumber(macro, pos, emit, args)
+		case *StringBuilder:
+			next = compileString(macro, pos, emit, args)
+		case *RegisterOperation, *ArrayOperation, *MacroOperation:
+			emit(instruction{r: r})
+			next = pos + 2
+			args(pos+1, next)
+		case *NegativeMacroOperation:
+			emit(instruction{r: r})
+			next = pos + 2
+			if pos+1 < len(macro) && isComparison(macro[pos+1]) {
+				next++ // and the register
+			}
+			args(pos+1, next)
+		case *ExtensionDispatcher:
+			emit(instruction{r: r})
+			next = through(pos+1, '}')
+			args(pos+1, next)
+		case CommentOperatorType:
+			emit(instruction{r: r})
+			next = through(pos+1, '\n')
+			args(pos+1, next)
+		default:
+			emit(instruction{r: r})
+		}
+		if next > len(macro) {
+			next = len(macro)
+		}
+		pos, from = next, next
+	}
+	p.end = from
+	return p
+}
+
+func isComparison(r rune) bool {
+	return r == '<' || r == '>' || r == '='
+}
+
+// compileNumber reads the number starting at pos with a NumberBuilder
+// of its own, and returns where it ends. A number that can't be read
+// is left to fail when it's run.
+func (i *Interpreter) compileNumber(macro []rune, pos int, emit func(instruction), args func(int, int)) int {
+	scratch := &Interpreter{Stack: new(Stack), InputRadix: i.InputRadix, DecimalSeparator: i.DecimalSeparator, GroupSeparator: i.GroupSeparator, Strict: i.Strict}
+	builder := NewNumberBuilder()
+	end := pos
+	var err error
+	for ; end < len(macro); end++ {
+		var finished bool
+		finished, err = builder.Operate(scratch, macro[end])
+		if finished {
+			break
+		}
+	}
+	if end == len(macro) {
+		err = builder.Flush(scratch)
+	}
+	if err != nil && err != ErrContinueProcessingRune {
+		// The rune that ended the number is taken by the error.
+		emit(instruction{r: macro[pos]})
+		args(pos+1, end+1)
+		return end + 1
+	}
+	emit(instruction{r: macro[pos], value: scratch.Stack.Pop()})
+	return end
+}
+
+// compileString reads the string starting with the '[' at pos, and
+// returns where it ends.
+func compileString(macro []rune, pos int, emit func(instruction), args func(int, int)) int {
+	level := 0
+	for end := pos + 1; end < len(macro); end++ {
+		switch macro[end] {
+		case '[':
+			level++
+		case ']':
+			if level == 0 {
+				str := make([]rune, end-pos-1)
+				copy(str, macro[pos+1:end])
+				emit(instruction{r: '[', value: &Value{Type: VTString, strval: str, code: new(macroCode)}})
+				return end + 1
+			}
+			level--
+		}
+	}
+	// The string isn't finished, so the rest of the macro is in it.
+	emit(instruction{r: '['})
+	args(pos+1, len(macro))
+	return len(macro)
+}
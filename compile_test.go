@@ -0,0 +1,110 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+// hungryOperation takes the rune after it and pushes it as a string,
+// like a command added by an embedder that the compiler can't know
+// about.
+type hungryOperation struct {
+	hungry bool
+}
+
+func (h *hungryOperation) Operate(i *Interpreter, r rune) (bool, error) {
+	if !h.hungry {
+		h.hungry = true
+		return false, nil
+	}
+	h.hungry = false
+	i.Stack.Push(NewValueFromString(string(r)))
+	return true, nil
+}
+
+func TestCompiledMacros(t *testing.T) {
+	// run runs prog compiled and rune by rune, and checks they do the
+	// same thing.
+	run := func(prog string) (string, []*Value) {
+		outputs := make([]string, 2)
+		stacks := make([][]*Value, 2)
+		steps := make([]int64, 2)
+		for n := range outputs {
+			interpreter := NewInterpreter()
+			interpreter.RegisterOperation('T', new(hungryOperation))
+			buff := new(strings.Builder)
+			interpreter.SetOutput(buff)
+			if n == 0 {
+				interpreter.Stack.Push(NewValueFromString(prog))
+				interpreter.Interpret('x')
+			} else {
+				interpreter.InterpretMacro([]rune(prog))
+			}
+			outputs[n], stacks[n], steps[n] = buff.String(), interpreter.Stack.Values(), interpreter.Steps
+		}
+		if outputs[0] != outputs[1] {
+			t.Fatalf(`expected %q to print %q compiled; printed %q`, prog, outputs[1], outputs[0])
+		}
+		if len(stacks[0]) != len(stacks[1]) {
+			t.Fatalf(`expected %q to leave %v compiled; left %v`, prog, stacks[1], stacks[0])
+		}
+		for n := range stacks[0] {
+			if stacks[0][n].String() != stacks[1][n].String() {
+				t.Fatalf(`expected %q to leave %v compiled; left %v`, prog, stacks[1], stacks[0])
+			}
+		}
+		if steps[0] != steps[1]+1 {
+			t.Fatalf(`expected %q to take %d steps compiled; took %d`, prog, steps[1]+1, steps[0])
+		}
+		return outputs[0], stacks[0]
+	}
+
+	expect := func(prog, output string) {
+		if actual, _ := run(prog); actual != output {
+			t.Fatalf(`expected %q to print %q; printed %q`, prog, output, actual)
+		}
+	}
+
+	expect(`2 3+p`, "5\n")
+	expect(`1.5e2 _.25 *p`, "-37\n")
+	expect(`[hello [world]]p`, "hello [world]\n")
+	expect(`[5]s1 l1p`, "5\n")
+	expect(`3 4!<a 5 6!>[p]`, ``)
+	expect(`1 2 3:a 3;ap`, "2\n")
+	expect(`4 2{gcd}p # a comment [ 5p
+6p`, "2\n6\n")
+	expect(`16i FF p Ai`, "255\n")
+	expect(`20i GG p Ai`, "336\n")
+	expect(`T5 p`, "5\n")
+	expect(`1 2 3F 4p`, ``)
+	expect(`[3p]sa 1 2<a 2 1<a`, "3\n")
+	expect(`5[d1-d0<a]sa lax f`, "0\n1\n2\n3\n4\n5\n")
+	expect(`[unfinished`, ``)
+	expect(`12 34`, ``)
+	expect(`1 s`, ``)
+	expect(`20i 1 G`, ``)
+}
+
+func TestCompiledMacroCache(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	if _, err := interpreter.EvalString(`[1+]sa 0 lax lax`); err != nil {
+		t.Fatal(err)
+	}
+	macro := interpreter.Register('a').Peek()
+	if macro.code == nil || macro.code.prog == nil {
+		t.Fatalf(`expected the macro in a to have been compiled`)
+	}
+	prog := macro.code.prog
+	interpreter.EvalString(`lax`)
+	if macro.code.prog != prog {
+		t.Fatalf(`expected the macro to be compiled once`)
+	}
+	interpreter.EvalString(`16i lax Ai`)
+	if macro.code.prog == prog {
+		t.Fatalf(`expected a new input radix to compile the macro again`)
+	}
+	if top := interpreter.Stack.Peek(); top.String() != `4` {
+		t.Fatalf(`expected 4 on top of the stack; was %v`, top)
+	}
+}
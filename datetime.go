@@ -0,0 +1,103 @@
+package godc
+
+import (
+	"math/big"
+	"time"
+)
+
+// secondsPerDay is the length of a UTC day, ignoring leap seconds the
+// same way Unix time does.
+const secondsPerDay = 86400
+
+// timeNow is replaced in tests so {now} is predictable.
+var timeNow = time.Now
+
+func newInt64Value(i int64) *Value {
+	return &Value{numval: big.NewRat(i, 1)}
+}
+
+// NowOperation implements the {now} extension, which pushes the
+// current Unix timestamp in seconds, with as many fractional digits as
+// the scale allows, so the time a script takes can be measured.
+var NowOperation = OperationAdapter(func(i *Interpreter) error {
+	t := timeNow()
+	now := &Value{numval: new(big.Rat).Add(
+		big.NewRat(t.Unix(), 1),
+		big.NewRat(int64(t.Nanosecond()), int64(time.Second)),
+	)}
+	now.Truncate(i.Scale)
+	i.Stack.Push(now)
+	return nil
+})
+
+// DateOperation implements the {date} extension. It pops a Unix
+// timestamp and pushes its UTC year, month, day, hour, minute and
+// second, leaving the second on top.
+var DateOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+	if err := ensureNumeric(val); err != nil {
+		return nil, err
+	}
+	t := time.Unix(val.Dup().Int(), 0).UTC()
+	return []*Value{
+		newInt64Value(int64(t.Year())),
+		newInt64Value(int64(t.Month())),
+		newInt64Value(int64(t.Day())),
+		newInt64Value(int64(t.Hour())),
+		newInt64Value(int64(t.Minute())),
+		newInt64Value(int64(t.Second())),
+	}, nil
+})
+
+// TimestampOperation implements the {timestamp} extension, the
+// reverse of {date}. It pops a UTC year, month, day, hour, minute and
+// second and pushes the Unix timestamp. Out-of-range components are
+// normalized, so adding 30 to the day before calling {timestamp}
+// moves the date forward by 30 days.
+var TimestampOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 6 {
+		return ErrStackTooShort
+	}
+	components := make([]*Value, 6)
+	for c := len(components) - 1; c >= 0; c-- {
+		components[c] = i.Stack.Pop()
+	}
+	if err := ensureNumeric(components...); err != nil {
+		for _, c := range components {
+			i.Stack.Push(c)
+		}
+		return err
+	}
+	ints := make([]int, len(components))
+	for c, val := range components {
+		ints[c] = int(val.Int())
+	}
+	t := time.Date(ints[0], time.Month(ints[1]), ints[2], ints[3], ints[4], ints[5], 0, time.UTC)
+	i.Stack.Push(newInt64Value(t.Unix()))
+	return nil
+})
+
+// DaysOperation implements the {days} extension. It pops two Unix
+// timestamps and pushes the number of days from the first to the
+// second.
+var DaysOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
+	err := ensureNumeric(left, right)
+	if err != nil {
+		return nil, err
+	}
+	days := right.Dup()
+	if err = days.Subtract(left); err != nil {
+		return nil, err
+	}
+	if err = days.Divide(newInt64Value(secondsPerDay)); err != nil {
+		return nil, err
+	}
+	return []*Value{days}, nil
+})
+
+// DateTimeExtensions do date and time arithmetic on Unix timestamps.
+var DateTimeExtensions = ExtensionSet{
+	`now`:       NowOperation,
+	`date`:      DateOperation,
+	`timestamp`: TimestampOperation,
+	`days`:      DaysOperation,
+}
@@ -0,0 +1,68 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateTimeExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	defer func(orig func() time.Time) { timeNow = orig }(timeNow)
+	timeNow = func() time.Time {
+		return time.Date(2021, time.October, 31, 12, 30, 15, 254000000, time.UTC)
+	}
+
+	t.Run(`now`, func(t *testing.T) {
+		test(`0k{now}`)
+		expect(`1635683415`)
+	})
+
+	t.Run(`now with a scale`, func(t *testing.T) {
+		test(`2k{now}`)
+		expect(`1635683415.25`)
+		test(`12k{now}`)
+		expect(`1635683415.254000000000`)
+		test(`0k`)
+	})
+
+	t.Run(`timestamp to date`, func(t *testing.T) {
+		test(`0k{now}{date}`)
+		expect(`15`, `30`, `12`, `31`, `10`, `2021`)
+	})
+
+	t.Run(`date to timestamp`, func(t *testing.T) {
+		test(`0k2021 10 31 12 30 15{timestamp}`)
+		expect(`1635683415`)
+	})
+
+	t.Run(`out of range dates are normalized`, func(t *testing.T) {
+		test(`0k2021 12 32 0 0 0{timestamp}{date}`)
+		expect(`0`, `0`, `0`, `1`, `1`, `2022`)
+	})
+
+	t.Run(`day differences`, func(t *testing.T) {
+		test(`0k2000 1 1 0 0 0{timestamp}2021 1 1 0 0 0{timestamp}{days}`)
+		expect(`7671`)
+
+		test(`1k0 129600{days}`)
+		expect(`1.5`)
+	})
+}
@@ -0,0 +1,173 @@
+package godc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrDebuggerQuit is returned when the program is stopped from the
+// debugger.
+var ErrDebuggerQuit = fmt.Errorf(`quit from the debugger`)
+
+// Debugger stops an interpreter before the commands and registers it's
+// told to watch, or before every command when it's stepping, shows the
+// stack, and waits to be told what to do next. Set an interpreter's
+// Debugger to use it.
+type Debugger struct {
+	Breakpoints         map[rune]bool // commands to stop before
+	RegisterBreakpoints map[rune]bool // registers to stop before a command uses
+	Stepping            bool          // whether to stop before every command
+	in                  *bufio.Reader
+	out                 io.Writer
+	stopped             int64 // the step it last stopped at
+	quit                bool
+}
+
+// NewDebugger returns a debugger with no breakpoints that stops before
+// the first command. It reads its own commands from in, which
+// shouldn't be where the program is read from, and writes to out.
+func NewDebugger(in io.Reader, out io.Writer) *Debugger {
+	br, ok := in.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(in)
+	}
+	return &Debugger{
+		Breakpoints:         make(map[rune]bool),
+		RegisterBreakpoints: make(map[rune]bool),
+		Stepping:            true,
+		in:                  br,
+		out:                 out,
+	}
+}
+
+const debuggerHelp = `s, or an empty line  run the next command, and stop
+c                    continue to the next breakpoint
+b X                  stop before the command X, or stop no longer
+r X                  stop before a command uses register X, or stop no longer
+l                    list the breakpoints
+f                    print the stack
+q                    stop the program
+`
+
+// beforeCommand is called as each command is about to run.
+func (d *Debugger) beforeCommand(i *Interpreter) error {
+	if d.quit {
+		d.quit = false
+		return ErrDebuggerQuit
+	}
+	if !d.Stepping && !d.Breakpoints[i.command] {
+		return nil
+	}
+	d.stop(i, ``)
+	if d.quit {
+		d.quit = false
+		return ErrDebuggerQuit
+	}
+	return nil
+}
+
+// usingRegister is called as a command is about to use a register.
+// If the program is stopped here, it ends before the next command.
+func (d *Debugger) usingRegister(i *Interpreter, r rune) {
+	if !d.RegisterBreakpoints[r] || d.stopped == i.Steps {
+		return
+	}
+	d.stop(i, fmt.Sprintf(`, using register %q`, r))
+}
+
+// stop shows where the interpreter is and what's on its stack, and
+// reads commands until it's told to go on.
+func (d *Debugger) stop(i *Interpreter, why string) {
+	d.stopped = i.Steps
+	i.out.Flush() // show what the program has printed so far
+	out := d.out
+	fmt.Fprintf(out, "stopped at %q (%s)%s, %s\n", i.command, i.operationName(), why, i.position())
+	d.printStack(i, out)
+	for {
+		fmt.Fprint(out, `(debug) `)
+		line, err := d.in.ReadString('\n')
+		if line == `` && err != nil {
+			// Nothing more to be told, so let the program finish.
+			fmt.Fprintln(out)
+			d.Stepping = false
+			d.Breakpoints = nil
+			d.RegisterBreakpoints = nil
+			return
+		}
+		command, arg := strings.TrimSpace(line), ``
+		if n := strings.IndexAny(command, " \t"); n >= 0 {
+			command, arg = command[:n], strings.TrimSpace(command[n:])
+		}
+		switch command {
+		case ``, `s`:
+			d.Stepping = true
+			return
+		case `c`:
+			d.Stepping = false
+			return
+		case `b`, `r`:
+			r, size := utf8.DecodeRuneInString(arg)
+			if size == 0 || size != len(arg) {
+				fmt.Fprintf(out, "%s needs a single character\n", command)
+				continue
+			}
+			breakpoints := &d.Breakpoints
+			if command == `r` {
+				breakpoints = &d.RegisterBreakpoints
+			}
+			if *breakpoints == nil {
+				*breakpoints = make(map[rune]bool)
+			}
+			if (*breakpoints)[r] {
+				delete(*breakpoints, r)
+			} else {
+				(*breakpoints)[r] = true
+			}
+		case `l`:
+			fmt.Fprintf(out, "commands: %s\nregisters: %s\n", listRunes(d.Breakpoints), listRunes(d.RegisterBreakpoints))
+		case `f`:
+			d.printStack(i, out)
+		case `q`:
+			d.quit = true
+			return
+		case `h`, `?`:
+			fmt.Fprint(out, debuggerHelp)
+		default:
+			fmt.Fprintf(out, "unknown debugger command %q; h for help\n", command)
+		}
+	}
+}
+
+func (d *Debugger) printStack(i *Interpreter, out io.Writer) {
+	if i.Stack.Len() == 0 {
+		fmt.Fprintln(out, `stack empty`)
+		return
+	}
+	i.Stack.Each(func(depth int, v *Value) bool {
+		fmt.Fprintf(out, "%d: %s\n", depth, describeValue(v, i.OutputRadix, i.Precision))
+		return true
+	})
+}
+
+// listRunes lists the runes set in a map, in order.
+func listRunes(set map[rune]bool) string {
+	var runes []rune
+	for r, ok := range set {
+		if ok {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) == 0 {
+		return `none`
+	}
+	sort.Slice(runes, func(a, b int) bool { return runes[a] < runes[b] })
+	names := make([]string, len(runes))
+	for n, r := range runes {
+		names[n] = fmt.Sprintf(`%q`, r)
+	}
+	return strings.Join(names, ` `)
+}
@@ -0,0 +1,105 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	debug := func(src, commands string, setup func(*Debugger)) (string, string, error) {
+		interpreter := NewInterpreter()
+		output, debugOutput := new(strings.Builder), new(strings.Builder)
+		interpreter.SetOutput(output)
+		interpreter.SetErrorOutput(debugOutput)
+		interpreter.Debugger = NewDebugger(strings.NewReader(commands), debugOutput)
+		if setup != nil {
+			setup(interpreter.Debugger)
+		}
+		err := interpreter.Run(strings.NewReader(src))
+		return output.String(), debugOutput.String(), err
+	}
+
+	t.Run(`stepping`, func(t *testing.T) {
+		output, debugOutput, err := debug(`1 2+p`, "s\n\nc\n", nil)
+		if err != nil || output != "3\n" {
+			t.Fatalf(`expected the program to print 3; found %q, %v`, output, err)
+		}
+		expected := `stopped at '1' (number), line 1, column 1
+stack empty
+(debug) stopped at '2' (number), line 1, column 3
+0: 1 (number)
+(debug) stopped at '+' (add), line 1, column 4
+0: 2 (number)
+1: 1 (number)
+(debug) `
+		if debugOutput != expected {
+			t.Fatalf("expected\n%s\nfound\n%s", expected, debugOutput)
+		}
+	})
+
+	t.Run(`breakpoints`, func(t *testing.T) {
+		setup := func(d *Debugger) {
+			d.Stepping = false
+			d.Breakpoints['*'] = true
+		}
+		_, debugOutput, _ := debug(`[2*]sa 3 lax lax`, "f\nc\nc\n", setup)
+		if strings.Count(debugOutput, `stopped at '*' (multiply), line 1, column 12 > macro character 2`) != 1 ||
+			strings.Count(debugOutput, `line 1, column 16 > macro character 2`) != 1 {
+			t.Fatalf(`expected to stop at * in both runs of the macro; found %s`, debugOutput)
+		}
+		if !strings.Contains(debugOutput, "(debug) 0: 2 (number)\n1: 3 (number)\n") {
+			t.Fatalf(`expected f to print the stack; found %s`, debugOutput)
+		}
+	})
+
+	t.Run(`register breakpoints`, func(t *testing.T) {
+		setup := func(d *Debugger) {
+			d.Stepping = false
+			d.RegisterBreakpoints['a'] = true
+		}
+		_, debugOutput, _ := debug(`1sa 2sb lb la`, "c\nc\n", setup)
+		expected := "stopped at 's' (store), using register 'a', line 1, column 2\n0: 1 (number)\n(debug) " +
+			"stopped at 'l' (load), using register 'a', line 1, column 12\n0: 2 (number)\n(debug) "
+		if debugOutput != expected {
+			t.Fatalf("expected\n%s\nfound\n%s", expected, debugOutput)
+		}
+	})
+
+	t.Run(`setting breakpoints`, func(t *testing.T) {
+		_, debugOutput, _ := debug(`1 2+ 3+`, "b +\nr x\nl\nb +\nl\nb\nz\nc\n", nil)
+		for _, expected := range []string{
+			"commands: '+'\nregisters: 'x'\n",
+			"commands: none\nregisters: 'x'\n",
+			"b needs a single character\n",
+			`unknown debugger command "z"`,
+		} {
+			if !strings.Contains(debugOutput, expected) {
+				t.Fatalf("expected %q in\n%s", expected, debugOutput)
+			}
+		}
+		if strings.Count(debugOutput, `stopped`) != 1 {
+			t.Fatalf(`expected the breakpoint to be cleared; found %s`, debugOutput)
+		}
+	})
+
+	t.Run(`quitting`, func(t *testing.T) {
+		output, _, err := debug(`1p [2p 3p]x 4p`, "s\ns\ns\nq\n", func(d *Debugger) {
+			d.Stepping = false
+			d.Breakpoints['x'] = true
+		})
+		if !errors.Is(err, ErrDebuggerQuit) {
+			t.Fatalf(`expected ErrDebuggerQuit; found %v`, err)
+		}
+		if output != "1\n2\n" {
+			t.Fatalf(`expected the program to stop before 3p; found %q`, output)
+		}
+	})
+
+	t.Run(`running out of commands`, func(t *testing.T) {
+		output, _, err := debug(`1p 2p`, ``, nil)
+		if err != nil || output != "1\n2\n" {
+			t.Fatalf(`expected the program to finish; found %q, %v`, output, err)
+		}
+	})
+}
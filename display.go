@@ -0,0 +1,53 @@
+package godc
+
+import (
+	"math/big"
+)
+
+// SetPrecisionOperation implements the {precision} extension. It pops
+// the number of fractional digits to print, which from then on is
+// independent of the scale set by k. A negative number goes back to
+// printing as many digits as the scale.
+var SetPrecisionOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	p := i.Stack.Pop()
+	err := ensureNumeric(p)
+	if err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	precision, err := digitCount(p)
+	if err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	if precision < 0 {
+		i.SeparatePrecision = false
+		i.Precision = i.Scale
+		return nil
+	}
+	if err := i.checkDigits(precision, i.OutputRadix); err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	i.SeparatePrecision = true
+	i.Precision = precision
+	return nil
+})
+
+// GetPrecisionOperation implements the {getprecision} extension.
+var GetPrecisionOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Stack.Push(&Value{numval: big.NewRat(i.Precision, 1)})
+	return nil
+})
+
+// DisplayExtensions control how numbers are printed.
+var DisplayExtensions = ExtensionSet{
+	`precision`:    SetPrecisionOperation,
+	`getprecision`: GetPrecisionOperation,
+	`sci`:          makeSetNotation(NotationScientific),
+	`eng`:          makeSetNotation(NotationEngineering),
+	`fixed`:        makeSetNotation(NotationFixed),
+}
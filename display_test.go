@@ -0,0 +1,50 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeparatePrecision(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`k sets both scale and precision`, func(t *testing.T) {
+		test(`3kK{getprecision}`)
+		expect(`3.000`, `3.000`)
+	})
+
+	t.Run(`precision can be set separately`, func(t *testing.T) {
+		test(`6k2{precision}1 3/`)
+		expect(`0.33`)
+
+		test(`1 3/3*`)
+		expect(`0.99`)
+
+		test(`0kK`)
+		expect(`0.00`)
+
+		test(`1 3/`)
+		expect(`0.00`)
+	})
+
+	t.Run(`a negative precision follows k again`, func(t *testing.T) {
+		test(`_1{precision}4k1 3/`)
+		expect(`0.3333`)
+	})
+}
@@ -0,0 +1,43 @@
+package godc
+
+// PrintState prints everything about the interpreter that a program
+// can change: the main stack, top first, like the 'f' command, every
+// register that holds anything, like PrintRegisters, the scale,
+// precision and radixes, the quit level, the macros running, and the
+// command still waiting for more input, if there is one. It's for
+// seeing what a macro left behind when it failed halfway.
+func (i *Interpreter) PrintState() {
+	defer i.out.Flush()
+	if i.stackName == `` {
+		i.print(`stack:`)
+	} else {
+		i.printf(`stack %q:`, i.stackName)
+	}
+	if i.Stack.Len() == 0 {
+		i.println(` empty`)
+	} else {
+		i.println()
+	}
+	i.Stack.Each(func(_ int, v *Value) bool {
+		i.printf("  %s\n", i.colorText(v))
+		return true
+	})
+	i.printRegisters()
+	i.printf("scale: %d, precision: %d, input radix: %d, output radix: %d\n", i.Scale, i.Precision, i.InputRadix, i.OutputRadix)
+	i.printf("quit level: %d\n", i.QuitLevel)
+	if len(i.frames) > 0 {
+		i.printf("macros running: %d\n", len(i.frames))
+	}
+	if i.CurrentOperation != nil {
+		i.printf("in progress: %q (%s)\n", i.command, i.operationName())
+	}
+	if i.LastError != nil {
+		i.printf("last error: %v\n", i.LastError)
+	}
+}
+
+// DumpOperation implements the {dump} extension.
+var DumpOperation = OperationAdapter(func(i *Interpreter) error {
+	i.PrintState()
+	return nil
+})
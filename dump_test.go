@@ -0,0 +1,35 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintState(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	interpreter.SetErrorOutput(new(strings.Builder))
+	src := "1 2 3sa [x]0:b 2k 16o [4 {dump} 0/ 5]x"
+	interpreter.EvalString(src)
+	expected := "stack:\n  4.00\n  2.00\n  1.00\n" +
+		"register 'a':\n  3.00\nregister 'b':\n  [0] x\n" +
+		"scale: 2, precision: 2, input radix: 10, output radix: 16\n" +
+		"quit level: 0\nmacros running: 1\n"
+	if actual := buff.String(); actual != expected {
+		t.Fatalf("expected %q to print\n%s\nfound\n%s", src, expected, actual)
+	}
+
+	buff.Reset()
+	interpreter.Stack.Clear()
+	interpreter.Interpret('s')
+	interpreter.PrintState()
+	expected = "stack: empty\n" +
+		"register 'a':\n  3.00\nregister 'b':\n  [0] x\n" +
+		"scale: 2, precision: 2, input radix: 10, output radix: 16\n" +
+		"quit level: 0\nin progress: 's' (store)\n" +
+		"last error: line 1, column 38 > macro character 11: divide by zero\n"
+	if actual := buff.String(); actual != expected {
+		t.Fatalf("expected PrintState to print\n%s\nfound\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,287 @@
+package godc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DCError records the circumstances of a failed command: which
+// command it was, what was on top of the stack afterward, and the
+// interpreter settings in effect. It wraps the underlying error, so
+// errors.Is still matches sentinels like ErrStackTooShort.
+type DCError struct {
+	Err       error
+	Command   rune
+	Operation string // what the command does, e.g. "divide" or "{gcd}"
+	Position  Position
+	Operands  []*Value
+	// Stack is a copy of the whole stack, top first, if the
+	// interpreter's StackSnapshots is set.
+	Stack       []*Value
+	Scale       int64
+	Precision   int64
+	InputRadix  uint8
+	OutputRadix int64
+}
+
+// Error implements the error interface. The message starts with the
+// position of the command, if it's known.
+func (e *DCError) Error() string {
+	if pos := e.Position.String(); pos != `` {
+		return pos + `: ` + e.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+// Position is where a command is in a program. Line and Column count
+// from 1 through the input given to Run, EvalString or Interpret, and
+// point to the command that was read from it. If that command ran a
+// macro, Macros holds the position of the command in each macro in
+// turn, counting characters from 1, outermost first. A macro that
+// was left for one it called as its last command isn't listed.
+type Position struct {
+	Line   int
+	Column int
+	Macros []int
+}
+
+// String describes the position, e.g. "line 2, column 5 > macro
+// character 3", or returns an empty string if it isn't known.
+func (p Position) String() string {
+	var parts []string
+	if p.Line > 0 {
+		parts = append(parts, fmt.Sprintf(`line %d, column %d`, p.Line, p.Column))
+	}
+	for _, at := range p.Macros {
+		parts = append(parts, fmt.Sprintf(`macro character %d`, at))
+	}
+	return strings.Join(parts, ` > `)
+}
+
+// Unwrap lets errors.Is and errors.As see the underlying error.
+func (e *DCError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorPolicy decides what happens to a macro when one of its
+// commands fails.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyAbort stops the macro, and the macros that called it,
+	// and returns the error.
+	ErrorPolicyAbort ErrorPolicy = iota
+	// ErrorPolicyContinue reports the error to the error output and
+	// carries on with the macro's next command, as GNU dc does.
+	ErrorPolicyContinue
+)
+
+var errorPolicyNames = map[ErrorPolicy]string{
+	ErrorPolicyAbort:    `abort`,
+	ErrorPolicyContinue: `continue`,
+}
+
+// String implements fmt.Stringer.
+func (p ErrorPolicy) String() string {
+	if name, ok := errorPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf(`ErrorPolicy(%d)`, int(p))
+}
+
+// ParseErrorPolicy converts the name of an error policy, as returned
+// by String, back to an ErrorPolicy.
+func ParseErrorPolicy(name string) (ErrorPolicy, error) {
+	for policy, n := range errorPolicyNames {
+		if n == name {
+			return policy, nil
+		}
+	}
+	return ErrorPolicyAbort, fmt.Errorf(`unknown error policy %q`, name)
+}
+
+// fatal reports whether err has to stop the interpreter, whatever the
+// ErrorPolicy: the context was cancelled, the StepLimit reached, or
+// the program stopped from the debugger.
+func (i *Interpreter) fatal(err error) bool {
+	if i.ctx != nil && i.ctx.Err() != nil {
+		return true
+	}
+	return errors.Is(err, ErrStepLimitExceeded) || errors.Is(err, ErrDebuggerQuit)
+}
+
+// errorHints suggest a way out of the most common errors.
+var errorHints = []struct {
+	err  error
+	hint string
+}{
+	{ErrStackTooShort, `push more values before this command; use f to see what's on the stack`},
+	{ErrDivideByZero, `the value on top of the stack is zero; check it with p before dividing`},
+	{ErrNotANumber, `this command works on numbers, but found a string; use f to see the stack`},
+	{ErrValueNotNumeric, `this command works on numbers, but found a string; use f to see the stack`},
+	{ErrValueNotString, `conditional commands run a macro from the register; store one with [...]sa first`},
+	{ErrNoImaginaryNumbers, `take the square root of the absolute value, e.g. d0r-v for a negative number`},
+	{ErrIntegersOnly, `this command works on whole numbers; use 0k1/ to drop the fraction`},
+	{ErrNoInverse, `a number only has an inverse when it shares no factor with the modulus; check with {gcd}`},
+	{ErrRandomBound, `{rand} pops the number of possible results, so it must be at least 1`},
+	{ErrLogOfNonPositive, `{ln} needs a number greater than zero`},
+	{ErrStringIndexOutOfRange, `{substr} pops a length and a start, counting from 0, e.g. [hello]1 3{substr}`},
+	{ErrWholeExponentsOnly, `the exponent for | must be a positive whole number`},
+	{ErrArrayIndexOutOfRange, `array indexes run from 0 to 2147483647`},
+	{ErrStackCountOutOfRange, `stack counts start from 1 for the top of the stack, e.g. 2{pick} copies the value under it`},
+	{ErrInputRadixOutOfRange, `set the input radix with a whole number; Ai returns to decimal from any radix`},
+	{ErrDigitOutOfRange, `every digit must be smaller than the input radix; Ai returns to decimal`},
+	{ErrOutputRadixOutOfRange, `set the output radix with a whole number, e.g. 16o for hexadecimal`},
+	{ErrNotImplemented, `godc understands this dc command but doesn't support it yet`},
+	{ErrUnknownCommand, `dc has no such command; godc's own, such as {name} and R, can't be used in strict mode`},
+	{ErrNegativeScale, `set the scale with a whole number of digits, from 0 up`},
+	{ErrUnknownExtension, `check the spelling of the {name} command; extensions are case sensitive`},
+}
+
+func hintFor(err error) string {
+	for _, h := range errorHints {
+		if errors.Is(err, h.err) {
+			return h.hint
+		}
+	}
+	return ``
+}
+
+// recordError wraps err in a DCError describing the command that
+// just failed, and remembers it for the {explain} command. Errors
+// that were already recorded by a nested macro are passed through,
+// so the innermost failing command is the one reported.
+func (i *Interpreter) recordError(err error) error {
+	var dcErr *DCError
+	if errors.As(err, &dcErr) {
+		return err
+	}
+	dcErr = &DCError{
+		Err:         err,
+		Command:     i.command,
+		Operation:   i.operationName(),
+		Position:    i.position(),
+		Scale:       i.Scale,
+		Precision:   i.Precision,
+		InputRadix:  i.InputRadix,
+		OutputRadix: i.OutputRadix,
+	}
+	for depth := 0; depth < i.Stack.Len() && depth < 2; depth++ {
+		dcErr.Operands = append(dcErr.Operands, i.Stack.At(depth).Dup())
+	}
+	if i.StackSnapshots {
+		dcErr.Stack = i.Stack.Values()
+	}
+	i.LastError = dcErr
+	return dcErr
+}
+
+// commandNames describe what each of dc's commands does.
+var commandNames = map[rune]string{
+	'q': `quit`,
+	'p': `print`,
+	'P': `print raw`,
+	'n': `print and pop`,
+	'f': `print stack`,
+	'+': `add`,
+	'-': `subtract`,
+	'*': `multiply`,
+	'/': `divide`,
+	'%': `remainder`,
+	'~': `quotient and remainder`,
+	'^': `exponent`,
+	'|': `modular exponent`,
+	'v': `square root`,
+	'c': `clear`,
+	'd': `duplicate`,
+	'r': `swap`,
+	'R': `drop`,
+	's': `store`,
+	'l': `load`,
+	'S': `push to register`,
+	'L': `pop from register`,
+	'k': `set scale`,
+	'K': `get scale`,
+	'i': `set input radix`,
+	'o': `set output radix`,
+	'I': `get input radix`,
+	'O': `get output radix`,
+	'[': `string`,
+	'{': `extension`,
+	'a': `to character`,
+	'x': `execute`,
+	'>': `execute if greater`,
+	'<': `execute if less`,
+	'=': `execute if equal`,
+	'!': `execute unless`,
+	'?': `read line`,
+	'Q': `quit macros`,
+	'Z': `length`,
+	'X': `scale of`,
+	'z': `stack depth`,
+	'#': `comment`,
+	':': `store in array`,
+	';': `load from array`,
+}
+
+// operationName describes the command being run: its entry in
+// commandNames, the {name} of an extension, or "number".
+func (i *Interpreter) operationName() string {
+	if i.command == '{' && i.extension != `` {
+		return `{` + i.extension + `}`
+	}
+	if isDigit(i.command, i.InputRadix) {
+		return `number`
+	}
+	return commandNames[i.command]
+}
+
+func describeValue(val *Value, radix, precision int64) string {
+	if val.Type == VTString {
+		return fmt.Sprintf(`[%s] (string)`, val.Text(radix, precision))
+	}
+	return fmt.Sprintf(`%s (number)`, val.Text(radix, precision))
+}
+
+// ExplainOperation implements the {explain} extension, which prints
+// a longer description of the last error.
+var ExplainOperation = OperationAdapter(func(i *Interpreter) error {
+	e := i.LastError
+	if e == nil {
+		i.println(`no error to explain`)
+		return nil
+	}
+	i.printf("error: %v\n", e.Err)
+	if e.Operation != `` {
+		i.printf("command: %q (%s)\n", e.Command, e.Operation)
+	} else {
+		i.printf("command: %q\n", e.Command)
+	}
+	if pos := e.Position.String(); pos != `` {
+		i.printf("position: %s\n", pos)
+	}
+	if len(e.Operands) == 0 {
+		i.println(`stack: empty`)
+	}
+	for n, val := range e.Operands {
+		label := `top of stack`
+		if n > 0 {
+			label = `next on stack`
+		}
+		i.printf("%s: %s\n", label, describeValue(val, e.OutputRadix, e.Precision))
+	}
+	i.printf("scale: %d, precision: %d, input radix: %d, output radix: %d\n", e.Scale, e.Precision, e.InputRadix, e.OutputRadix)
+	if hint := hintFor(e.Err); hint != `` {
+		i.printf("hint: %s\n", hint)
+	}
+	return nil
+})
+
+// DiagnosticExtensions help track down problems in dc programs.
+var DiagnosticExtensions = ExtensionSet{
+	`explain`:   ExplainOperation,
+	`registers`: PrintRegistersOperation,
+	`dump`:      DumpOperation,
+	`profile`:   PrintProfileOperation,
+}
@@ -0,0 +1,191 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExplainLastError(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	run := func(str string) error {
+		interpreter.ResetPosition()
+		for _, r := range str {
+			if err := interpreter.Interpret(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	explain := func() string {
+		buff.Reset()
+		if err := run(`{explain}`); err != nil {
+			t.Fatalf(`could not explain: %v`, err)
+		}
+		return buff.String()
+	}
+
+	t.Run(`nothing to explain`, func(t *testing.T) {
+		if actual := explain(); actual != "no error to explain\n" {
+			t.Fatalf(`expected no error; found %q`, actual)
+		}
+	})
+
+	t.Run(`errors match their sentinels`, func(t *testing.T) {
+		err := run(`c3k1 0/`)
+		if !errors.Is(err, ErrDivideByZero) {
+			t.Fatalf(`expected divide by zero; found %v`, err)
+		}
+		var dcErr *DCError
+		if !errors.As(err, &dcErr) {
+			t.Fatalf(`expected a *DCError; found %T`, err)
+		}
+		if dcErr.Command != '/' {
+			t.Fatalf(`expected command '/'; found %q`, dcErr.Command)
+		}
+	})
+
+	t.Run(`explaining an error`, func(t *testing.T) {
+		expected := strings.Join([]string{
+			`error: divide by zero`,
+			`command: '/' (divide)`,
+			`position: line 1, column 7`,
+			`top of stack: 0.000 (number)`,
+			`next on stack: 1.000 (number)`,
+			`scale: 3, precision: 3, input radix: 10, output radix: 10`,
+			`hint: ` + hintFor(ErrDivideByZero),
+			``,
+		}, "\n")
+		if actual := explain(); actual != expected {
+			t.Fatalf("expected:\n%s\nfound:\n%s", expected, actual)
+		}
+	})
+
+	t.Run(`errors inside macros report the failing command`, func(t *testing.T) {
+		err := run(`c[abc]sa[1 la+]x`)
+		if !errors.Is(err, ErrValueNotNumeric) {
+			t.Fatalf(`expected a non-numeric error; found %v`, err)
+		}
+		if actual := interpreter.LastError.Command; actual != '+' {
+			t.Fatalf(`expected command '+'; found %q`, actual)
+		}
+		if actual := explain(); !strings.Contains(actual, `top of stack: [abc] (string)`) {
+			t.Fatalf(`expected the string operand to be described; found %q`, actual)
+		}
+	})
+}
+
+func TestErrorPositions(t *testing.T) {
+	position := func(src string) Position {
+		interpreter := NewInterpreter()
+		interpreter.SetOutput(new(strings.Builder))
+		_, err := interpreter.EvalString(src)
+		var dcErr *DCError
+		if !errors.As(err, &dcErr) {
+			t.Fatalf(`expected %q to fail with a *DCError; found %v`, src, err)
+		}
+		return dcErr.Position
+	}
+	expect := func(src, expected string) {
+		if actual := position(src).String(); actual != expected {
+			t.Fatalf(`expected the error in %q at %q; found %q`, src, expected, actual)
+		}
+	}
+
+	expect(`+`, `line 1, column 1`)
+	expect("1 2+\n3 +\n  \t+", `line 3, column 4`)
+	expect(`[ [[a] +]x 1]x`, `line 1, column 14 > macro character 9 > macro character 5`)
+	expect(`[ [[a] +]x]x`, `line 1, column 12 > macro character 5`) // a tail call
+	expect(`[  +]sa 1 2 lax [b] lax`, `line 1, column 23 > macro character 3`)
+	expect("\n1 1F", `line 2, column 3`)
+	if actual := position(`[1 +]x`).Macros; len(actual) != 1 || actual[0] != 3 {
+		t.Fatalf(`expected the error at character 3 of the macro; found %v`, actual)
+	}
+	if actual := (&DCError{Err: ErrStackTooShort}).Error(); actual != ErrStackTooShort.Error() {
+		t.Fatalf(`expected an error without a position to read like its cause; found %q`, actual)
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	fail := func(src string) *DCError {
+		interpreter.Stack.Clear()
+		_, err := interpreter.EvalString(src)
+		var dcErr *DCError
+		if !errors.As(err, &dcErr) {
+			t.Fatalf(`expected %q to fail with a *DCError; found %v`, src, err)
+		}
+		return dcErr
+	}
+	operation := func(src, expected string) {
+		if actual := fail(src).Operation; actual != expected {
+			t.Fatalf(`expected %q to fail in %q; found %q`, src, expected, actual)
+		}
+	}
+
+	operation(`1 0/`, `divide`)
+	operation(`[x]sa 1 [b]<a`, `execute if less`)
+	operation(`1.5 2{gcd}`, `{gcd}`)
+	operation(`{nope}`, `{nope}`)
+	operation(`1 1F`, `number`)
+
+	if err := fail(`1 2 3 [a]+`); err.Stack != nil {
+		t.Fatalf(`expected no snapshot of the stack; found %v`, err.Stack)
+	}
+	interpreter.StackSnapshots = true
+	err := fail(`1 2 3 [a]+`)
+	if len(err.Stack) != 4 || err.Stack[0].String() != `a` || err.Stack[3].String() != `1` {
+		t.Fatalf(`expected a snapshot of the stack, top first; found %v`, err.Stack)
+	}
+	if !errors.Is(err, ErrValueNotNumeric) {
+		t.Fatalf(`expected the error to match its sentinel`)
+	}
+}
+
+func TestErrorPolicy(t *testing.T) {
+	run := func(policy ErrorPolicy, src string) (string, string) {
+		interpreter := NewInterpreter()
+		interpreter.ErrorPolicy = policy
+		output, errorOutput := new(strings.Builder), new(strings.Builder)
+		interpreter.SetOutput(output)
+		interpreter.SetErrorOutput(errorOutput)
+		if err := interpreter.Run(strings.NewReader(src)); err != nil {
+			t.Fatalf(`expected %q to run; found %v`, src, err)
+		}
+		return output.String(), errorOutput.String()
+	}
+	expect := func(policy ErrorPolicy, src, expected string, errors int) {
+		output, errorOutput := run(policy, src)
+		if output != expected {
+			t.Fatalf(`expected %q to print %q under %v; found %q`, src, expected, policy, output)
+		}
+		if actual := strings.Count(errorOutput, "\n"); actual != errors {
+			t.Fatalf(`expected %q to report %d errors under %v; found %q`, src, errors, policy, errorOutput)
+		}
+	}
+
+	expect(ErrorPolicyAbort, `[1 0/ 2p]x 3p`, "3\n", 1)
+	expect(ErrorPolicyContinue, `[1 0/ 2p]x 3p`, "2\n3\n", 1)
+	expect(ErrorPolicyContinue, `[[+ 1p]x + 2p]x`, "1\n2\n", 2)
+	expect(ErrorPolicyContinue, `[[[+ q 4p]x 5p]x 6p]x 7p`, "6\n7\n", 1)
+
+	interpreter := NewInterpreter()
+	interpreter.ErrorPolicy = ErrorPolicyContinue
+	interpreter.SetErrorOutput(new(strings.Builder))
+	interpreter.StepLimit = 10
+	if _, err := interpreter.EvalString(`[+ lax]dsax`); !errors.Is(err, ErrStepLimitExceeded) {
+		t.Fatalf(`expected the step limit to stop the macro anyway; found %v`, err)
+	}
+
+	for _, policy := range []ErrorPolicy{ErrorPolicyAbort, ErrorPolicyContinue} {
+		if parsed, err := ParseErrorPolicy(policy.String()); err != nil || parsed != policy {
+			t.Fatalf(`expected %v to parse back; found %v, %v`, policy, parsed, err)
+		}
+	}
+	if _, err := ParseErrorPolicy(`ignore`); err == nil {
+		t.Fatalf(`expected an unknown error policy to be rejected`)
+	}
+}
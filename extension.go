@@ -0,0 +1,114 @@
+package godc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownExtension is returned when a {name} command names an
+// extension that hasn't been loaded into the Interpreter.
+var ErrUnknownExtension = fmt.Errorf(`unknown extension`)
+
+// ExtensionSet is a group of godc-specific commands, keyed by the
+// name used to invoke them inside braces.
+type ExtensionSet map[string]Operation
+
+// LoadExtensions makes every command in the set available through
+// the '{' command, replacing any extension of the same name.
+func (i *Interpreter) LoadExtensions(set ExtensionSet) {
+	for name, op := range set {
+		i.Extensions[name] = op
+	}
+}
+
+// ExtensionNames lists the extensions that can be run with {name}, in
+// order.
+func (i *Interpreter) ExtensionNames() []string {
+	names := make([]string, 0, len(i.Extensions))
+	for name := range i.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// extensionPacks are the extension sets registered by name, to be
+// loaded when they're asked for.
+var extensionPacks = make(map[string]ExtensionSet)
+
+// RegisterExtensionPack makes an extension set available by name to
+// programs that load extensions when they're asked for, as godc's
+// -load flag does. A package of extensions calls it from its init
+// function, so a custom build only has to import the package. A second
+// pack of the same name replaces the first.
+func RegisterExtensionPack(name string, set ExtensionSet) {
+	extensionPacks[name] = set
+}
+
+// ExtensionPack returns the extension set registered with name, and
+// whether there is one.
+func ExtensionPack(name string) (ExtensionSet, bool) {
+	set, ok := extensionPacks[name]
+	return set, ok
+}
+
+// ExtensionPackNames lists the registered extension packs, in order.
+func ExtensionPackNames() []string {
+	names := make([]string, 0, len(extensionPacks))
+	for name := range extensionPacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExtensionDispatcher implements the '{' command. dc has used up most
+// of the single-rune command space, so godc's own commands live in a
+// multi-character namespace: {name} runs the extension called name.
+// An extension that is hungry for more runes (e.g. a register name)
+// keeps receiving them after the closing brace.
+type ExtensionDispatcher struct {
+	State OperationState
+	name  []rune
+	op    Operation
+}
+
+func (eo *ExtensionDispatcher) reset() {
+	eo.State = OSNotHungry
+	eo.name = nil
+	eo.op = nil
+}
+
+// Operate implements the Operation interface.
+func (eo *ExtensionDispatcher) Operate(i *Interpreter, r rune) (bool, error) {
+	if eo.State == OSNotHungry {
+		eo.State = OSHungry
+		eo.name = []rune{}
+		return false, nil
+	}
+	if eo.op == nil {
+		if r != '}' {
+			eo.name = append(eo.name, r)
+			return false, nil
+		}
+		name := string(eo.name)
+		i.extension = name
+		op, ok := i.Extensions[name]
+		if !ok {
+			eo.reset()
+			return true, fmt.Errorf(`%w: {%s}`, ErrUnknownExtension, name)
+		}
+		eo.op = op
+	}
+	// The extension may run a macro, which may use extensions too, so
+	// the dispatcher is reset while it runs and restored if it's hungry.
+	op := eo.op
+	eo.reset()
+	i.CurrentOperation = nil
+	finished, err := op.Operate(i, r)
+	if !finished {
+		eo.State = OSHungry
+		eo.op = op
+	}
+	return finished, err
+}
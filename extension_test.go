@@ -0,0 +1,39 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtensionPacks(t *testing.T) {
+	defer func() { delete(extensionPacks, `test`) }()
+	if _, ok := ExtensionPack(`test`); ok {
+		t.Fatal(`expected no pack before it's registered`)
+	}
+	RegisterExtensionPack(`test`, ExtensionSet{
+		`answer`: OperationAdapter(func(i *Interpreter) error {
+			i.Stack.Push(NewValueFromInt64(42))
+			return nil
+		}),
+	})
+	set, ok := ExtensionPack(`test`)
+	if !ok {
+		t.Fatal(`expected the pack to be registered`)
+	}
+	if names := strings.Join(ExtensionPackNames(), ` `); !strings.Contains(names, `test`) {
+		t.Fatalf(`expected the pack to be listed; found %q`, names)
+	}
+
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`{answer}`); err == nil {
+		t.Fatal(`expected the pack not to be loaded until it's asked for`)
+	}
+	interpreter.LoadExtensions(set)
+	stack, err := interpreter.EvalString(`{answer}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stack) != 1 || stack[0].String() != `42` {
+		t.Fatalf(`expected the extension to push 42; found %v`, stack)
+	}
+}
@@ -0,0 +1,51 @@
+package godc
+
+// fixScale rounds the number a command has left on top of the stack to
+// the scale, with the rounding mode, when FixedScale is set, so that
+// every number has exactly as many fractional digits as the scale.
+func (i *Interpreter) fixScale() {
+	v := i.Stack.Peek()
+	if v == nil || v.Type != VTNumber || v.scale == i.Scale {
+		return
+	}
+	// The value may be shared, as with one just loaded from a register,
+	// so it's replaced rather than changed.
+	fixed := v.Dup()
+	fixed.Round(i.Scale, i.RoundingMode)
+	i.Stack.set(0, fixed)
+}
+
+// MoneyOperation implements the {money} extension. It pops a scale,
+// and from then on keeps every number at that scale, rounding results
+// half to even, as is usual for money. A negative scale turns this off
+// again, leaving the scale and rounding mode as they are.
+var MoneyOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	p := i.Stack.Pop()
+	if err := ensureNumeric(p); err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	scale := p.Int()
+	if scale < 0 {
+		i.FixedScale = false
+		return nil
+	}
+	if err := i.checkDigits(scale, 10); err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	i.Scale, i.FixedScale, i.RoundingMode = scale, true, RoundHalfEven
+	if !i.SeparatePrecision {
+		i.Precision = scale
+	}
+	return nil
+})
+
+// FixedPointExtensions keep numbers at a fixed scale, for sums of money
+// that mustn't lose a cent.
+var FixedPointExtensions = ExtensionSet{
+	`money`: MoneyOperation,
+}
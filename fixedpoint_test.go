@@ -0,0 +1,68 @@
+package godc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFixedPoint(t *testing.T) {
+	interpreter := NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	expect := func(src, expected string) {
+		out.Reset()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if out.String() != expected {
+			t.Fatalf(`expected %q to print %q; found %q`, src, expected, out.String())
+		}
+	}
+
+	expect(`2{money} 0.125p 0.135p 1.005 2.005+p`, "0.12\n0.14\n3.00\n")
+	if !interpreter.FixedScale || interpreter.Scale != 2 || interpreter.RoundingMode != RoundHalfEven {
+		t.Fatalf(`expected {money} to set the scale and the rounding mode`)
+	}
+	expect(`c 10 3/p 3*p 1.25 1.25*p 2vp`, "3.33\n9.99\n1.56\n1.41\n")
+	// Numbers in compiled macros, and loaded from registers, are fixed
+	// too, without changing the register.
+	v, _ := NewValueFromDecimalString(`0.125`)
+	interpreter.Register('b').Push(v)
+	expect(`c [0.125 0.001+]sa lax p lbp [lbn]x`, "0.12\n0.12\n0.12")
+	if v := interpreter.Register('b').Peek(); v.scale != 3 {
+		t.Fatalf(`expected the register to keep its own value; found scale %d`, v.scale)
+	}
+	expect(`c 1 8/ _1{money} 1 8/ f`, "0.12\n0.12\n")
+	if interpreter.FixedScale {
+		t.Fatal(`expected _1{money} to turn fixed point off`)
+	}
+	expect(`c 4k 1 8/ p`, "0.1250\n")
+
+	if _, err := interpreter.EvalString(`[x]{money}`); !errors.Is(err, ErrValueNotNumeric) {
+		t.Fatalf(`expected a string scale to be refused; found %v`, err)
+	}
+}
+
+func TestFixedPointState(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.EvalString(`2{money} [ ]{group}`)
+	var saved bytes.Buffer
+	if err := interpreter.SaveJSON(&saved); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewInterpreter()
+	if err := restored.LoadJSON(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.FixedScale || restored.GroupSeparator != ` ` {
+		t.Fatalf(`expected the settings to be restored; found %v, %q`, restored.FixedScale, restored.GroupSeparator)
+	}
+	snapshot := interpreter.Snapshot()
+	interpreter.EvalString(`_1{money} []{group}`)
+	interpreter.RestoreSnapshot(snapshot)
+	if !interpreter.FixedScale || interpreter.GroupSeparator != ` ` {
+		t.Fatal(`expected the snapshot to restore the settings`)
+	}
+}
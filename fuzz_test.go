@@ -0,0 +1,159 @@
+//go:build go1.18
+
+package godc
+
+import (
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Limits for the fuzzed interpreters, small enough that no input can
+// make one run for long or fill memory.
+const (
+	fuzzSteps       = 10000
+	fuzzNumberBits  = 4096
+	fuzzStringChars = 4096
+	fuzzStackDepth  = 1000
+)
+
+// fuzzInterpreter returns an interpreter that prints nowhere, with
+// every limit set.
+func fuzzInterpreter() *Interpreter {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(io.Discard)
+	interpreter.SetErrorOutput(io.Discard)
+	interpreter.SetInput(strings.NewReader(``))
+	interpreter.StepLimit = fuzzSteps
+	interpreter.MaxNumberBits = fuzzNumberBits
+	interpreter.MaxStringLength = fuzzStringChars
+	interpreter.MaxStackDepth = fuzzStackDepth
+	return interpreter
+}
+
+// checkBounded fails t if anything the interpreter holds is bigger
+// than its limits allow.
+func checkBounded(t *testing.T, interpreter *Interpreter) {
+	check := func(where string, s *Stack) {
+		if err := interpreter.checkDepth(s); err != nil {
+			t.Fatalf(`%s: %v`, where, err)
+		}
+		s.Each(func(_ int, v *Value) bool {
+			if err := interpreter.checkValue(v); err != nil {
+				t.Fatalf(`%s: %v`, where, err)
+			}
+			return true
+		})
+	}
+	check(`stack`, interpreter.Stack)
+	for name, register := range interpreter.Registers {
+		check(`register `+string(name), register)
+	}
+	for name, stack := range interpreter.stacks {
+		check(`stack `+name, stack)
+	}
+}
+
+func FuzzInterpret(f *testing.F) {
+	for _, seed := range []string{
+		`2 3+p`,
+		`1 2 3f`,
+		`[Hello, world!]p`,
+		`10k 2vp`,
+		`16o 255p 2i 1010p`,
+		`1.5e3 2e_2*p`,
+		`[lad1-dsa0<b]sb 10sa lbx`,
+		`[d1-d1<f*]sf 10lfxp`,
+		`0 1:a 1;ap`,
+		`2 1000^ 3%p`,
+		`20k {pi}p {e}p 2{ln}p 1{exp}p`,
+		`1 2 3 3{roll} 2{pick} {over} 2{dropn}f`,
+		`[aux]{stack} 1 2 [main]{stack}`,
+		`{mathlib} 1 lsx`,
+		`[1 lax]dsax`,
+		`?`,
+		`1Q 2Q c q`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		interpreter := fuzzInterpreter()
+		// Errors are expected; it's panics and runaway growth that
+		// the fuzzer is looking for.
+		interpreter.Run(strings.NewReader(string(src)))
+		checkBounded(t, interpreter)
+		if interpreter.Steps > fuzzSteps {
+			t.Fatalf(`ran %d commands, but the limit is %d`, interpreter.Steps, fuzzSteps)
+		}
+	})
+}
+
+func FuzzNumberBuilder(f *testing.F) {
+	for _, seed := range []string{
+		`0`, `12`, `_12`, `1.25`, `.5`, `1.`, `1e3`, `1.5e_3`, `_e`, `1e`, `1e_`,
+		`1..2`, `1__2`, `1e2e3`, `1e2.5`, `FF`, `123456789012345678901234567890`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		interpreter := fuzzInterpreter()
+		// A number ends when a rune that can't be part of it arrives,
+		// and that rune must be processed again as a command of its own
+		// rather than being lost: Interpret panics if a builder asks for
+		// that without finishing.
+		interpreter.EvalString(src + ` z`)
+		checkBounded(t, interpreter)
+
+		// A whole decimal number reads the same as NewValueFromDecimalString.
+		if strings.ContainsAny(src, "e_ \t\n") {
+			return
+		}
+		expected, err := NewValueFromDecimalString(src)
+		if err != nil {
+			return
+		}
+		interpreter = fuzzInterpreter()
+		values, err := interpreter.EvalString(src)
+		if err != nil || len(values) != 1 {
+			return
+		}
+		if values[0].numval.Cmp(expected.numval) != 0 {
+			t.Fatalf(`%q read as %s; NewValueFromDecimalString gives %s`, src, values[0].numval, expected.numval)
+		}
+	})
+}
+
+func FuzzValueText(f *testing.F) {
+	f.Add(int64(1), int64(3), int64(10), int64(5), uint8(0), uint8(0))
+	f.Add(int64(-22), int64(7), int64(16), int64(20), uint8(1), uint8(1))
+	f.Add(int64(255), int64(1), int64(1000), int64(2), uint8(2), uint8(2))
+	f.Add(int64(0), int64(1), int64(2), int64(0), uint8(0), uint8(1))
+	f.Fuzz(func(t *testing.T, num, denom, radix, precision int64, mode, notation uint8) {
+		if denom == 0 {
+			return
+		}
+		// Keep the radix legal, and the digits few enough to be quick.
+		if radix < 0 {
+			radix = -radix
+		}
+		radix = 2 + radix%(MaxOutputRadix-1)
+		precision %= 64
+		if precision < 0 {
+			precision = -precision
+		}
+		roundingMode := RoundingMode(mode % 3)
+
+		v := NewValueFromBigRat(big.NewRat(num, denom))
+		before := v.Rat()
+		text := v.Text(radix, precision)
+		if text == `` {
+			t.Fatalf(`%s printed in radix %d as nothing`, before, radix)
+		}
+		v.RoundedText(radix, precision, roundingMode)
+		v.NotationText(radix, Notation(notation%3), precision+1, roundingMode)
+		if v.Rat().Cmp(before) != 0 {
+			t.Fatalf(`printing %s changed it to %s`, before, v.Rat())
+		}
+	})
+}
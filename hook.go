@@ -0,0 +1,64 @@
+package godc
+
+// Hook is told about every command an interpreter runs, including
+// those in macros, so tracing, auditing, limits and metrics can be
+// added without changing the interpreter. Add one with AddHook.
+type Hook interface {
+	// BeforeCommand is called as a command is about to run. If it
+	// returns an error, the command fails with it instead of running.
+	BeforeCommand(i *Interpreter, command rune) error
+	// AfterCommand is called once a command has finished, with the
+	// error it failed with, if any, or ErrExitRequested if it was q or
+	// Q. The error it returns replaces it.
+	AfterCommand(i *Interpreter, command rune, err error) error
+}
+
+// HookFuncs adapts a pair of functions to the Hook interface. Either
+// may be nil.
+type HookFuncs struct {
+	Before func(i *Interpreter, command rune) error
+	After  func(i *Interpreter, command rune, err error) error
+}
+
+// BeforeCommand implements Hook.
+func (h HookFuncs) BeforeCommand(i *Interpreter, command rune) error {
+	if h.Before == nil {
+		return nil
+	}
+	return h.Before(i, command)
+}
+
+// AfterCommand implements Hook.
+func (h HookFuncs) AfterCommand(i *Interpreter, command rune, err error) error {
+	if h.After == nil {
+		return err
+	}
+	return h.After(i, command, err)
+}
+
+// AddHook adds a hook to be told about every command from now on.
+// Hooks are called before a command in the order they were added, and
+// after it in the reverse order, so each wraps the ones added after it.
+func (i *Interpreter) AddHook(h Hook) {
+	i.hooks = append(i.hooks, h)
+}
+
+// beforeHooks calls the hooks before a command.
+func (i *Interpreter) beforeHooks() error {
+	for _, h := range i.hooks {
+		if err := h.BeforeCommand(i, i.command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterHooks calls the hooks after a command, and returns the error
+// they leave it with. The command is passed in, as a command that ran
+// a macro has had the macro's commands run since.
+func (i *Interpreter) afterHooks(command rune, err error) error {
+	for n := len(i.hooks) - 1; n >= 0; n-- {
+		err = i.hooks[n].AfterCommand(i, command, err)
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package godc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	interpreter := NewInterpreter()
+	var calls []string
+	interpreter.AddHook(HookFuncs{
+		Before: func(_ *Interpreter, command rune) error {
+			calls = append(calls, fmt.Sprintf(`before %c`, command))
+			return nil
+		},
+		After: func(i *Interpreter, command rune, err error) error {
+			calls = append(calls, fmt.Sprintf(`after %c %d %v`, command, i.Stack.Len(), err))
+			return err
+		},
+	})
+	if _, err := interpreter.EvalString(`12 [3+]x`); err != nil {
+		t.Fatal(err)
+	}
+	expected := `before 1,after 1 1 <nil>,before [,after [ 2 <nil>,before x,before 3,after 3 2 <nil>,before +,after + 1 <nil>,after x 1 <nil>`
+	if actual := strings.Join(calls, `,`); actual != expected {
+		t.Fatalf("expected the hooks to be called\n%s\nfound\n%s", expected, actual)
+	}
+
+	calls = nil
+	interpreter.Stack.Clear()
+	if _, err := interpreter.EvalString(`+`); !errors.Is(err, ErrStackTooShort) {
+		t.Fatalf(`expected a failing command to fail; found %v`, err)
+	}
+	if actual := strings.Join(calls, `,`); actual != `before +,after + 0 stack too short` {
+		t.Fatalf(`expected the hook to see the error; found %s`, actual)
+	}
+
+	// A hook can refuse commands, and change how they end.
+	refused := errors.New(`refused`)
+	interpreter.AddHook(HookFuncs{
+		Before: func(_ *Interpreter, command rune) error {
+			if command == 'd' {
+				return refused
+			}
+			return nil
+		},
+		After: func(_ *Interpreter, _ rune, err error) error {
+			if errors.Is(err, ErrStackTooShort) {
+				return nil
+			}
+			return err
+		},
+	})
+	calls = nil
+	values, err := interpreter.EvalString(`1d`)
+	if !errors.Is(err, refused) || len(values) != 1 {
+		t.Fatalf(`expected d to be refused; found %v, leaving %v`, err, values)
+	}
+	if _, err := interpreter.EvalString(`c+`); err != nil {
+		t.Fatalf(`expected the hook to clear the error; found %v`, err)
+	}
+	if actual := strings.Join(calls, `,`); !strings.HasPrefix(actual, `before 1,after 1 1 <nil>,before d,before c`) {
+		t.Fatalf(`expected a refused command not to finish; found %s`, actual)
+	}
+}
@@ -0,0 +1,449 @@
+// Package infix is a front end for godc that reads a small language of
+// infix expressions and variables, in the style of bc, and compiles it
+// to dc commands for a godc.Interpreter to run.
+//
+// A program is a list of statements, separated by semicolons or new
+// lines. A statement is either an assignment, name = expression, which
+// prints nothing, or an expression on its own, whose value is printed.
+// Expressions have numbers, variables, parentheses, the operators
+// + - * / % and ^, and the functions sqrt, ln, exp, length, scale and
+// pi(). As in bc, ^ is right associative and binds less tightly than
+// unary minus, so -2^2 is 4, and the special variables scale, ibase
+// and obase are the interpreter's scale and radixes. A # starts a
+// comment that runs to the end of the line.
+package infix
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Unquabain/godc"
+)
+
+// ErrSyntax is returned when a program can't be parsed.
+var ErrSyntax = fmt.Errorf(`syntax error`)
+
+// ErrUndefined is returned when a program uses a variable before it's
+// been assigned a value.
+var ErrUndefined = fmt.Errorf(`undefined variable`)
+
+// firstRegister is the register the first variable with a name longer
+// than one letter is kept in. Registers from there on are in Unicode's
+// private use area, so they won't clash with those dc programs use.
+const firstRegister = '\uE000'
+
+// specials are the variables that stand for the interpreter's
+// settings, with the commands that set and get them.
+var specials = map[string][2]string{
+	`scale`: {`k`, `K`},
+	`ibase`: {`i`, `I`},
+	`obase`: {`o`, `O`},
+}
+
+// functions are the functions that take one argument, with the
+// command that computes each.
+var functions = map[string]string{
+	`sqrt`:   `v`,
+	`ln`:     `{ln}`,
+	`exp`:    `{exp}`,
+	`length`: `Z`,
+	`scale`:  `X`,
+}
+
+// constants are the functions that take no arguments.
+var constants = map[string]string{
+	`pi`: `{pi}`,
+}
+
+// binaryOperators are the commands for each binary operator.
+var binaryOperators = map[rune]string{
+	'+': `+`,
+	'-': `-`,
+	'*': `*`,
+	'/': `/`,
+	'%': `%`,
+	'^': `^`,
+}
+
+// Compiler compiles programs to dc. It remembers the variables that
+// have been assigned, and the registers they're kept in, from one
+// program to the next, so a session can be compiled a line at a time.
+type Compiler struct {
+	registers map[string]rune
+	next      rune
+}
+
+// NewCompiler returns a Compiler that knows no variables yet.
+func NewCompiler() *Compiler {
+	return &Compiler{registers: make(map[string]rune), next: firstRegister}
+}
+
+// Register returns the register the variable name is kept in, and
+// whether it's been assigned. Variables with one-letter names are
+// kept in the register of the same name.
+func (c *Compiler) Register(name string) (rune, bool) {
+	r, ok := c.registers[name]
+	return r, ok
+}
+
+// assign returns the register for a variable that's being assigned,
+// choosing one if it's new.
+func (c *Compiler) assign(name string) rune {
+	if r, ok := c.registers[name]; ok {
+		return r
+	}
+	r := []rune(name)[0]
+	if len(name) > 1 {
+		r = c.next
+		c.next++
+	}
+	c.registers[name] = r
+	return r
+}
+
+// Compile compiles a program to dc. Each statement is run as a macro
+// of its own, so one that fails doesn't go on to print or assign a
+// wrong result. If a statement can't be compiled, the dc for those
+// before it is returned with the error, so they can still be run.
+func (c *Compiler) Compile(src string) (string, error) {
+	return c.compile(src, 1)
+}
+
+// compile is Compile, for src that starts on the given line of a
+// longer program.
+func (c *Compiler) compile(src string, line int) (string, error) {
+	p := newParser(c, src, line)
+	if p.err != nil {
+		return ``, p.err
+	}
+	var dc strings.Builder
+	for {
+		// New lines are kept, so the interpreter's errors give the
+		// right line.
+		for t := p.peek(); t.kind == tokSeparator; t = p.peek() {
+			if t.text == "\n" {
+				dc.WriteString("\n")
+			}
+			p.advance()
+		}
+		if p.peek().kind == tokEOF {
+			return dc.String(), nil
+		}
+		stmt, err := p.statement()
+		if err != nil {
+			return dc.String(), err
+		}
+		fmt.Fprintf(&dc, `[%s]x`, stmt)
+	}
+}
+
+// CompileExpression compiles a single expression to dc commands that
+// push its value.
+func (c *Compiler) CompileExpression(src string) (string, error) {
+	p := newParser(c, src, 1)
+	if p.err != nil {
+		return ``, p.err
+	}
+	expr, err := p.expression()
+	if err != nil {
+		return ``, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return ``, p.errorf(t, `unexpected %s`, t)
+	}
+	return expr, nil
+}
+
+// Evaluate compiles an expression and runs it with i, returning its
+// value. Whatever's on i's stack is left there.
+func (c *Compiler) Evaluate(i *godc.Interpreter, src string) (*godc.Value, error) {
+	expr, err := c.CompileExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	depth := i.Stack.Len()
+	if _, err := i.EvalString(expr); err != nil {
+		return nil, err
+	}
+	if i.Stack.Len() != depth+1 {
+		return nil, fmt.Errorf(`%q did not leave a value`, src)
+	}
+	return i.Stack.Pop(), nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokName
+	tokOperator
+	tokSeparator
+)
+
+type token struct {
+	kind         tokenKind
+	text         string
+	line, column int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return `end of input`
+	case tokSeparator:
+		if t.text == "\n" {
+			return `end of line`
+		}
+	}
+	return fmt.Sprintf(`%q`, t.text)
+}
+
+// lex splits src into tokens. Numbers are digits, upper-case letters
+// for digits past 9, and a point, as dc reads them.
+func lex(src string, line int) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	start := 0
+	for n := 0; n < len(runes); {
+		r := runes[n]
+		column := n - start + 1
+		switch {
+		case r == '\n':
+			tokens = append(tokens, token{tokSeparator, "\n", line, column})
+			line, start = line+1, n+1
+			n++
+		case r == ';':
+			tokens = append(tokens, token{tokSeparator, `;`, line, column})
+			n++
+		case r == '#':
+			for n < len(runes) && runes[n] != '\n' {
+				n++
+			}
+		case unicode.IsSpace(r):
+			n++
+		case isDigit(r) || r == '.':
+			end := n
+			for end < len(runes) && (isDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[n:end]), line, column})
+			n = end
+		case isNameStart(r):
+			end := n
+			for end < len(runes) && (isNameStart(runes[end]) || runes[end] >= '0' && runes[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, token{tokName, string(runes[n:end]), line, column})
+			n = end
+		case strings.ContainsRune(`+-*/%^=()`, r):
+			tokens = append(tokens, token{tokOperator, string(r), line, column})
+			n++
+		default:
+			return nil, fmt.Errorf(`%w: line %d, column %d: unexpected %q`, ErrSyntax, line, column, r)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, line: line, column: len(runes) - start + 1}), nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'A' && r <= 'F'
+}
+
+func isNameStart(r rune) bool {
+	return r >= 'a' && r <= 'z' || r == '_'
+}
+
+// parser is a recursive descent parser, which writes the dc for each
+// part of the program as it's parsed.
+type parser struct {
+	compiler *Compiler
+	tokens   []token
+	pos      int
+	err      error
+}
+
+func newParser(c *Compiler, src string, line int) *parser {
+	tokens, err := lex(src, line)
+	if err != nil {
+		tokens = []token{{kind: tokEOF}}
+	}
+	return &parser{compiler: c, tokens: tokens, err: err}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf(`%w: line %d, column %d: %s`, ErrSyntax, t.line, t.column, fmt.Sprintf(format, args...))
+}
+
+// expect consumes the operator op, or returns an error.
+func (p *parser) expect(op string) error {
+	t := p.peek()
+	if t.kind != tokOperator || t.text != op {
+		return p.errorf(t, `expected %q; found %s`, op, t)
+	}
+	p.advance()
+	return nil
+}
+
+// statement parses an assignment or an expression to print, up to the
+// next separator.
+func (p *parser) statement() (string, error) {
+	var stmt string
+	if t := p.peek(); t.kind == tokName && p.peekAt(1).text == `=` {
+		p.advance()
+		p.advance()
+		expr, err := p.expression()
+		if err != nil {
+			return ``, err
+		}
+		if special, ok := specials[t.text]; ok {
+			stmt = expr + special[0]
+		} else if _, ok := functions[t.text]; ok || constants[t.text] != `` {
+			return ``, p.errorf(t, `can't assign to %s`, t.text)
+		} else {
+			stmt = fmt.Sprintf(`%ss%c`, expr, p.compiler.assign(t.text))
+		}
+	} else {
+		expr, err := p.expression()
+		if err != nil {
+			return ``, err
+		}
+		stmt = expr + `pR`
+	}
+	if t := p.peek(); t.kind != tokSeparator && t.kind != tokEOF {
+		return ``, p.errorf(t, `unexpected %s`, t)
+	}
+	return stmt, nil
+}
+
+// expression parses terms joined by + and -.
+func (p *parser) expression() (string, error) {
+	return p.binary(p.term, `+-`)
+}
+
+// term parses powers joined by *, / and %.
+func (p *parser) term() (string, error) {
+	return p.binary(p.power, `*/%`)
+}
+
+// binary parses operands joined by any of the operators ops, which
+// associate to the left.
+func (p *parser) binary(operand func() (string, error), ops string) (string, error) {
+	left, err := operand()
+	if err != nil {
+		return ``, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOperator || !strings.Contains(ops, t.text) {
+			return left, nil
+		}
+		p.advance()
+		right, err := operand()
+		if err != nil {
+			return ``, err
+		}
+		left = fmt.Sprintf(`%s %s%s`, left, right, binaryOperators[[]rune(t.text)[0]])
+	}
+}
+
+// power parses a unary expression, raised to a power if there's a ^.
+func (p *parser) power() (string, error) {
+	base, err := p.unary()
+	if err != nil {
+		return ``, err
+	}
+	if t := p.peek(); t.kind != tokOperator || t.text != `^` {
+		return base, nil
+	}
+	p.advance()
+	exponent, err := p.power()
+	if err != nil {
+		return ``, err
+	}
+	return fmt.Sprintf(`%s %s^`, base, exponent), nil
+}
+
+// unary parses a primary expression, negated by any number of -.
+func (p *parser) unary() (string, error) {
+	if t := p.peek(); t.kind == tokOperator && t.text == `-` {
+		p.advance()
+		operand, err := p.unary()
+		if err != nil {
+			return ``, err
+		}
+		return fmt.Sprintf(`0 %s-`, operand), nil
+	}
+	return p.primary()
+}
+
+// primary parses a number, a variable, a function call or an
+// expression in parentheses.
+func (p *parser) primary() (string, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokNumber:
+		if strings.Count(t.text, `.`) > 1 {
+			return ``, p.errorf(t, `invalid number %q`, t.text)
+		}
+		return t.text, nil
+	case tokName:
+		if next := p.peek(); next.kind == tokOperator && next.text == `(` {
+			return p.call(t)
+		}
+		if special, ok := specials[t.text]; ok {
+			return special[1], nil
+		}
+		r, ok := p.compiler.Register(t.text)
+		if !ok {
+			return ``, fmt.Errorf(`%w: line %d, column %d: %s`, ErrUndefined, t.line, t.column, t.text)
+		}
+		return fmt.Sprintf(`l%c`, r), nil
+	case tokOperator:
+		if t.text == `(` {
+			expr, err := p.expression()
+			if err != nil {
+				return ``, err
+			}
+			return expr, p.expect(`)`)
+		}
+	}
+	return ``, p.errorf(t, `unexpected %s`, t)
+}
+
+// call parses the arguments to the function named by t.
+func (p *parser) call(t token) (string, error) {
+	p.advance()
+	if command, ok := constants[t.text]; ok {
+		return command, p.expect(`)`)
+	}
+	command, ok := functions[t.text]
+	if !ok {
+		return ``, p.errorf(t, `unknown function %s`, t.text)
+	}
+	arg, err := p.expression()
+	if err != nil {
+		return ``, err
+	}
+	return arg + command, p.expect(`)`)
+}
@@ -0,0 +1,99 @@
+package infix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestCompile(t *testing.T) {
+	expect := func(src, expected string) {
+		interpreter := godc.NewInterpreter()
+		out := new(strings.Builder)
+		interpreter.SetOutput(out)
+		dc, err := NewCompiler().Compile(src)
+		if err != nil {
+			t.Fatalf(`could not compile %q: %v`, src, err)
+		}
+		if _, err := interpreter.EvalString(dc); err != nil {
+			t.Fatalf(`could not run %q, compiled to %q: %v`, src, dc, err)
+		}
+		if out.String() != expected {
+			t.Fatalf(`expected %q to print %q; found %q`, src, expected, out.String())
+		}
+		if interpreter.Stack.Len() != 0 {
+			t.Fatalf(`expected %q to leave the stack empty; found %d values`, src, interpreter.Stack.Len())
+		}
+	}
+
+	expect(`1 + 2 * 3`, "7\n")
+	expect(`(1 + 2) * 3`, "9\n")
+	expect(`10 - 4 - 3; 2 ^ 3 ^ 2`, "3\n512\n")
+	expect(`-2 ^ 2; -(2 ^ 2); 2 - -1`, "4\n-4\n3\n")
+	expect(`17 % 5; 7 / 2`, "2\n3\n")
+	expect("scale = 3\n1 / 3\nscale", "0.333\n3.000\n")
+	expect("x = 3\ntotal = x * 2 # doubled\ntotal + x", "9\n")
+	expect(`x = 1; x = x + 1; x`, "2\n")
+	expect(`scale = 2; sqrt(2); scale = 0; length(12345); scale(1.50)`, "1.41\n5\n2\n")
+	expect(`scale = 4; pi(); exp(0); ln(1)`, "3.1415\n1.0000\n0.0000\n")
+	expect(`obase = 16; 255; ibase = 16; FF`, "FF\nFF\n")
+	expect("\n\n;;", ``)
+}
+
+func TestCompileErrors(t *testing.T) {
+	expect := func(src string, expected error, message string) {
+		_, err := NewCompiler().Compile(src)
+		if !errors.Is(err, expected) || !strings.Contains(err.Error(), message) {
+			t.Fatalf(`expected %q to fail with %v, %q; found %v`, src, expected, message, err)
+		}
+	}
+
+	expect(`1 +`, ErrSyntax, `line 1, column 4: unexpected end of input`)
+	expect("1\n(2", ErrSyntax, `line 2, column 3: expected ")"`)
+	expect(`1 2`, ErrSyntax, `unexpected "2"`)
+	expect(`1 $ 2`, ErrSyntax, `column 3: unexpected '$'`)
+	expect(`1..2`, ErrSyntax, `invalid number`)
+	expect(`sqrt = 2`, ErrSyntax, `can't assign to sqrt`)
+	expect(`cos(1)`, ErrSyntax, `unknown function cos`)
+	expect(`x + 1`, ErrUndefined, `x`)
+	expect(`x = x + 1`, ErrUndefined, `x`)
+
+	// The statements before one that can't be compiled can still be run.
+	c := NewCompiler()
+	dc, err := c.Compile("x = 5\ny +")
+	if err == nil || dc != "[5sx]x\n" {
+		t.Fatalf(`expected the assignment to be compiled before the error; found %q, %v`, dc, err)
+	}
+	if r, ok := c.Register(`x`); !ok || r != 'x' {
+		t.Fatalf(`expected x to be kept in register x; found %q, %v`, r, ok)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	c := NewCompiler()
+	interpreter := godc.NewInterpreter()
+	if _, err := c.Compile(`rate = 0; x = 0`); err != nil {
+		t.Fatal(err)
+	}
+	interpreter.Stack.Push(godc.NewValueFromString(`untouched`))
+	for _, tc := range []struct{ rate, x, expected int64 }{{2, 3, 7}, {10, 1, 11}} {
+		r, _ := c.Register(`rate`)
+		interpreter.Register(r).Push(godc.NewValueFromInt64(tc.rate))
+		interpreter.Register('x').Push(godc.NewValueFromInt64(tc.x))
+		v, err := c.Evaluate(interpreter, `rate * x + 1`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.String() != godc.NewValueFromInt64(tc.expected).String() {
+			t.Fatalf(`expected %d; found %s`, tc.expected, v)
+		}
+	}
+	if interpreter.Stack.Len() != 1 {
+		t.Fatalf(`expected the stack to be left alone; found %d values`, interpreter.Stack.Len())
+	}
+	if _, err := c.Evaluate(interpreter, `x = 1`); !errors.Is(err, ErrSyntax) {
+		t.Fatalf(`expected an assignment not to be an expression; found %v`, err)
+	}
+}
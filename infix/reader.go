@@ -0,0 +1,52 @@
+package infix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// reader compiles what it reads a line at a time, so that it can be
+// used interactively.
+type reader struct {
+	in       *bufio.Reader
+	compiler *Compiler
+	errors   io.Writer
+	line     int
+	buff     []byte
+}
+
+// NewReader returns a reader of the dc compiled from the program read
+// from r, so it can be given to godc.Interpreter.Run. Each line is
+// compiled as soon as it's read, with compiler, and a line that can't
+// be compiled is reported to errors and the rest of it skipped.
+func NewReader(r io.Reader, compiler *Compiler, errors io.Writer) io.Reader {
+	return &reader{in: bufio.NewReader(r), compiler: compiler, errors: errors}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buff) == 0 {
+		line, err := r.in.ReadString('\n')
+		if line != `` {
+			r.line++
+			dc, cerr := r.compiler.compile(line, r.line)
+			if cerr != nil {
+				fmt.Fprintf(r.errors, "error compiling: %v\n", cerr)
+				if strings.HasSuffix(line, "\n") {
+					dc += "\n"
+				}
+			}
+			r.buff = []byte(dc)
+		}
+		if err != nil && len(r.buff) == 0 {
+			return 0, err
+		}
+		if err != nil {
+			break
+		}
+	}
+	n := copy(p, r.buff)
+	r.buff = r.buff[n:]
+	return n, nil
+}
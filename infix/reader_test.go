@@ -0,0 +1,27 @@
+package infix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/godc"
+)
+
+func TestReader(t *testing.T) {
+	interpreter := godc.NewInterpreter()
+	out, errs := new(strings.Builder), new(strings.Builder)
+	interpreter.SetOutput(out)
+	interpreter.SetErrorOutput(errs)
+	src := "a = 6\nb = a *\nb = a * 7\nb\nb / 0\nb - 2"
+	if err := interpreter.Run(NewReader(strings.NewReader(src), NewCompiler(), errs)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "42\n40\n" {
+		t.Fatalf(`expected the statements that work to print; found %q`, out.String())
+	}
+	expected := "error compiling: syntax error: line 2, column 8: unexpected end of line\n" +
+		"error processing command: line 5, column 10 > macro character 5: divide by zero\n"
+	if errs.String() != expected {
+		t.Fatalf(`expected the bad lines to be reported as %q; found %q`, expected, errs.String())
+	}
+}
@@ -1,32 +1,112 @@
-package main
+// Package godc is a dc-compatible desk calculator engine. An
+// Interpreter takes dc commands a rune at a time, and keeps the main
+// stack and registers of Values. The godc command in cmd/godc is a
+// thin front end for it.
+package godc
 
 import (
+	"bufio"
+	"context"
+	crand "crypto/rand"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 )
 
 // ErrStackTooShort is returned when an operation wants more
 // arguments than are available.
 var ErrStackTooShort = fmt.Errorf(`stack too short`)
 
+// DefaultLineLength is the width printed numbers are wrapped to,
+// matching GNU dc.
+const DefaultLineLength = 70
+
 // ErrExitRequested is returned when an operation asks to quit
 // the program or the currently running macro.
 var ErrExitRequested = fmt.Errorf(`goodbye`)
 
+// ErrStepLimitExceeded is returned when an interpreter has run as
+// many commands as its StepLimit allows.
+var ErrStepLimitExceeded = fmt.Errorf(`step limit exceeded`)
+
+// ErrOperationExists is returned by RegisterOperation when the rune
+// is already a command or a digit.
+var ErrOperationExists = fmt.Errorf(`rune is already bound to an operation`)
+
 // Interpreter interprets commands and macros and maintains
 // the main stack and the various registers.
 type Interpreter struct {
-	Stack            *Stack
-	Registers        map[rune]*Stack
-	NumberBuilder    *NumberBuilder
-	Precision        int64
-	CurrentOperation Operation
-	Operations       map[rune]Operation
-	output           io.Writer
-	QuitLevel        int64
-	InputRadix       uint8
-	OutputRadix      uint8
+	Stack             *Stack
+	Registers         map[rune]*Stack
+	Arrays            map[rune]*Array
+	NumberBuilder     *NumberBuilder
+	Scale             int64
+	Precision         int64
+	SeparatePrecision bool
+	RoundingMode      RoundingMode
+	ByteStrings       bool
+	Notation          Notation
+	SignificantDigits int64
+	FixedScale        bool   // whether every number is kept at the scale
+	GroupSeparator    string // written between each three digits of whole numbers printed in decimal
+	DecimalSeparator  string // printed for the point in numbers, if it isn't a point
+	CurrentOperation  Operation
+	Operations        map[rune]Operation // change with RegisterOperation
+	Extensions        ExtensionSet
+	Random            io.Reader
+	output            io.Writer
+	out               *bufio.Writer // buffers what's written to output
+	errorOutput       io.Writer
+	input             *bufio.Reader
+	QuitLevel         int64
+	QuitOneLevel      bool // whether q only leaves the macro it's in, as godc's q used to
+	Strict            bool // whether to accept only dc's own commands, and behave as GNU dc does
+	InputRadix        uint8
+	OutputRadix       int64
+	LineLength        int
+	LastError         *DCError
+	StepLimit         int64               // the most commands to run, or 0 for no limit
+	MaxNumberBits     int64               // the most bits in a number's numerator or denominator, or 0 for no limit
+	MaxStringLength   int64               // the most characters in a string, or 0 for no limit
+	MaxStackDepth     int64               // the most values on the stack or in a register, or 0 for no limit
+	Steps             int64               // the number of commands run so far
+	StackSnapshots    bool                // whether errors carry a copy of the whole stack
+	ErrorPolicy       ErrorPolicy         // what a failing command does to the macro running it
+	Debugger          *Debugger           // stops the program to look around, if it's set
+	Trace             bool                // whether to write each command run to the error output
+	Profile           *Profile            // counts and times commands and macros, if it's set
+	Color             bool                // whether to color printed numbers and strings for a terminal
+	ColorErrors       bool                // whether to color reported errors for a terminal
+	PrintHook         PrintHook           // called with each value printed, if it's set
+	NewStackBackend   func() StackBackend // makes the backend of each new register and named stack, if it's set
+	hooks             []Hook
+	arrayLevels       map[rune][]*Array // the arrays S has put aside, for L to bring back
+	stacks            map[string]*Stack // the main stacks not in use, by name
+	stackName         string            // the name of the main stack in use, if it isn't the default
+	pi                *constant
+	e                 *constant
+	command           rune
+	extension         string // the name of the extension being run
+	ctx               context.Context
+	frames            []*frame
+	base              int  // the first frame of the innermost InterpretMacro
+	running           bool // whether InterpretMacro is running
+	macroQuit         bool // whether the QuitLevel was set by Q, which never quits
+	version           int  // changed whenever Operations is, so macros are compiled again
+	macros            *macroCache
+	line, column      int // where the next rune of input is
+	runeLine          int // where the rune being interpreted is
+	runeColumn        int
+	commandLine       int // where the command being run started
+	commandColumn     int
+	commandAt         int  // where the command started in the innermost macro
+	commandDepth      int  // how many macros deep the command is
+	traced            bool // whether the command has been traced
+	quit              bool // whether Run was stopped by a q command
 }
 
 // NewInterpreter intitializes an interpreter and its
@@ -35,33 +115,41 @@ func NewInterpreter() *Interpreter {
 	i := new(Interpreter)
 	i.Stack = new(Stack)
 	i.Registers = make(map[rune]*Stack)
-	for r := 'a'; r <= 'z'; r++ {
-		i.Registers[r] = new(Stack)
-	}
-	i.output = os.Stdout
+	i.Arrays = make(map[rune]*Array)
+	i.SetOutput(os.Stdout)
+	i.errorOutput = os.Stderr
+	i.input = bufio.NewReader(os.Stdin)
 	i.InputRadix = 10
 	i.OutputRadix = 10
+	i.LineLength = DefaultLineLength
+	i.SignificantDigits = DefaultSignificantDigits
+	i.Random = crand.Reader
+	i.pi = &constant{compute: piFixed}
+	i.e = &constant{compute: eFixed}
+	i.macros = newMacroCache(DefaultMacroCacheSize)
+	i.ResetPosition()
+	// Hungry operations keep the state of a command between runes, so
+	// every interpreter needs instances of its own.
+	i.NumberBuilder = NewNumberBuilder()
 	i.Operations = map[rune]Operation{
-		'0': NumberBuilderOperation,
-		'1': NumberBuilderOperation,
-		'2': NumberBuilderOperation,
-		'3': NumberBuilderOperation,
-		'4': NumberBuilderOperation,
-		'5': NumberBuilderOperation,
-		'6': NumberBuilderOperation,
-		'7': NumberBuilderOperation,
-		'8': NumberBuilderOperation,
-		'9': NumberBuilderOperation,
-		'A': NumberBuilderOperation,
-		'B': NumberBuilderOperation,
-		'C': NumberBuilderOperation,
-		'D': NumberBuilderOperation,
-		'E': NumberBuilderOperation,
-		'F': NumberBuilderOperation,
-		'G': NumberBuilderOperation,
-		'H': NumberBuilderOperation,
-		'.': NumberBuilderOperation,
-		'_': NumberBuilderOperation,
+		'0': i.NumberBuilder,
+		'1': i.NumberBuilder,
+		'2': i.NumberBuilder,
+		'3': i.NumberBuilder,
+		'4': i.NumberBuilder,
+		'5': i.NumberBuilder,
+		'6': i.NumberBuilder,
+		'7': i.NumberBuilder,
+		'8': i.NumberBuilder,
+		'9': i.NumberBuilder,
+		'A': i.NumberBuilder,
+		'B': i.NumberBuilder,
+		'C': i.NumberBuilder,
+		'D': i.NumberBuilder,
+		'E': i.NumberBuilder,
+		'F': i.NumberBuilder,
+		'.': i.NumberBuilder,
+		'_': i.NumberBuilder,
 		'q': QuitOperation,
 		'p': PrintOperation,
 		'P': PrintRawOperation, // Prints the raw bytes in the number representation
@@ -79,44 +167,253 @@ func NewInterpreter() *Interpreter {
 		'c': ClearStackOperation,
 		'd': DuplicationOperation,
 		'r': ReverseOperation,
-		's': MoveToRegisterOperation,
-		'l': MoveFromRegisterOperation,
-		'S': MoveToRegisterStackOperation,
-		'L': MoveFromRegisterStackOperation,
-		'k': SetPrecisionOperation,
-		'i': SetInputRadixOperation,        // TODO: set input radix
-		'o': SetOutputRadixOperation,       // TODO: set output radix
-		'I': GetInputRadixOperation,        // TODO: get input radix
-		'O': GetOutputRadixOperation,       // TODO: get output radix
-		'[': StringBuilderOperation,        // begin string
-		'a': NotImplementedOperation,       // TODO: chr(i) (for int) or s[0] (for string)
-		'x': ExecuteMacroOperation,         // execute macro
-		'>': ExecuteMacroIfGTOperation,     // conditional execute macro
-		'!': ExecuteMacroNegativeOperation, // conditional execute macro
-		'<': ExecuteMacroIfLTOperation,     // conditional execute macro
-		'=': ExecuteMacroIfEqOperation,     // conditional execute macro
-		'?': NotImplementedOperation,       // TODO: get input from STDIN
-		'Q': MacroQuitOperation,            // exit n macros
-		'Z': NotImplementedOperation,       // TODO: len(v.String())
-		'X': NotImplementedOperation,       // TODO: number of fractional digits.
+		'R': DropOperation,
+		's': &RegisterOperation{Func: moveToRegister, Level: (*Interpreter).dropArrayLevels},
+		'l': &RegisterOperation{Func: moveFromRegister},
+		'S': &RegisterOperation{Func: moveToRegisterStack, Level: (*Interpreter).pushArray},
+		'L': &RegisterOperation{Func: moveFromRegisterStack, Level: (*Interpreter).popArray},
+		'k': SetScaleOperation,
+		'K': GetScaleOperation,
+		'i': SetInputRadixOperation,                // set input radix
+		'o': SetOutputRadixOperation,               // set output radix
+		'I': GetInputRadixOperation,                // get input radix
+		'O': GetOutputRadixOperation,               // get output radix
+		'[': new(StringBuilder),                    // begin string
+		'a': ToCharacterOperation,                  // chr(i) (for int) or s[0] (for string)
+		'x': ExecuteMacroOperation,                 // execute macro
+		'>': &MacroOperation{Predicate: isGreater}, // conditional execute macro
+		'!': new(NegativeMacroOperation),           // conditional execute macro
+		'<': &MacroOperation{Predicate: isLess},    // conditional execute macro
+		'=': &MacroOperation{Predicate: isEqual},   // conditional execute macro
+		'?': ReadLineOperation,                     // read a line of input and execute it
+		'Q': MacroQuitOperation,                    // exit n macros
+		'Z': LengthOperation,                       // number of digits or characters
+		'X': ScaleOperation,                        // number of fractional digits
 		'z': PushLengthOperation,
 		'#': CommentOperator,
-		':': NotImplementedOperation, // TODO: push to specific index in register
-		';': NotImplementedOperation, // TODO: fetch from specific index in register
+		':': &ArrayOperation{Func: storeToArray},  // store to specific index in register's array
+		';': &ArrayOperation{Func: loadFromArray}, // fetch from specific index in register's array
+		'{': new(ExtensionDispatcher),             // godc-specific {name} commands
 	}
+	i.Extensions = make(ExtensionSet)
+	i.LoadExtensions(UnitExtensions)
+	i.LoadExtensions(DateTimeExtensions)
+	i.LoadExtensions(DiagnosticExtensions)
+	i.LoadExtensions(DisplayExtensions)
+	i.LoadExtensions(RoundingExtensions)
+	i.LoadExtensions(BitwiseExtensions)
+	i.LoadExtensions(NumberTheoryExtensions)
+	i.LoadExtensions(RandomExtensions)
+	i.LoadExtensions(MathLibraryExtensions)
+	i.LoadExtensions(MathExtensions)
+	i.LoadExtensions(StringExtensions)
+	i.LoadExtensions(StackExtensions)
+	i.LoadExtensions(ShuffleExtensions)
+	i.LoadExtensions(FixedPointExtensions)
+	i.LoadExtensions(LocaleExtensions)
+	i.LoadExtensions(ExtensionSet{
+		// {clearreg} is hungry for a register name, so it needs an
+		// instance of its own too.
+		`clearreg`: new(ClearRegisterOperation),
+		`reset`:    ResetOperation,
+	})
 	return i
 }
 
+// RegisterOperation binds op to the command r, so embedders can add
+// commands of their own. It returns ErrOperationExists rather than
+// replace a built-in command, or a rune that's always read as part of
+// a number. Letters after F are only digits in large input radixes,
+// where they're read as digits before commands bound to them.
+func (i *Interpreter) RegisterOperation(r rune, op Operation) error {
+	if _, ok := i.LookupOperation(r); ok {
+		return fmt.Errorf(`%w: %q`, ErrOperationExists, r)
+	}
+	i.Operations[r] = op
+	i.version++
+	return nil
+}
+
+// LookupOperation returns the operation bound to the command r, and
+// whether there is one. Digits are bound to the NumberBuilder.
+func (i *Interpreter) LookupOperation(r rune) (Operation, bool) {
+	if isDigit(r, 0) {
+		return i.NumberBuilder, true
+	}
+	op, ok := i.Operations[r]
+	return op, ok
+}
+
+// CommandInfo describes a command, for help and completion.
+type CommandInfo struct {
+	Rune        rune
+	Description string // what the command does, or empty if it's not known
+	Register    bool   // whether a register name follows the command
+}
+
+// Commands describes the commands bound to runes, in order of their
+// runes. Digits aren't included.
+func (i *Interpreter) Commands() []CommandInfo {
+	commands := make([]CommandInfo, 0, len(i.Operations))
+	for r, op := range i.Operations {
+		if _, ok := op.(*NumberBuilder); ok {
+			continue
+		}
+		info := CommandInfo{Rune: r, Description: commandNames[r]}
+		switch op.(type) {
+		case *RegisterOperation, *ArrayOperation, *MacroOperation:
+			info.Register = true
+		}
+		commands = append(commands, info)
+	}
+	sort.Slice(commands, func(a, b int) bool { return commands[a].Rune < commands[b].Rune })
+	return commands
+}
+
+// Register returns the stack belonging to the named register. Any
+// rune can name a register; they're created the first time they're
+// used.
+func (i *Interpreter) Register(r rune) *Stack {
+	if i.Debugger != nil {
+		i.Debugger.usingRegister(i, r)
+	}
+	reg, ok := i.Registers[r]
+	if !ok {
+		reg = i.newStack()
+		i.Registers[r] = reg
+	}
+	return reg
+}
+
+// Array returns the array belonging to the named register, creating
+// it the first time it's used.
+func (i *Interpreter) Array(r rune) *Array {
+	if i.Debugger != nil {
+		i.Debugger.usingRegister(i, r)
+	}
+	arr, ok := i.Arrays[r]
+	if !ok {
+		arr = new(Array)
+		i.Arrays[r] = arr
+	}
+	return arr
+}
+
+// SetInput sets where the '?' command reads lines from. A front end
+// that reads commands from the same stream should pass the same
+// *bufio.Reader, so the two share its buffer.
+func (i *Interpreter) SetInput(r io.Reader) {
+	if br, ok := r.(*bufio.Reader); ok {
+		i.input = br
+		return
+	}
+	i.input = bufio.NewReader(r)
+}
+
+// Input returns the reader the '?' command reads lines from. It's
+// standard input unless SetInput has changed it.
+func (i *Interpreter) Input() *bufio.Reader {
+	return i.input
+}
+
+// SetOutput sets where printing commands such as p, n, f and P write
+// to. It's standard output by default. Anything still buffered for the
+// old writer is flushed to it first.
+func (i *Interpreter) SetOutput(w io.Writer) {
+	if i.out != nil {
+		i.out.Flush()
+	}
+	i.output = w
+	i.out = bufio.NewWriter(w)
+}
+
+// Output returns the writer that printing commands write to. What
+// they print is buffered, so call FlushOutput before writing to it
+// directly.
+func (i *Interpreter) Output() io.Writer {
+	return i.output
+}
+
+// FlushOutput writes anything printed that's still buffered to the
+// output. Run, EvalString and Interpret flush it themselves before
+// they return, before waiting for more input, and before reporting an
+// error, so it's only needed by operations and extensions that write
+// to Output directly.
+func (i *Interpreter) FlushOutput() error {
+	return i.out.Flush()
+}
+
+// SetErrorOutput sets where Run reports errors to, so they don't get
+// mixed up with what's printed. It's standard error by default.
+func (i *Interpreter) SetErrorOutput(w io.Writer) {
+	i.errorOutput = w
+}
+
+// ErrorOutput returns the writer that errors are reported to.
+func (i *Interpreter) ErrorOutput() io.Writer {
+	return i.errorOutput
+}
+
+// Format writes v out the way the interpreter prints it, in the
+// output radix, precision, rounding mode and notation, but without
+// wrapping long numbers or coloring it.
+func (i *Interpreter) Format(v *Value) string {
+	if v.Type == VTString {
+		return string(i.encodeString(v.strval))
+	}
+	if i.Strict {
+		// dc writes each number to its own scale, and without a 0
+		// before the point.
+		str := v.Text(i.OutputRadix, v.scale)
+		if strings.HasPrefix(str, `0.`) || strings.HasPrefix(str, `-0.`) {
+			str = strings.Replace(str, `0.`, `.`, 1)
+		}
+		return str
+	}
+	if i.Notation == NotationFixed {
+		return v.RoundedText(i.OutputRadix, i.Precision, i.RoundingMode)
+	}
+	return v.NotationText(i.OutputRadix, i.Notation, i.SignificantDigits, i.RoundingMode)
+}
+
+// text formats a value for printing in the current output radix,
+// precision and rounding mode, with the separators of the locale, if
+// they're set. Numbers longer than LineLength are wrapped, with a
+// backslash at the end of each broken line. A LineLength of 0
+// disables wrapping.
+func (i *Interpreter) text(v *Value) string {
+	str := i.Format(v)
+	if v.Type != VTNumber {
+		return str
+	}
+	str = i.localize(str)
+	if i.LineLength < 2 {
+		return str
+	}
+	width := i.LineLength - 1
+	if len(str) <= width {
+		return str
+	}
+	b := new(strings.Builder)
+	for len(str) > width {
+		b.WriteString(str[:width])
+		b.WriteString("\\\n")
+		str = str[width:]
+	}
+	b.WriteString(str)
+	return b.String()
+}
+
 func (i *Interpreter) print(args ...interface{}) {
-	fmt.Fprint(i.output, args...)
+	fmt.Fprint(i.out, args...)
 }
 
 func (i *Interpreter) printf(format string, args ...interface{}) {
-	fmt.Fprintf(i.output, format, args...)
+	fmt.Fprintf(i.out, format, args...)
 }
 
 func (i *Interpreter) println(args ...interface{}) {
-	fmt.Fprintln(i.output, args...)
+	fmt.Fprintln(i.out, args...)
 }
 
 // Interpret interprets one rune from input or a macro.
@@ -126,23 +423,166 @@ func (i *Interpreter) println(args ...interface{}) {
 // by macros to determine whether to raise that error
 // to calling macros or to continue on. Most other
 // errors are not fatal. They should be printed and
-// execution should continue.
+// execution should continue. They are wrapped in a
+// *DCError, which is also kept in LastError for {explain}.
+// Anything the command prints is flushed to the output before it
+// returns.
 func (i *Interpreter) Interpret(r rune) error {
-	var (
-		op Operation
-		ok bool
-	)
+	defer i.out.Flush()
+	return i.interpretInput(r)
+}
+
+// interpretInput is Interpret without flushing the output, for Run and
+// EvalString, which flush it less often.
+func (i *Interpreter) interpretInput(r rune) error {
+	if !i.running {
+		// Keep track of where r is in the input, for errors.
+		i.runeLine, i.runeColumn = i.line, i.column
+		if r == '\n' {
+			i.line, i.column = i.line+1, 1
+		} else {
+			i.column++
+		}
+	}
+	return i.interpret(r)
+}
+
+// interpret is Interpret for runes that aren't read from the input.
+func (i *Interpreter) interpret(r rune) error {
 	if i.CurrentOperation != nil {
-		op = i.CurrentOperation
-	} else {
-		op, ok = i.Operations[r]
-		if !ok {
+		return i.operate(i.CurrentOperation, r)
+	}
+	op, ok := i.commandFor(r)
+	if !ok {
+		if !i.Strict || unicode.IsSpace(r) {
 			return nil
 		}
+		if err := i.begin(r); err != nil {
+			return err
+		}
+		return i.recordError(fmt.Errorf(`%w: %q`, ErrUnknownCommand, r))
+	}
+	if err := i.begin(r); err != nil {
+		return err
+	}
+	return i.operate(op, r)
+}
+
+// nonDCCommands are the commands godc binds that dc doesn't have, which
+// strict interpreters don't know.
+const nonDCCommands = `{R`
+
+// commandFor returns the operation the command r runs, if there is
+// one.
+func (i *Interpreter) commandFor(r rune) (Operation, bool) {
+	if isDigit(i.numberRune(r), i.InputRadix) {
+		// In large radixes, letters are digits before they're commands.
+		return i.NumberBuilder, true
+	}
+	if i.Strict && strings.ContainsRune(nonDCCommands, r) {
+		return nil, false
+	}
+	op, ok := i.Operations[r]
+	return op, ok
+}
+
+// begin checks that another command may be run, and counts it.
+func (i *Interpreter) begin(r rune) error {
+	if i.ctx != nil {
+		if err := i.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if i.StepLimit > 0 && i.Steps >= i.StepLimit {
+		return ErrStepLimitExceeded
+	}
+	i.Steps++
+	if i.Profile != nil {
+		i.Profile.command(i)
+	}
+	i.command = r
+	i.extension = ``
+	i.commandDepth = len(i.frames)
+	i.traced = false
+	if !i.running {
+		i.commandLine, i.commandColumn = i.runeLine, i.runeColumn
+	} else if len(i.frames) > 0 {
+		i.commandAt = i.frames[len(i.frames)-1].at()
+	}
+	if i.Debugger != nil {
+		if err := i.Debugger.beforeCommand(i); err != nil {
+			return err
+		}
+	}
+	if len(i.hooks) > 0 {
+		return i.beforeHooks()
+	}
+	return nil
+}
+
+// position returns the position of the command being run.
+func (i *Interpreter) position() Position {
+	pos := Position{Line: i.commandLine, Column: i.commandColumn}
+	if !i.running {
+		return pos
+	}
+	for n, f := range i.frames {
+		at := f.at()
+		if n == len(i.frames)-1 {
+			at = i.commandAt
+		}
+		pos.Macros = append(pos.Macros, at+1)
+	}
+	return pos
+}
+
+// ResetPosition starts counting lines and columns from the beginning
+// again, for a new input. Run and EvalString call it themselves.
+func (i *Interpreter) ResetPosition() {
+	i.line, i.column = 1, 1
+}
+
+// operate passes r to op, keeping track of whether op is hungry for
+// more runes.
+func (i *Interpreter) operate(op Operation, r rune) error {
+	command := i.command
+	stack, limited := i.Stack, i.limited()
+	var low int
+	if limited {
+		low = stack.mark()
 	}
 	finished, err := op.Operate(i, r)
+	var pushed int
+	if limited {
+		pushed = stack.unmark(low)
+		if i.Stack != stack {
+			// It switched to a stack whose values were checked as they
+			// were pushed.
+			pushed = 0
+		}
+	}
 	if finished {
 		i.CurrentOperation = nil
+		if (err == nil || err == ErrContinueProcessingRune) && i.FixedScale {
+			i.fixScale()
+		}
+		if (err == nil || err == ErrContinueProcessingRune) && limited {
+			if lerr := i.checkResult(pushed); lerr != nil {
+				err = lerr
+			}
+		}
+		if i.Trace {
+			i.trace()
+		}
+		if len(i.hooks) > 0 {
+			if err == ErrContinueProcessingRune {
+				if herr := i.afterHooks(command, nil); herr != nil {
+					err = herr
+				}
+			} else {
+				err = i.afterHooks(command, err)
+			}
+		}
 	} else {
 		i.CurrentOperation = op
 	}
@@ -150,28 +590,344 @@ func (i *Interpreter) Interpret(r rune) error {
 		if i.CurrentOperation != nil {
 			panic(`operation returned !finished, ErrContinueProcessingRune`)
 		}
-		return i.Interpret(r)
+		return i.interpret(r)
+	}
+	if err != nil && err != ErrExitRequested {
+		return i.recordError(err)
 	}
 	return err
 }
 
-// InterpretMacro runs a macro sequence. The only difference between
-// this and the main loop is that the QuitLevel number is consulted
-// to determine how many layers of macro should be terminated when
-// a q or Q command is encountered.
-func (i *Interpreter) InterpretMacro(macro []rune) error {
-	for _, r := range macro {
-		err := i.Interpret(r)
+// InterpretContext is like Interpret, but gives up with ctx's error
+// if ctx is cancelled or its deadline passes before the command, and
+// any macros it runs, have finished. The context is checked before
+// each command, so a runaway loop such as [lax]salax can be stopped.
+func (i *Interpreter) InterpretContext(ctx context.Context, r rune) error {
+	defer i.withContext(ctx)()
+	return i.Interpret(r)
+}
+
+// RunContext is like Run, but stops with ctx's error if ctx is
+// cancelled or its deadline passes. It can't interrupt a read from r
+// that is blocked, so the reader should be closed as well to stop
+// waiting for input.
+func (i *Interpreter) RunContext(ctx context.Context, r io.Reader) error {
+	defer i.withContext(ctx)()
+	return i.Run(r)
+}
+
+// withContext makes the interpreter check ctx between commands, and
+// returns a function that puts back the context it had before.
+func (i *Interpreter) withContext(ctx context.Context) func() {
+	prev := i.ctx
+	i.ctx = ctx
+	return func() { i.ctx = prev }
+}
+
+// Run interprets commands from r until it runs out or a q command is
+// run. As in dc, an error in a command is reported to the error
+// output and the rest of the input is still run, so Run only returns an error if
+// r can't be read or the StepLimit is reached. '?' still reads from
+// the interpreter's Input, so a script can read standard input; to
+// have it read the next line of r instead, pass Run the same
+// *bufio.Reader as SetInput. What's printed is buffered, and flushed
+// whenever Run has to wait for more of r, so it's seen before any
+// prompt, and when Run returns.
+func (i *Interpreter) Run(r io.Reader) error {
+	src, ok := r.(*bufio.Reader)
+	if !ok {
+		src = bufio.NewReader(r)
+	}
+	i.ResetPosition()
+	i.quit = false
+	defer i.out.Flush()
+	defer func() {
+		if i.Profile != nil {
+			i.Profile.finish(i) // count the last command
+		}
+	}()
+	for {
+		if i.Profile != nil {
+			i.Profile.pause() // don't count waiting for input
+		}
+		if src.Buffered() == 0 {
+			i.out.Flush()
+		}
+		c, err := i.readCommand(src)
+		if err == io.EOF {
+			i.report(i.Flush())
+			return nil
+		}
 		if err != nil {
+			return err
+		}
+		err = i.interpretInput(c)
+		if err == ErrExitRequested {
+			i.quit = true
+			return nil
+		}
+		if i.fatal(err) {
+			if i.ctx != nil && i.ctx.Err() != nil {
+				return i.ctx.Err()
+			}
+			return err
+		}
+		i.report(err)
+	}
+}
+
+// Quit reports whether the last Run stopped because a q command was
+// run, rather than because the input ran out, so a program made of
+// several inputs knows not to go on to the next.
+func (i *Interpreter) Quit() bool {
+	return i.quit
+}
+
+// EvalString interprets src and returns what's left on the stack,
+// from the bottom to the top. Unlike Run, it stops at the first
+// error and returns it along with the stack as it was then. Like Run,
+// it reads src a byte at a time when strings are bytes.
+func (i *Interpreter) EvalString(src string) ([]*Value, error) {
+	i.ResetPosition()
+	defer i.out.Flush()
+	runes := []rune(src)
+	if i.ByteStrings {
+		runes = decodeBytes([]byte(src))
+	}
+	for _, r := range runes {
+		if err := i.interpretInput(r); err != nil {
 			if err == ErrExitRequested {
-				if i.QuitLevel == 0 {
-					continue
-				}
-				i.QuitLevel--
+				break
 			}
+			return i.Stack.bottomUp(), err
+		}
+	}
+	err := i.Flush()
+	return i.Stack.bottomUp(), err
+}
+
+// readCommand reads the next rune of src, or the next byte when
+// strings are bytes.
+func (i *Interpreter) readCommand(src *bufio.Reader) (rune, error) {
+	if i.ByteStrings {
+		b, err := src.ReadByte()
+		return rune(b), err
+	}
+	r, _, err := src.ReadRune()
+	return r, err
+}
+
+func (i *Interpreter) report(err error) {
+	if err == nil {
+		return
+	}
+	i.out.Flush() // so the error comes after what was printed before it
+	if i.ColorErrors {
+		fmt.Fprintf(i.errorOutput, "%serror processing command: %v%s\n", colorError, err, colorReset)
+		return
+	}
+	fmt.Fprintln(i.errorOutput, `error processing command:`, err)
+}
+
+// Flush finishes a number that's still being entered, as at the end
+// of the input.
+func (i *Interpreter) Flush() error {
+	nb, ok := i.CurrentOperation.(*NumberBuilder)
+	if !ok {
+		return nil
+	}
+	i.CurrentOperation = nil
+	if err := nb.Flush(i); err != nil {
+		return i.recordError(err)
+	}
+	if i.FixedScale {
+		i.fixScale()
+	}
+	if err := i.checkResult(1); err != nil {
+		return i.recordError(err)
+	}
+	if len(i.hooks) > 0 {
+		if err := i.afterHooks(i.command, nil); err != nil {
+			return i.recordError(err)
+		}
+	}
+	return nil
+}
+
+// frame is a macro being run, and how far through it the
+// interpreter has got. If the macro has been compiled, pc counts
+// through its program, and otherwise through its runes.
+type frame struct {
+	macro []rune
+	prog  *program
+	pc    int
+	// depth is the number of macro calls the frame stands for. A
+	// macro that calls another as its last command is replaced by
+	// it, but q and Q still count it.
+	depth   int64
+	started time.Time // when it was called, if the interpreter is profiled
+}
+
+// done reports whether the frame has nothing left to run.
+func (f *frame) done() bool {
+	if f.prog != nil {
+		return f.pc == len(f.prog.code)
+	}
+	return f.pc == len(f.macro)
+}
+
+// at returns the offset in the macro of the rune the frame ran last.
+func (f *frame) at() int {
+	if f.prog != nil {
+		return f.prog.code[f.pc-1].at
+	}
+	return f.pc - 1
+}
+
+// step runs the frame's next instruction, or its next rune.
+func (f *frame) step(i *Interpreter) error {
+	if f.prog != nil {
+		in := &f.prog.code[f.pc]
+		if in.arg || i.CurrentOperation == nil && f.prog.usable(i) {
+			f.pc++
+			return in.run(i)
+		}
+		// The program no longer reads the macro the way the
+		// interpreter would, so carry on a rune at a time.
+		f.prog, f.pc = nil, in.from
+	}
+	r := f.macro[f.pc]
+	f.pc++
+	return i.interpret(r)
+}
+
+// InterpretMacro runs a macro sequence, and any macros it calls,
+// before returning. The only difference between this and the main
+// loop is that the QuitLevel number is consulted to determine how
+// many layers of macro should be terminated when a q or Q command is
+// encountered.
+//
+// Macros don't call each other recursively: commands that run a
+// macro push it onto the interpreter's own stack of frames, and this
+// loop runs whichever is on top. A macro called as the last command
+// of another replaces it, so loops like [d1-d0<a]sa run in constant
+// space, however many times they go round.
+func (i *Interpreter) InterpretMacro(macro []rune) error {
+	if !i.running {
+		defer i.out.Flush()
+	}
+	return i.runMacro(&frame{macro: macro, depth: 1})
+}
+
+func (i *Interpreter) runMacro(f *frame) error {
+	base, running := i.base, i.running
+	i.base, i.running = len(i.frames), true
+	defer func() {
+		i.dropFrames(i.base)
+		i.base, i.running = base, running
+	}()
+	i.pushFrame(f)
+	for len(i.frames) > i.base {
+		f := i.frames[len(i.frames)-1]
+		if f.done() {
+			if f.prog != nil && f.prog.end < len(f.macro) && (i.CurrentOperation != nil || !f.prog.usable(i)) {
+				// The runes after the last instruction may mean something now.
+				f.prog, f.pc = nil, f.prog.end
+				continue
+			}
+			i.dropFrames(len(i.frames) - 1)
+			i.interpret(' ') // Make sure to flush any digit in the works
+			continue
+		}
+		err := f.step(i)
+		if err == ErrExitRequested {
+			err = i.quitMacros()
+		}
+		if err != nil && err != ErrExitRequested && (i.ErrorPolicy == ErrorPolicyContinue || i.Strict) && !i.fatal(err) {
+			i.report(err)
+			continue
+		}
+		if err != nil {
 			return err
 		}
 	}
-	i.Interpret(' ') // Make sure to flush any digit in the works
 	return nil
 }
+
+// callMacro runs a macro for a command, using its program if it has
+// been compiled. If a macro is already being run, the macro is pushed
+// to be run as soon as the command is done, so the command must have
+// nothing left to do.
+func (i *Interpreter) callMacro(macro []rune, prog *program) error {
+	f := &frame{macro: macro, prog: prog, depth: 1}
+	if !i.running {
+		if i.Trace {
+			i.trace() // before the macro's own commands
+		}
+		err := i.runMacro(f)
+		i.traced = true
+		return err
+	}
+	if top := len(i.frames) - 1; top >= i.base && i.frames[top].done() {
+		// A tail call: the caller has nothing left to run.
+		f.depth += i.frames[top].depth
+		i.dropFrames(top)
+	}
+	i.pushFrame(f)
+	return nil
+}
+
+// pushFrame starts running a macro.
+func (i *Interpreter) pushFrame(f *frame) {
+	if i.Profile != nil {
+		i.Profile.startMacro(f)
+	}
+	i.frames = append(i.frames, f)
+}
+
+// dropFrames leaves the macros above the first n frames.
+func (i *Interpreter) dropFrames(n int) {
+	if i.Profile != nil {
+		for _, f := range i.frames[n:] {
+			i.Profile.endMacro(f)
+		}
+	}
+	i.frames = i.frames[:n]
+}
+
+// quitMacros leaves as many macros as the QuitLevel asks after a q or
+// Q command. If there are more levels to leave than InterpretMacro was
+// running, it returns ErrExitRequested for its caller to carry on
+// quitting, except that Q stops at the top level: as in dc, it never
+// quits the program, but q, in a macro run from the top level, does.
+// With QuitOneLevel, q also quits when the one macro it leaves was the
+// last InterpretMacro was running, as godc's q used to.
+func (i *Interpreter) quitMacros() error {
+	for i.QuitLevel > 0 {
+		if len(i.frames) == i.base {
+			if i.macroQuit && i.base == 0 {
+				i.QuitLevel = 0
+				return nil
+			}
+			return ErrExitRequested
+		}
+		f := i.frames[len(i.frames)-1]
+		i.dropFrames(len(i.frames) - 1)
+		if i.QuitLevel < f.depth {
+			// The rest of the calls f stands for had finished anyway.
+			i.QuitLevel = 0
+			return nil
+		}
+		i.QuitLevel -= f.depth
+		if len(i.frames) == i.base && i.quitOneLevel() {
+			return ErrExitRequested
+		}
+	}
+	return nil
+}
+
+// quitOneLevel reports whether the last quit was a q that only leaves
+// the macro it's in.
+func (i *Interpreter) quitOneLevel() bool {
+	return i.QuitOneLevel && !i.Strict && !i.macroQuit
+}
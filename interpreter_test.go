@@ -1,9 +1,15 @@
-package main
+package godc
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func testWithInterpreter(interpreter *Interpreter, str string) error {
@@ -43,7 +49,7 @@ func expectWithInterpreter(buff *strings.Builder, values ...string) error {
 func TestBasicMath(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		err := testWithInterpreter(interpreter, str)
 		if err != nil {
@@ -160,6 +166,34 @@ func TestBasicMath(t *testing.T) {
 		expect(`61.74`)
 	})
 
+	t.Run(`zero and negative exponents`, func(t *testing.T) {
+		test(`0k2 0^`)
+		expect(`1`)
+
+		test(`3k2 _3^`)
+		expect(`0.125`)
+
+		test(`2k3 _1^`)
+		expect(`0.33`)
+	})
+
+	t.Run(`fractional exponents`, func(t *testing.T) {
+		test(`10k2 0.5^`)
+		expect(`1.4142135623`)
+
+		test(`10k10 _0.5^`)
+		expect(`0.3162277660`)
+
+		test(`20k3 3.3^`)
+		expect(`37.54050759852955219310`)
+
+		test(`4k0 2.5^`)
+		expect(`0.0000`)
+
+		test(`4k2 100.5^`)
+		expect(`1792728671193156477399422023278.6614`)
+	})
+
 	t.Run(`modular exponents`, func(t *testing.T) {
 		test(`0k2 8 7|`)
 		expect(`4`)
@@ -182,6 +216,15 @@ func TestBasicMath(t *testing.T) {
 		test(`4k1.41 2^v`)
 		expect(`1.4100`)
 
+		test(`10k2v`)
+		expect(`1.4142135623`)
+
+		test(`50k2v`)
+		expect(`1.41421356237309504880168872420969807856967187537694`)
+
+		test(`20k1 3/v`)
+		expect(`0.57735026918962576450`)
+
 	})
 
 }
@@ -189,7 +232,7 @@ func TestBasicMath(t *testing.T) {
 func TestRegisterOperations(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		err := testWithInterpreter(interpreter, str)
 		if err != nil {
@@ -226,16 +269,36 @@ func TestRegisterOperations(t *testing.T) {
 		expect(`45`, `34`, `89`, `67`, `12`)
 	})
 
+	t.Run(`loaded values are copies`, func(t *testing.T) {
+		test(`5sala1+la`)
+		expect(`5`, `6`)
+	})
+
 	t.Run(`save and retrieve strings`, func(t *testing.T) {
 		test(`[test A]sx[test B]sy[B]ly[A]lx`)
 		expect(`test A`, `A`, `test B`, `B`)
 	})
+
+	t.Run(`any rune names a register`, func(t *testing.T) {
+		test(`1sA2s.3s 4sπlAl.l lπ`)
+		expect(`4`, `3`, `2`, `1`)
+	})
+
+	t.Run(`run a macro from a punctuation register`, func(t *testing.T) {
+		test(`[50]s+0 1>+`)
+		expect(`50`)
+	})
+
+	t.Run(`arrays in any register`, func(t *testing.T) {
+		test(`0k7 3:Q3;Q`)
+		expect(`7`)
+	})
 }
 
 func TestMacroOperations(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		err := testWithInterpreter(interpreter, str)
 		if err != nil {
@@ -256,16 +319,88 @@ func TestMacroOperations(t *testing.T) {
 		expect(`5`)
 	})
 
+	t.Run(`macros that push macros`, func(t *testing.T) {
+		test(`[[1 2+]x]x`)
+		expect(`3`)
+
+		test(`[[[3 4*]x]x]x`)
+		expect(`12`)
+
+		test(`[[5 5+]sa]xlax`)
+		expect(`10`)
+	})
+
 	t.Run(`test 1-level exit`, func(t *testing.T) {
 		test(`[15 3/pq10*p]x`)
 		expect(`5`)
 	})
 
+	t.Run(`q leaves two macros`, func(t *testing.T) {
+		test(`[[[1p q 2p]x 3p]x 4p]x`)
+		expect(`1`, `4`, `4`, `1`)
+
+		// As dc's manual says, q only quits from the top level, or
+		// from a macro run from the top level.
+		test(`[[1p q 2p]x 3p]x 4p`)
+		expect(`1`, `4`, `4`, `1`)
+
+		test(`[1p q 2p]x 4p`)
+		expect(`1`)
+
+		interpreter.QuitOneLevel = true
+		defer func() { interpreter.QuitOneLevel = false }()
+		test(`[[1p q 2p]x 3p]x 4p`)
+		expect(`1`, `3`, `4`, `4`, `3`, `1`)
+
+		test(`[1p q 2p]x 3p`)
+		expect(`1`)
+	})
+
 	t.Run(`test multi-level exit`, func(t *testing.T) {
 		test(`[3Q][x1][x2][x3][x4][x5]x`)
 		expect(`5`, `4`, `3`)
 	})
 
+	t.Run(`Q never quits`, func(t *testing.T) {
+		test(`[[1p 5Q 2p]x 3p]x 4p`)
+		expect(`1`, `4`, `4`, `1`)
+
+		test(`1p 5Q 2p`)
+		expect(`1`, `2`, `2`, `1`)
+	})
+
+	t.Run(`exit across tail calls`, func(t *testing.T) {
+		// lbx is the last command of c, so b replaces c, but Q still
+		// counts c as a macro to leave.
+		test(`[5p2Q]sb[lbx]sc [lcx [6]]sd ldx [7]`)
+		expect(`5`, `7`, `6`, `5`)
+
+		test(`[5p3Q]sb[lbx]sc [lcx [6]]sd ldx [7]`)
+		expect(`5`, `7`, `5`)
+	})
+
+	t.Run(`tail calls run in constant space`, func(t *testing.T) {
+		depth := 0
+		interpreter.RegisterOperation('T', OperationAdapter(func(i *Interpreter) error {
+			if len(i.frames) > depth {
+				depth = len(i.frames)
+			}
+			return nil
+		}))
+		test(`[T1-d0<a]sa 1000 lax`)
+		expect(`0`)
+		if depth != 1 {
+			t.Fatalf(`expected the loop to use one frame; used %d`, depth)
+		}
+
+		depth = 0
+		test(`[lax]sb [T1-d0<b]sa 1000 lax`)
+		expect(`0`)
+		if depth != 1 {
+			t.Fatalf(`expected mutually recursive macros to use one frame; used %d`, depth)
+		}
+	})
+
 	t.Run(`run macro if gt`, func(t *testing.T) {
 		test(`[50]sa0 1>a`)
 		expect(`50`)
@@ -300,7 +435,7 @@ func TestMacroOperations(t *testing.T) {
 func TestNegativeMacroOperations(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		err := testWithInterpreter(interpreter, str)
 		if err != nil {
@@ -345,12 +480,55 @@ func TestNegativeMacroOperations(t *testing.T) {
 		test(`[nope][25 2*5+]sa1 1!=a`)
 		expect(`nope`)
 	})
+
+	t.Run(`string conditionals`, func(t *testing.T) {
+		test(`[[yes]]sa[abc][abc]=a`)
+		expect(`yes`)
+
+		test(`[no][[yes]]sa[abc][abd]=a`)
+		expect(`no`)
+
+		test(`[[yes]]sa[abc][abd]!=a`)
+		expect(`yes`)
+
+		test(`[[yes]]sa[abc][abd]>a`)
+		expect(`yes`)
+
+		test(`[no][[yes]]sa[abd][abc]>a`)
+		expect(`no`)
+	})
+
+	t.Run(`strings can't be compared with numbers`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `[[yes]]sa1[1]=a`)
+		if !errors.Is(err, ErrValueNotNumeric) {
+			t.Fatalf(`expected a type error; got %v`, err)
+		}
+		buff.Reset()
+	})
+
+	t.Run(`conditionals leave the macro in the register`, func(t *testing.T) {
+		test(`[7]sa1 2>a1 2>a`)
+		expect(`7`, `7`)
+	})
+
+	t.Run(`nested conditionals`, func(t *testing.T) {
+		test(`[[inner]]sb[[outer]1 2>b]sa1 2>a`)
+		expect(`inner`, `outer`)
+
+		test(`[[inner]]sb[[outer]2 1!>b]sa2 1!>a`)
+		expect(`inner`, `outer`)
+	})
+
+	t.Run(`loops`, func(t *testing.T) {
+		test(`0[1+d5>a]sa0 1>a`)
+		expect(`5`)
+	})
 }
 
 func TestRadixOperations(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		interpreter.InputRadix = 10
 		interpreter.OutputRadix = 10
@@ -432,6 +610,29 @@ func TestRadixOperations(t *testing.T) {
 		expect(`A`)
 	})
 
+	t.Run(`output radixes above 16`, func(t *testing.T) {
+		test(`0k100o_12345`)
+		expect(`- 01 23 45`)
+
+		test(`1k20o10.5`)
+		expect(`10. 10`)
+		interpreter.Scale, interpreter.Precision = 0, 0
+	})
+
+	t.Run(`output radix out of range`, func(t *testing.T) {
+		interpreter.OutputRadix = 10
+		for _, str := range []string{`1o`, `0o`, `_16o`, `2147483648o`} {
+			err := testWithInterpreter(interpreter, str)
+			if !errors.Is(err, ErrOutputRadixOutOfRange) {
+				t.Fatalf(`expected %q to be out of range; got %v`, str, err)
+			}
+			if interpreter.OutputRadix != 10 {
+				t.Fatalf(`expected the output radix not to change; was %d`, interpreter.OutputRadix)
+			}
+		}
+		buff.Reset()
+	})
+
 	t.Run(`output commands`, func(t *testing.T) {
 		test(`8oO`)
 		expect(`10`)
@@ -442,12 +643,113 @@ func TestRadixOperations(t *testing.T) {
 		test(`14iI`)
 		expect(`14`)
 	})
+
+	t.Run(`input radixes above 16`, func(t *testing.T) {
+		test(`36iZZ`)
+		expect(`1295`)
+
+		test(`20i1kJ.A`)
+		expect(`19.5`)
+		interpreter.Scale, interpreter.Precision = 0, 0
+
+		test(`36iZ Ai10`)
+		expect(`10`, `35`)
+
+		test(`17iG`)
+		expect(`16`)
+	})
+
+	t.Run(`letters past the radix are still commands`, func(t *testing.T) {
+		test(`12iI`)
+		expect(`12`)
+	})
+
+	t.Run(`input radix out of range`, func(t *testing.T) {
+		for _, str := range []string{`1i`, `_16i`, `37i`} {
+			err := testWithInterpreter(interpreter, str)
+			if !errors.Is(err, ErrInputRadixOutOfRange) {
+				t.Fatalf(`expected %q to be out of range; got %v`, str, err)
+			}
+			interpreter.Interpret('c')
+		}
+	})
+
+	t.Run(`digits out of range`, func(t *testing.T) {
+		interpreter.InputRadix = 2
+		err := testWithInterpreter(interpreter, `102 `)
+		if !errors.Is(err, ErrDigitOutOfRange) {
+			t.Fatalf(`expected a digit out of range error; got %v`, err)
+		}
+		buff.Reset()
+		interpreter.InputRadix = 2
+		if err := testWithInterpreter(interpreter, `A`); err != nil {
+			t.Fatalf(`could not set up test: %v`, err)
+		}
+		expect(`10`)
+	})
+}
+
+func TestPrintRaw(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	expect := func(src, expected string) {
+		buff.Reset()
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if buff.String() != expected {
+			t.Fatalf(`expected %q to write %q; found %q`, src, expected, buff.String())
+		}
+		if interpreter.Stack.Len() != 0 {
+			t.Fatalf(`expected %q to pop what it wrote`, src)
+		}
+	}
+
+	expect(`16i 48656C6C6F P Ai`, `Hello`)
+	expect(`65536P`, "\x01\x00\x00")
+	expect(`0P`, ``)
+	expect(`0.99P`, ``)
+	expect(`_65P`, `A`)
+	expect(`_66.9P`, `B`)
+	expect(`[]P`, ``)
+	expect("[\x00 ok]P", "\x00 ok")
+
+	// Writing a value mustn't change the register it was loaded from.
+	expect(`_67sa laP`, `C`)
+	if a := interpreter.Register('a').Peek(); a.String() != `-67` {
+		t.Fatalf(`expected register a to still hold -67; found %v`, a)
+	}
+}
+
+func TestToCharacter(t *testing.T) {
+	interpreter := NewInterpreter()
+	for src, expected := range map[string]string{
+		`65a`:      `A`,
+		`321a`:     `A`,
+		`_66.9a`:   `B`,
+		`0a`:       "\x00",
+		`[hello]a`: `h`,
+		`[]a`:      ``,
+	} {
+		values, err := interpreter.EvalString(`c` + src)
+		if err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if len(values) != 1 || !values[0].IsString() || values[0].String() != expected {
+			t.Fatalf(`expected %q to leave the string %q; found %v`, src, expected, values)
+		}
+	}
+	if _, err := interpreter.EvalString(`ca`); !errors.Is(err, ErrStackTooShort) {
+		t.Fatalf(`expected a to need a value; found %v`, err)
+	}
 }
 
 func TestPrintOperations(t *testing.T) {
 	interpreter := NewInterpreter()
 	buff := new(strings.Builder)
-	interpreter.output = buff
+	interpreter.SetOutput(buff)
 	test := func(str string) {
 		interpreter.InputRadix = 10
 		interpreter.OutputRadix = 10
@@ -480,8 +782,770 @@ func TestPrintOperations(t *testing.T) {
 		expect(`HELLO`)
 	})
 
+	t.Run(`test raw printing a number writes bytes`, func(t *testing.T) {
+		test(`0k65535P`)
+		expect("\xff\xff")
+	})
+
 	t.Run(`test a string with nested brackets`, func(t *testing.T) {
 		test(`[a string with [nested] brackets]`)
 		expect(`a string with [nested] brackets`)
 	})
+
+	t.Run(`test a string with deeply nested brackets`, func(t *testing.T) {
+		test(`[a [b [c] d] e]`)
+		expect(`a [b [c] d] e`)
+	})
+
+	t.Run(`long numbers are wrapped`, func(t *testing.T) {
+		defer func() { interpreter.LineLength = DefaultLineLength }()
+		interpreter.LineLength = 5
+		test(`0k_1234567890`)
+		expect(`-123\`, `4567\`, `890`)
+
+		test(`0k1234`)
+		expect(`1234`)
+
+		test(`[long strings are not wrapped]`)
+		expect(`long strings are not wrapped`)
+	})
+
+	t.Run(`wrapping can be disabled`, func(t *testing.T) {
+		defer func() { interpreter.LineLength = DefaultLineLength }()
+		interpreter.LineLength = 0
+		test(`0k2 256^`)
+		expect(`115792089237316195423570985008687907853269984665640564039457584007913129639936`)
+	})
+}
+
+func TestArrayOperations(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`store and fetch`, func(t *testing.T) {
+		test(`0k12 0:a34 1:a1;a0;a`)
+		expect(`12`, `34`)
+	})
+
+	t.Run(`fetching an unset index pushes zero`, func(t *testing.T) {
+		test(`0k99;a`)
+		expect(`0`)
+	})
+
+	t.Run(`arrays are independent of the register stack`, func(t *testing.T) {
+		test(`0k5sb7 0:b0;blb`)
+		expect(`5`, `7`)
+	})
+
+	t.Run(`each level of a register has its own array`, func(t *testing.T) {
+		// As in GNU dc, S starts a new array, and L brings back the
+		// one it put aside.
+		test(`0k1 0:g 0Sg 0;g 2 0:g 0;g Lg 0;g`)
+		expect(`1`, `0`, `2`, `0`)
+
+		test(`0k3 0:h 4sh 0;h`)
+		expect(`3`)
+
+		test(`0k7si 5 0:i Li 0;i`)
+		expect(`0`, `7`)
+
+		// s leaves one level, so the arrays S put aside go too.
+		test(`0k9 3:k [x]Sk 1 0:k 5sk Lk 3;k`)
+		expect(`0`, `5`)
+	})
+
+	t.Run(`fetched values are copies`, func(t *testing.T) {
+		test(`0k10 3:c3;c1+3;c`)
+		expect(`10`, `11`)
+	})
+
+	t.Run(`strings can be stored`, func(t *testing.T) {
+		test(`[hello]2:d2;d`)
+		expect(`hello`)
+	})
+
+	t.Run(`arrays are sparse`, func(t *testing.T) {
+		test(`0k1 1000000000:j 2 2147483647:j 1000000000;j 999;j`)
+		expect(`0`, `1`)
+		if n := interpreter.Array('j').Len(); n != 2 {
+			t.Fatalf(`expected array j to hold 2 entries; found %d`, n)
+		}
+		if indexes := interpreter.Array('j').Indexes(); len(indexes) != 2 || indexes[0] != 1000000000 {
+			t.Fatalf(`expected the indexes in order; found %v`, indexes)
+		}
+	})
+
+	t.Run(`index out of range`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `1_1:a`)
+		if err == nil {
+			t.Fatalf(`expected an error for a negative index`)
+		}
+		err = testWithInterpreter(interpreter, `1 2147483648:a`)
+		if err == nil {
+			t.Fatalf(`expected an error for a large index`)
+		}
+		buff.Reset()
+	})
+}
+
+func TestReadArray(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`9 7:a 16i`); err != nil {
+		t.Fatal(err)
+	}
+	n, err := interpreter.ReadArray('a', strings.NewReader("1 -A\n\t_FF.8\n"))
+	if err != nil || n != 3 {
+		t.Fatalf(`expected to read 3 numbers; found %d, %v`, n, err)
+	}
+	arr := interpreter.Array('a')
+	if arr.Len() != 3 || arr.Get(1).String() != `-10` || arr.Get(2).String() != `-255.5` || arr.Get(7) != nil {
+		t.Fatalf(`expected the array to be replaced by 1, -10 and -255.5; found %v`, arr.values)
+	}
+	if interpreter.Stack.Len() != 0 {
+		t.Fatalf(`expected the stack to be left alone; found %v`, interpreter.Stack.Values())
+	}
+
+	if _, err := interpreter.ReadArray('a', strings.NewReader(`2 two`)); !errors.Is(err, ErrInvalidNumber) {
+		t.Fatalf(`expected ErrInvalidNumber; found %v`, err)
+	}
+	if arr := interpreter.Array('a'); arr.Len() != 3 || interpreter.Stack.Len() != 0 {
+		t.Fatalf(`expected a failed ReadArray to leave the array and stack alone`)
+	}
+}
+
+func TestReadLineOperation(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	interpreter.input = bufio.NewReader(strings.NewReader("2 3+\n10\n[abc]"))
+
+	t.Run(`reading a line executes it`, func(t *testing.T) {
+		test(`?`)
+		expect(`5`)
+	})
+
+	t.Run(`reading in a macro`, func(t *testing.T) {
+		test(`[?2*]x`)
+		expect(`20`)
+	})
+
+	t.Run(`reading the last line without a newline`, func(t *testing.T) {
+		test(`?`)
+		expect(`abc`)
+	})
+
+	t.Run(`reading at end of input does nothing`, func(t *testing.T) {
+		test(`7?`)
+		expect(`7`)
+	})
+}
+
+func TestLengthOperation(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`length of numbers`, func(t *testing.T) {
+		test(`0k12345Z`)
+		expect(`5`)
+
+		test(`0k_12.345Z`)
+		expect(`5`)
+
+		// As in GNU dc, leading zeros aren't significant, but zero has
+		// one digit.
+		test(`0k.05Z`)
+		expect(`1`)
+
+		test(`0k0Z`)
+		expect(`1`)
+	})
+
+	t.Run(`length of strings`, func(t *testing.T) {
+		test(`0k[hello, world]Z`)
+		expect(`12`)
+	})
+}
+
+func TestScaleOperation(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`scale of entered numbers`, func(t *testing.T) {
+		test(`0k12X12.345X_0.10X`)
+		expect(`2`, `3`, `0`)
+	})
+
+	t.Run(`scale of strings`, func(t *testing.T) {
+		test(`0k[1.234]X`)
+		expect(`0`)
+	})
+
+	t.Run(`scale of results`, func(t *testing.T) {
+		test(`0k1.5 2.25+X`)
+		expect(`2`)
+
+		test(`0k1.5 2.25*X`)
+		expect(`2`)
+
+		test(`5k1.5 2.25*X0k`)
+		expect(`3`)
+
+		test(`0k1.5 3^X`)
+		expect(`1`)
+
+		test(`0k12.34 1/X`)
+		expect(`0`)
+
+		test(`3k12.34 1/X0k`)
+		expect(`3`)
+
+		test(`0k2.25vX`)
+		expect(`2`)
+	})
+
+	t.Run(`results are truncated to their scale`, func(t *testing.T) {
+		test(`2k1 3/5*`)
+		expect(`1.65`)
+
+		test(`0k7.5 2%`)
+		expect(`1`)
+
+		test(`1k7.5 2%`)
+		expect(`0.1`)
+
+		test(`1k7.5 2~`)
+		expect(`3.7`, `0.1`)
+	})
+
+	t.Run(`trailing zeros count toward length`, func(t *testing.T) {
+		test(`0k12.340Z`)
+		expect(`5`)
+	})
+}
+
+func TestStackOperations(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`duplicate`, func(t *testing.T) {
+		test(`0k1 2d`)
+		expect(`2`, `2`, `1`)
+	})
+
+	t.Run(`reverse`, func(t *testing.T) {
+		test(`0k1 2r`)
+		expect(`1`, `2`)
+	})
+
+	t.Run(`drop`, func(t *testing.T) {
+		test(`0k1 2 3R`)
+		expect(`2`, `1`)
+
+		test(`0k[abc]1R`)
+		expect(`abc`)
+	})
+
+	t.Run(`drop from an empty stack`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `R`)
+		if err == nil {
+			t.Fatalf(`expected an error dropping from an empty stack`)
+		}
+		buff.Reset()
+	})
+}
+
+func TestRun(t *testing.T) {
+	run := func(input, expected, expectedErrors string) {
+		interpreter := NewInterpreter()
+		buff, errBuff := new(strings.Builder), new(strings.Builder)
+		interpreter.SetOutput(buff)
+		interpreter.SetErrorOutput(errBuff)
+		in := bufio.NewReader(strings.NewReader(input))
+		interpreter.SetInput(in)
+		if err := interpreter.Run(in); err != nil {
+			t.Fatalf(`could not run %q: %v`, input, err)
+		}
+		if buff.String() != expected {
+			t.Fatalf(`expected %q to print %q; printed %q`, input, expected, buff.String())
+		}
+		if errBuff.String() != expectedErrors {
+			t.Fatalf(`expected %q to report %q; reported %q`, input, expectedErrors, errBuff.String())
+		}
+	}
+	run("2 3+p\n", "5\n", ``)
+	run("2 3+p q 4p", "5\n", ``)
+	run("1 0/ 4p", "4\n", "error processing command: line 1, column 4: divide by zero\n")
+	// Given the same reader, '?' shares Run's buffer, so it reads the
+	// next line of the input rather than skipping past what Run has
+	// already buffered.
+	run("1?2p\n3p\n", "2\n3\n", ``)
+	run("?\n2 3+\np\n", "5\n", ``)
+
+	// Otherwise '?' reads from the interpreter's input, not the script.
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	interpreter.SetInput(strings.NewReader("5\n"))
+	if err := interpreter.Run(strings.NewReader(`?p [not read]`)); err != nil {
+		t.Fatal(err)
+	}
+	if buff.String() != "5\n" {
+		t.Fatalf(`expected '?' to read from the input rather than the script; printed %q`, buff.String())
+	}
+
+	interpreter = NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	if err := interpreter.Run(strings.NewReader(`12 34`)); err != nil {
+		t.Fatalf(`could not run: %v`, err)
+	}
+	if interpreter.Stack.Len() != 2 || interpreter.Stack.Peek().String() != `34` {
+		t.Fatalf(`expected the last number to be pushed at the end of the input`)
+	}
+	if interpreter.Quit() {
+		t.Fatalf(`expected running out of input not to count as quitting`)
+	}
+	if err := interpreter.Run(strings.NewReader(`5 q 6`)); err != nil || !interpreter.Quit() {
+		t.Fatalf(`expected q to be reported; found %v`, err)
+	}
+	if err := interpreter.Run(strings.NewReader(`7`)); err != nil || interpreter.Quit() {
+		t.Fatalf(`expected the next Run to start again; found %v`, err)
+	}
+}
+
+func TestEvalString(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	values, err := interpreter.EvalString(`2 3+ [five] 1.5`)
+	if err != nil {
+		t.Fatalf(`could not evaluate: %v`, err)
+	}
+	if len(values) != 3 || values[0].String() != `5` || values[1].String() != `five` || values[2].String() != `1.5` {
+		t.Fatalf(`expected [5 five 1.5]; was %v`, values)
+	}
+
+	interpreter.Stack.Clear()
+	values, err = interpreter.EvalString(`1 0/ 4`)
+	if !errors.Is(err, ErrDivideByZero) {
+		t.Fatalf(`expected a divide by zero error; was %v`, err)
+	}
+	if len(values) != 2 {
+		t.Fatalf(`expected evaluation to stop at the error; stack was %v`, values)
+	}
+}
+
+func TestSetInputOutput(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	if interpreter.Output() != buff {
+		t.Fatalf(`expected Output to return the writer given to SetOutput`)
+	}
+	errBuff := new(strings.Builder)
+	interpreter.SetErrorOutput(errBuff)
+	if interpreter.ErrorOutput() != errBuff {
+		t.Fatalf(`expected ErrorOutput to return the writer given to SetErrorOutput`)
+	}
+	interpreter.SetInput(strings.NewReader("2 3+p\n"))
+	if err := interpreter.Interpret('?'); err != nil {
+		t.Fatal(err)
+	}
+	if buff.String() != "5\n" {
+		t.Fatalf(`expected '?' to read from the new input and print 5; printed %q`, buff.String())
+	}
+
+	reader := bufio.NewReader(strings.NewReader(``))
+	interpreter.SetInput(reader)
+	if interpreter.Input() != reader {
+		t.Fatalf(`expected SetInput to keep a *bufio.Reader as it is`)
+	}
+}
+
+// countingWriter counts the writes made to it. Run writes to it from
+// another goroutine, so it's locked.
+type countingWriter struct {
+	mu     sync.Mutex
+	b      strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.b.Write(p)
+}
+
+func (w *countingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}
+
+func (w *countingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
+
+func TestBufferedOutput(t *testing.T) {
+	interpreter := NewInterpreter()
+	output := new(countingWriter)
+	interpreter.SetOutput(output)
+	if _, err := interpreter.EvalString(`1 2 3 4 5f [done]p`); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "5\n4\n3\n2\n1\ndone\n" || output.count() != 1 {
+		t.Fatalf(`expected EvalString to print everything in one write; found %q in %d`, output.String(), output.count())
+	}
+
+	// Run flushes what's been printed before it waits for more input.
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- interpreter.Run(r) }()
+	io.WriteString(w, "6p [x]n ")
+	for n := 0; output.String() != "5\n4\n3\n2\n1\ndone\n6\nx"; n++ {
+		if n == 100 {
+			t.Fatalf(`expected Run to flush before waiting for input; found %q`, output.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	interpreter.SetOutput(output)
+	interpreter.printf("%d", 8)
+	if err := interpreter.FlushOutput(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(output.String(), "x8") {
+		t.Fatalf(`expected FlushOutput to write what was buffered; found %q`, output.String())
+	}
+}
+
+func TestRegisterOperation(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+	}
+
+	double := makeUnaryOperation(func(i *Interpreter, v *Value) ([]*Value, error) {
+		sum := v.Dup()
+		err := sum.Add(v)
+		return []*Value{sum}, err
+	})
+	if err := interpreter.RegisterOperation('D', double); !errors.Is(err, ErrOperationExists) {
+		t.Fatalf(`expected a digit to be taken; error was %v`, err)
+	}
+	if err := interpreter.RegisterOperation('p', double); !errors.Is(err, ErrOperationExists) {
+		t.Fatalf(`expected p to be taken; error was %v`, err)
+	}
+	if _, ok := interpreter.LookupOperation('T'); ok {
+		t.Fatalf(`expected T to be free`)
+	}
+	if err := interpreter.RegisterOperation('T', double); err != nil {
+		t.Fatalf(`could not register T: %v`, err)
+	}
+	if _, ok := interpreter.LookupOperation('T'); !ok {
+		t.Fatalf(`expected T to be bound to the new operation`)
+	}
+	test(`21T`)
+	expect(`42`)
+}
+
+func TestIndependentInterpreters(t *testing.T) {
+	a, b := NewInterpreter(), NewInterpreter()
+	a.SetOutput(new(strings.Builder))
+	b.SetOutput(new(strings.Builder))
+	// Feed the two interpreters alternately, in the middle of numbers,
+	// strings and register commands.
+	progA, progB := []rune(`12[[ab]]sx lx 1 2>x`), []rune(`34[[cd]]sy ly 2 1<y`)
+	for n := range progA {
+		a.Interpret(progA[n])
+		b.Interpret(progB[n])
+	}
+	a.Flush()
+	b.Flush()
+	check := func(name string, i *Interpreter, expected ...string) {
+		values := i.Stack.Values()
+		if len(values) != len(expected) {
+			t.Fatalf(`expected interpreter %s to have %v on its stack; had %v`, name, expected, values)
+		}
+		for n, v := range values {
+			if v.String() != expected[n] {
+				t.Fatalf(`expected interpreter %s to have %v on its stack; had %v`, name, expected, values)
+			}
+		}
+	}
+	check(`a`, a, `ab`, `[ab]`, `12`)
+	check(`b`, b, `cd`, `[cd]`, `34`)
+}
+
+func TestInterpretContext(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	for _, r := range `[lax]sa la` {
+		interpreter.Interpret(r)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := interpreter.InterpretContext(ctx, 'x')
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf(`expected the loop to stop at the deadline; error was %v`, err)
+	}
+
+	// The interpreter still works afterward.
+	interpreter.Stack.Clear()
+	values, err := interpreter.EvalString(`2 3+`)
+	if err != nil || len(values) != 1 || values[0].String() != `5` {
+		t.Fatalf(`expected the interpreter to carry on after cancellation; got %v, %v`, values, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	err = interpreter.RunContext(ctx, strings.NewReader(`1p`))
+	if !errors.Is(err, context.Canceled) || buff.String() != `` {
+		t.Fatalf(`expected a cancelled context to stop Run; error was %v, printed %q`, err, buff.String())
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	interpreter.StepLimit = 1000
+	err := interpreter.Run(strings.NewReader(`[lax]salax 1p`))
+	if !errors.Is(err, ErrStepLimitExceeded) {
+		t.Fatalf(`expected the loop to reach the step limit; error was %v`, err)
+	}
+	if buff.String() != `` {
+		t.Fatalf(`expected Run to stop at the step limit; printed %q`, buff.String())
+	}
+	if interpreter.Steps != 1000 {
+		t.Fatalf(`expected 1000 steps to have been run; was %d`, interpreter.Steps)
+	}
+
+	interpreter.Steps = 0
+	interpreter.Stack.Clear()
+	values, err := interpreter.EvalString(`2 3+`)
+	if err != nil || len(values) != 1 || values[0].String() != `5` {
+		t.Fatalf(`expected resetting Steps to allow more commands; got %v, %v`, values, err)
+	}
+	if interpreter.Steps != 3 {
+		t.Fatalf(`expected 2 3+ to take 3 steps; took %d`, interpreter.Steps)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	interpreter := NewInterpreter()
+	commands := interpreter.Commands()
+	found := make(map[rune]CommandInfo)
+	for n, c := range commands {
+		if n > 0 && commands[n-1].Rune >= c.Rune {
+			t.Fatalf(`expected the commands in order; found %q before %q`, commands[n-1].Rune, c.Rune)
+		}
+		found[c.Rune] = c
+	}
+	if c := found['/']; c.Description != `divide` || c.Register {
+		t.Fatalf(`expected / to divide; found %+v`, c)
+	}
+	for _, r := range `slSL:;<>=` {
+		if !found[r].Register {
+			t.Fatalf(`expected %q to take a register`, r)
+		}
+	}
+	for _, r := range `0A` {
+		if _, ok := found[r]; ok {
+			t.Fatalf(`expected the digit %q not to be listed`, r)
+		}
+	}
+
+	names := interpreter.ExtensionNames()
+	if len(names) != len(interpreter.Extensions) {
+		t.Fatalf(`expected every extension to be listed; found %v`, names)
+	}
+	for n := 1; n < len(names); n++ {
+		if names[n-1] >= names[n] {
+			t.Fatalf(`expected the extensions in order; found %v`, names)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.LineLength = 4
+	if _, err := interpreter.EvalString(`2k 16o 1 3/ 123456 [x]`); err != nil {
+		t.Fatal(err)
+	}
+	values := interpreter.Stack.Values()
+	for n, expected := range []string{`x`, `1E240.00`, `0.54`} {
+		if actual := interpreter.Format(values[n]); actual != expected {
+			t.Fatalf(`expected %s to be formatted as %q; found %q`, values[n].String(), expected, actual)
+		}
+	}
+}
+
+func benchmarkEval(b *testing.B, setup, src string) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(io.Discard)
+	if _, err := interpreter.EvalString(setup); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNumberParsing(b *testing.B) {
+	benchmarkEval(b, ``, `12345.6789 _0.001 987654321 3.14159265358979 42`)
+}
+
+func BenchmarkArithmetic(b *testing.B) {
+	benchmarkEval(b, `10k`, `1.5 2.25+ 3*4/ 0.7- 2^ 3% 5~ +v`)
+}
+
+func BenchmarkMacroLoop(b *testing.B) {
+	benchmarkEval(b, `[1+d1000>a]sa`, `0lax`)
+}
+
+func TestStrict(t *testing.T) {
+	run := func(src string) (string, string) {
+		interpreter := NewInterpreter()
+		interpreter.Strict = true
+		interpreter.QuitOneLevel = true
+		output, errorOutput := new(strings.Builder), new(strings.Builder)
+		interpreter.SetOutput(output)
+		interpreter.SetErrorOutput(errorOutput)
+		if err := interpreter.Run(strings.NewReader(src)); err != nil {
+			t.Fatalf(`expected %q to run; found %v`, src, err)
+		}
+		return output.String(), errorOutput.String()
+	}
+	expect := func(src, expected, expectedError string) {
+		output, errorOutput := run(src)
+		if output != expected {
+			t.Fatalf(`expected %q to print %q in strict mode; found %q`, src, expected, output)
+		}
+		if !strings.Contains(errorOutput, expectedError) || expectedError == `` && errorOutput != `` {
+			t.Fatalf(`expected %q to report %q in strict mode; found %q`, src, expectedError, errorOutput)
+		}
+	}
+
+	// Numbers are printed to their own scale.
+	expect(`2k 3p 1 3/p 1.50p 2 1.5*p`, "3\n.33\n1.50\n3.0\n", ``)
+	// { is an unknown command, so the name after it is read as
+	// commands, and errors don't stop the macro.
+	expect(`[1 {gcd} 2p]x 3p`, "2\n3\n", ErrUnknownCommand.Error()+`: '{'`)
+	expect(`[[a]sa [b][a]>a 4p]x`, "4\n", ErrValueNotNumeric.Error())
+	expect(`1e2 f`, "2\n1\n", ErrUnknownCommand.Error()+`: 'e'`)
+	// q leaves two macros, even with QuitOneLevel, and only quits from
+	// a macro run from the top level.
+	expect(`[[q]x [inner]p]x [outer]p`, "outer\n", ``)
+	expect(`[q]x [outer]p`, ``, ``)
+	// ~ leaves the remainder on top, and the scale can't be negative.
+	expect(`0k 7 2~ f`, "1\n3\n", ``)
+	expect(`_1k K f`, "0\n-1\n", ErrNegativeScale.Error())
+	// R isn't one of dc's commands, compiled or not.
+	expect(`1 2R f`, "2\n1\n", ErrUnknownCommand.Error()+`: 'R'`)
+	expect(`[1 2R]x f`, "2\n1\n", ErrUnknownCommand.Error()+`: 'R'`)
+
+	// Outside strict mode, the scale still can't be negative.
+	if _, err := NewInterpreter().EvalString(`_1k`); !errors.Is(err, ErrNegativeScale) {
+		t.Fatalf(`expected a negative scale to be refused; found %v`, err)
+	}
 }
@@ -0,0 +1,259 @@
+package godc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"unicode/utf8"
+)
+
+// jsonValue is how a Value is written in JSON. A number is exact:
+// its numerator and denominator are decimal strings.
+type jsonValue struct {
+	Type   string `json:"type"` // "number" or "string"
+	Num    string `json:"num,omitempty"`
+	Denom  string `json:"denom,omitempty"`
+	Scale  *int64 `json:"scale,omitempty"`
+	String string `json:"string,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. A number is written as
+// {"type":"number","num":"5","denom":"4","scale":2}, and a string as
+// {"type":"string","string":"hello"}.
+func (n *Value) MarshalJSON() ([]byte, error) {
+	if n.Type == VTString {
+		return json.Marshal(jsonValue{Type: `string`, String: string(n.strval)})
+	}
+	scale := n.scale
+	return json.Marshal(jsonValue{
+		Type:  `number`,
+		Num:   n.numval.Num().String(),
+		Denom: n.numval.Denom().String(),
+		Scale: &scale,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A number's denom may be
+// left out for a whole number, and its scale for the digits needed
+// to write it out exactly.
+func (n *Value) UnmarshalJSON(data []byte) error {
+	var j jsonValue
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	switch j.Type {
+	case `string`:
+		*n = Value{Type: VTString, strval: []rune(j.String)}
+		return nil
+	case `number`:
+	default:
+		return fmt.Errorf(`unknown type of value %q`, j.Type)
+	}
+	if j.Denom == `` {
+		j.Denom = `1`
+	}
+	num, ok := new(big.Int).SetString(j.Num, 10)
+	if !ok {
+		return fmt.Errorf(`%w: %q`, ErrInvalidNumber, j.Num)
+	}
+	denom, ok := new(big.Int).SetString(j.Denom, 10)
+	if !ok {
+		return fmt.Errorf(`%w: %q`, ErrInvalidNumber, j.Denom)
+	}
+	if denom.Sign() == 0 {
+		return ErrDivideByZero
+	}
+	v := Value{numval: new(big.Rat).SetFrac(num, denom)}
+	if j.Scale == nil {
+		v.scale = v.DecimalScale(0)
+	} else if v.scale = *j.Scale; v.scale < 0 {
+		return fmt.Errorf(`negative scale %d`, v.scale)
+	}
+	*n = v
+	return nil
+}
+
+// jsonState is how SaveJSON writes an interpreter's state. Stacks are
+// bottom first, and registers are named by one-character strings.
+type jsonState struct {
+	Stack             []*Value                    `json:"stack"`
+	Registers         map[string][]*Value         `json:"registers"`
+	Arrays            map[string]map[int]*Value   `json:"arrays"`
+	ArrayLevels       map[string][]map[int]*Value `json:"array_levels,omitempty"`
+	Scale             int64                       `json:"scale"`
+	Precision         int64                       `json:"precision"`
+	SeparatePrecision bool                        `json:"separate_precision"`
+	InputRadix        uint8                       `json:"input_radix"`
+	OutputRadix       int64                       `json:"output_radix"`
+	Rounding          string                      `json:"rounding"`
+	Notation          string                      `json:"notation"`
+	SignificantDigits int64                       `json:"significant_digits"`
+	FixedScale        bool                        `json:"fixed_scale"`
+	GroupSeparator    string                      `json:"group_separator"`
+	DecimalSeparator  string                      `json:"decimal_separator"`
+}
+
+// SaveJSON writes the same state as Save, as JSON that other programs
+// can read. The format is described in the README.
+func (i *Interpreter) SaveJSON(w io.Writer) error {
+	state := i.state()
+	j := jsonState{
+		Stack:             make([]*Value, 0, len(state.Stack)),
+		Registers:         make(map[string][]*Value),
+		Arrays:            make(map[string]map[int]*Value),
+		Scale:             state.Scale,
+		Precision:         state.Precision,
+		SeparatePrecision: state.SeparatePrecision,
+		InputRadix:        state.InputRadix,
+		OutputRadix:       state.OutputRadix,
+		Rounding:          state.RoundingMode.String(),
+		Notation:          state.Notation.String(),
+		SignificantDigits: state.SignificantDigits,
+		FixedScale:        state.FixedScale,
+		GroupSeparator:    state.GroupSeparator,
+		DecimalSeparator:  state.DecimalSeparator,
+	}
+	values := func(saved []savedValue) []*Value {
+		values := make([]*Value, len(saved))
+		for n, s := range saved {
+			values[n], _ = s.value()
+		}
+		return values
+	}
+	j.Stack = values(state.Stack)
+	for r, saved := range state.Registers {
+		j.Registers[string(r)] = values(saved)
+	}
+	array := func(entries map[int]savedValue) map[int]*Value {
+		arr := make(map[int]*Value)
+		for index, s := range entries {
+			arr[index], _ = s.value()
+		}
+		return arr
+	}
+	for r, entries := range state.Arrays {
+		j.Arrays[string(r)] = array(entries)
+	}
+	for r, levels := range state.ArrayLevels {
+		if j.ArrayLevels == nil {
+			j.ArrayLevels = make(map[string][]map[int]*Value)
+		}
+		for _, entries := range levels {
+			j.ArrayLevels[string(r)] = append(j.ArrayLevels[string(r)], array(entries))
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent(``, `  `)
+	return encoder.Encode(j)
+}
+
+// LoadJSON replaces the interpreter's state with one written by
+// SaveJSON, or by another program. Settings that are left out keep
+// their current values, but the stack and registers are replaced
+// whether they're given or not. If it fails, the interpreter is left
+// as it was.
+func (i *Interpreter) LoadJSON(r io.Reader) error {
+	j := jsonState{
+		Scale:             i.Scale,
+		Precision:         i.Precision,
+		SeparatePrecision: i.SeparatePrecision,
+		InputRadix:        i.InputRadix,
+		OutputRadix:       i.OutputRadix,
+		Rounding:          i.RoundingMode.String(),
+		Notation:          i.Notation.String(),
+		SignificantDigits: i.SignificantDigits,
+		FixedScale:        i.FixedScale,
+		GroupSeparator:    i.GroupSeparator,
+		DecimalSeparator:  i.DecimalSeparator,
+	}
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return fmt.Errorf(`%w: %v`, ErrBadState, err)
+	}
+	state := savedState{
+		Version:           stateVersion,
+		Registers:         make(map[rune][]savedValue),
+		Arrays:            make(map[rune]map[int]savedValue),
+		Scale:             j.Scale,
+		Precision:         j.Precision,
+		SeparatePrecision: j.SeparatePrecision,
+		InputRadix:        j.InputRadix,
+		OutputRadix:       j.OutputRadix,
+		SignificantDigits: j.SignificantDigits,
+		FixedScale:        j.FixedScale,
+		GroupSeparator:    j.GroupSeparator,
+		DecimalSeparator:  j.DecimalSeparator,
+	}
+	var err error
+	if state.RoundingMode, err = ParseRoundingMode(j.Rounding); err != nil {
+		return err
+	}
+	if state.Notation, err = ParseNotation(j.Notation); err != nil {
+		return err
+	}
+	saved := func(values []*Value) ([]savedValue, error) {
+		saved := make([]savedValue, len(values))
+		for n, v := range values {
+			if v == nil {
+				return nil, fmt.Errorf(`%w: null value`, ErrBadState)
+			}
+			saved[n] = saveValue(v)
+		}
+		return saved, nil
+	}
+	register := func(name string) (rune, error) {
+		r, size := utf8.DecodeRuneInString(name)
+		if size == 0 || size != len(name) {
+			return 0, fmt.Errorf(`%w: register name %q`, ErrBadState, name)
+		}
+		return r, nil
+	}
+	if state.Stack, err = saved(j.Stack); err != nil {
+		return err
+	}
+	for name, values := range j.Registers {
+		r, err := register(name)
+		if err != nil {
+			return err
+		}
+		if state.Registers[r], err = saved(values); err != nil {
+			return err
+		}
+	}
+	array := func(entries map[int]*Value) (map[int]savedValue, error) {
+		saved := make(map[int]savedValue)
+		for index, v := range entries {
+			if v == nil {
+				return nil, fmt.Errorf(`%w: null value`, ErrBadState)
+			}
+			saved[index] = saveValue(v)
+		}
+		return saved, nil
+	}
+	for name, entries := range j.Arrays {
+		r, err := register(name)
+		if err != nil {
+			return err
+		}
+		if state.Arrays[r], err = array(entries); err != nil {
+			return err
+		}
+	}
+	for name, levels := range j.ArrayLevels {
+		r, err := register(name)
+		if err != nil {
+			return err
+		}
+		if state.ArrayLevels == nil {
+			state.ArrayLevels = make(map[rune][]map[int]savedValue)
+		}
+		for _, entries := range levels {
+			saved, err := array(entries)
+			if err != nil {
+				return err
+			}
+			state.ArrayLevels[r] = append(state.ArrayLevels[r], saved)
+		}
+	}
+	return i.restore(state)
+}
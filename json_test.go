@@ -0,0 +1,134 @@
+package godc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestValueJSON(t *testing.T) {
+	marshal := func(v *Value, expected string) {
+		actual, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf(`could not marshal %v: %v`, v, err)
+		}
+		if string(actual) != expected {
+			t.Fatalf(`expected %s; found %s`, expected, actual)
+		}
+		back := new(Value)
+		if err := json.Unmarshal(actual, back); err != nil {
+			t.Fatalf(`could not unmarshal %s: %v`, actual, err)
+		}
+		if back.Type != v.Type || back.String() != v.String() || back.Scale() != v.Scale() ||
+			(v.Type == VTNumber && back.Rat().Cmp(v.Rat()) != 0) {
+			t.Fatalf(`expected %s to read back as it was written`, actual)
+		}
+	}
+	third := NewValueFromBigRat(big.NewRat(-1, 3))
+	third.scale = 20
+	marshal(third, `{"type":"number","num":"-1","denom":"3","scale":20}`)
+	marshal(NewValueFromInt64(12), `{"type":"number","num":"12","denom":"1","scale":0}`)
+	marshal(NewValueFromString(`"hi"`), `{"type":"string","string":"\"hi\""}`)
+	marshal(NewValueFromString(``), `{"type":"string"}`)
+
+	unmarshal := func(src, expected string, scale int64) {
+		v := new(Value)
+		if err := json.Unmarshal([]byte(src), v); err != nil {
+			t.Fatalf(`could not unmarshal %s: %v`, src, err)
+		}
+		if v.String() != expected || v.Scale() != scale {
+			t.Fatalf(`expected %s to be %s with scale %d; was %s with scale %d`, src, expected, scale, v.String(), v.Scale())
+		}
+	}
+	unmarshal(`{"type":"number","num":"5","denom":"4"}`, `1.25`, 2)
+	unmarshal(`{"type":"number","num":"42"}`, `42`, 0)
+
+	for _, src := range []string{
+		`{"type":"number","num":"1.5"}`,
+		`{"type":"number","num":"1","denom":"0"}`,
+		`{"type":"number","num":"1","scale":-1}`,
+		`{"type":"array"}`,
+		`{"type":"number"}`,
+	} {
+		if err := json.Unmarshal([]byte(src), new(Value)); err == nil {
+			t.Fatalf(`expected %s to be rejected`, src)
+		}
+	}
+}
+
+func TestSaveLoadJSON(t *testing.T) {
+	saved := NewInterpreter()
+	saved.SetOutput(new(strings.Builder))
+	if _, err := saved.EvalString(`1 2.50 [hi]sa 4sb 5Sb 7 3:c 16o 3k 8{sci} 12i`); err != nil {
+		t.Fatalf(`could not set up the interpreter: %v`, err)
+	}
+	buff := new(bytes.Buffer)
+	if err := saved.SaveJSON(buff); err != nil {
+		t.Fatalf(`could not save: %v`, err)
+	}
+
+	loaded := NewInterpreter()
+	if err := loaded.LoadJSON(buff); err != nil {
+		t.Fatalf(`could not load: %v`, err)
+	}
+	if loaded.Scale != 3 || loaded.InputRadix != 12 || loaded.OutputRadix != 16 ||
+		loaded.Notation != NotationScientific || loaded.SignificantDigits != 8 {
+		t.Fatalf(`expected the settings to be restored`)
+	}
+	stack := loaded.Stack.Values()
+	if len(stack) != 2 || stack[0].String() != `2.50` || stack[1].String() != `1` {
+		t.Fatalf(`expected the stack to be restored; found %v`, stack)
+	}
+	if b := loaded.Register('b').Values(); len(b) != 2 || b[0].String() != `5` {
+		t.Fatalf(`expected register b to be restored; found %v`, b)
+	}
+	if v := loaded.Array('c').Get(3); v == nil || v.String() != `7` {
+		t.Fatalf(`expected c[3] to be restored; found %v`, v)
+	}
+
+	// The arrays S put aside are kept with their levels.
+	levels := NewInterpreter()
+	if _, err := levels.EvalString(`0k9 3:c [x]Sc 1 0:c`); err != nil {
+		t.Fatalf(`could not set up the interpreter: %v`, err)
+	}
+	buff.Reset()
+	if err := levels.SaveJSON(buff); err != nil {
+		t.Fatalf(`could not save: %v`, err)
+	}
+	levels = NewInterpreter()
+	if err := levels.LoadJSON(buff); err != nil {
+		t.Fatalf(`could not load: %v`, err)
+	}
+	if values, err := levels.EvalString(`0;c Lc 3;c`); err != nil || len(values) != 3 || values[0].String() != `1` || values[2].String() != `9` {
+		t.Fatalf(`expected the put aside array to come back with L; found %v, %v`, values, err)
+	}
+
+	// Another program can leave out what it doesn't care about.
+	if err := loaded.LoadJSON(strings.NewReader(`{"stack":[{"type":"number","num":"3"}],"registers":{"x":[{"type":"string","string":"lop"}]}}`)); err != nil {
+		t.Fatalf(`could not load: %v`, err)
+	}
+	if loaded.Scale != 3 || loaded.Register('b').Len() != 0 || loaded.Register('x').Peek().String() != `lop` {
+		t.Fatalf(`expected settings to be kept and registers replaced`)
+	}
+
+	for _, src := range []string{
+		`[1, 2]`,
+		`{"stack":[null]}`,
+		`{"registers":{"ab":[]}}`,
+		`{"input_radix":1}`,
+		`{"rounding":"sideways"}`,
+	} {
+		if err := loaded.LoadJSON(strings.NewReader(src)); err == nil {
+			t.Fatalf(`expected %s to be rejected`, src)
+		}
+	}
+	if err := loaded.LoadJSON(strings.NewReader(`[]`)); !errors.Is(err, ErrBadState) {
+		t.Fatalf(`expected ErrBadState; found %v`, err)
+	}
+	if loaded.Stack.Len() != 1 || loaded.InputRadix != 12 {
+		t.Fatalf(`expected a failed load to leave the interpreter alone`)
+	}
+}
@@ -0,0 +1,118 @@
+package godc
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrNumberTooLarge is returned when a number would need more bits
+// than the interpreter's MaxNumberBits allows.
+var ErrNumberTooLarge = fmt.Errorf(`number too large`)
+
+// ErrStringTooLong is returned when a string would be longer than the
+// interpreter's MaxStringLength allows.
+var ErrStringTooLong = fmt.Errorf(`string too long`)
+
+// ErrStackTooDeep is returned when a stack would hold more values than
+// the interpreter's MaxStackDepth allows.
+var ErrStackTooDeep = fmt.Errorf(`stack too deep`)
+
+// bits returns the number of bits needed for the larger of a number's
+// numerator and denominator.
+func bits(v *Value) int64 {
+	n, d := v.numval.Num().BitLen(), v.numval.Denom().BitLen()
+	if d > n {
+		n = d
+	}
+	return int64(n)
+}
+
+// checkValue returns an error if v is bigger than the interpreter's
+// limits allow.
+func (i *Interpreter) checkValue(v *Value) error {
+	if v.Type == VTString {
+		if i.MaxStringLength > 0 && int64(len(v.strval)) > i.MaxStringLength {
+			return fmt.Errorf(`%w: %d characters, but the limit is %d`, ErrStringTooLong, len(v.strval), i.MaxStringLength)
+		}
+		return nil
+	}
+	if i.MaxNumberBits > 0 && bits(v) > i.MaxNumberBits {
+		return fmt.Errorf(`%w: %d bits, but the limit is %d`, ErrNumberTooLarge, bits(v), i.MaxNumberBits)
+	}
+	return nil
+}
+
+// limited reports whether any of the interpreter's limits are set.
+func (i *Interpreter) limited() bool {
+	return i.MaxNumberBits > 0 || i.MaxStringLength > 0 || i.MaxStackDepth > 0
+}
+
+// checkDepth returns an error if a stack holds more values than the
+// interpreter's limit allows.
+func (i *Interpreter) checkDepth(s *Stack) error {
+	if i.MaxStackDepth > 0 && int64(s.Len()) > i.MaxStackDepth {
+		return fmt.Errorf(`%w: the limit is %d values`, ErrStackTooDeep, i.MaxStackDepth)
+	}
+	return nil
+}
+
+// checkResult checks the stack a command has left: the values it
+// pushed, which are on top, or at least the one on top, dropping them
+// all if any is too big, and then the number of values, dropping those
+// past the most it may hold.
+func (i *Interpreter) checkResult(pushed int) error {
+	if pushed < 1 {
+		pushed = 1
+	}
+	if pushed > i.Stack.Len() {
+		pushed = i.Stack.Len()
+	}
+	for depth := 0; depth < pushed; depth++ {
+		if err := i.checkValue(i.Stack.At(depth)); err != nil {
+			i.Stack.truncate(i.Stack.Len() - pushed)
+			return err
+		}
+	}
+	if err := i.checkDepth(i.Stack); err != nil {
+		i.Stack.truncate(int(i.MaxStackDepth))
+		return err
+	}
+	return nil
+}
+
+// checkGrowth returns ErrNumberTooLarge if a number of the given bits
+// would be too big, so that commands that can make huge numbers, such
+// as ^, can refuse before they start.
+func (i *Interpreter) checkGrowth(bits float64) error {
+	if i.MaxNumberBits > 0 && bits > float64(i.MaxNumberBits) {
+		if bits > math.MaxInt64 {
+			return fmt.Errorf(`%w: the limit is %d bits`, ErrNumberTooLarge, i.MaxNumberBits)
+		}
+		return fmt.Errorf(`%w: about %d bits, but the limit is %d`, ErrNumberTooLarge, int64(bits), i.MaxNumberBits)
+	}
+	return nil
+}
+
+// digitCount returns v, a count of digits such as a scale, as an
+// int64, discarding any fractional part, without changing v. It
+// returns ErrNumberTooLarge if the count doesn't fit in an int64.
+func digitCount(v *Value) (int64, error) {
+	n := v.Dup()
+	if err := n.IntVal(); err != nil {
+		return 0, err
+	}
+	if !n.numval.Num().IsInt64() {
+		return 0, fmt.Errorf(`%w: %s digits`, ErrNumberTooLarge, n.numval.Num())
+	}
+	return n.numval.Num().Int64(), nil
+}
+
+// checkDigits is like checkGrowth, for a number with the given count
+// of digits in a radix, such as a scale, or a precision to print to,
+// which the arithmetic and printing that follow would have to make.
+func (i *Interpreter) checkDigits(digits, radix int64) error {
+	if digits < 0 {
+		digits = -digits
+	}
+	return i.checkGrowth(float64(digits) * math.Log2(float64(radix)))
+}
@@ -0,0 +1,136 @@
+package godc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	interpreter := NewInterpreter()
+	expect := func(src string, expected error) {
+		interpreter.Stack.Clear()
+		_, err := interpreter.EvalString(src)
+		if !errors.Is(err, expected) {
+			t.Fatalf(`expected %q to give %v; found %v`, src, expected, err)
+		}
+	}
+
+	expect(`2 100^ 2 100{shl} [`+string(make([]rune, 50))+`]`, nil)
+	interpreter.MaxNumberBits = 64
+	interpreter.MaxStringLength = 10
+	expect(`2 63^ 1 63{shl} 1 1000000^ 0 1000000^ 9 9^ [0123456789]`, nil)
+	expect(`9 9 9^^`, ErrNumberTooLarge)
+	if interpreter.Stack.Len() != 2 {
+		t.Fatalf(`expected a refused exponent to leave its operands; found %d values`, interpreter.Stack.Len())
+	}
+	expect(`2 64^`, ErrNumberTooLarge)
+	expect(`3 41^`, ErrNumberTooLarge)
+	if interpreter.Stack.Len() != 0 {
+		t.Fatalf(`expected a result that's too big to be dropped; found %d values`, interpreter.Stack.Len())
+	}
+	expect(`1 64{shl}`, ErrNumberTooLarge)
+	expect(`2 32^d*`, ErrNumberTooLarge)
+	expect(`1 2 63^/`, nil)
+	expect(`1 2 64^/`, ErrNumberTooLarge)
+	expect(`[0123456789a]`, ErrStringTooLong)
+	expect(`123456789012345678901`, ErrNumberTooLarge)
+	expect(`123456789012345678901 1`, ErrNumberTooLarge)
+
+	// Every value a command pushes is checked, not just the one on top:
+	// here, ~'s quotient, which strict mode leaves under its remainder.
+	interpreter.Strict = true
+	expect(`0k 2 63^ .25~`, ErrNumberTooLarge)
+	interpreter.Strict = false
+	if interpreter.Stack.Len() != 0 {
+		t.Fatalf(`expected ~'s results to be dropped; found %v`, interpreter.Stack.Values())
+	}
+
+	var dcErr *DCError
+	interpreter.Stack.Clear()
+	if _, err := interpreter.EvalString(`2 100^`); !errors.As(err, &dcErr) || dcErr.Command != '^' {
+		t.Fatalf(`expected the error to be recorded against ^; found %v`, err)
+	}
+}
+
+func TestMaxStackDepth(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.MaxStackDepth = 3
+	if _, err := interpreter.EvalString(`1 2 3 4`); !errors.Is(err, ErrStackTooDeep) {
+		t.Fatalf(`expected the fourth value to be refused; found %v`, err)
+	}
+	if interpreter.Stack.Len() != 3 {
+		t.Fatalf(`expected the stack to be kept to 3 values; found %d`, interpreter.Stack.Len())
+	}
+
+	// A loop that only pushes is stopped, compiled or not.
+	for _, src := range []string{`c [1 lax]dsax`, `c [1 lbx]sb [lbx]dsax`} {
+		if _, err := interpreter.EvalString(src); !errors.Is(err, ErrStackTooDeep) {
+			t.Fatalf(`expected %q to be stopped; found %v`, src, err)
+		}
+		if interpreter.Stack.Len() != 3 {
+			t.Fatalf(`expected %q to leave 3 values; found %d`, src, interpreter.Stack.Len())
+		}
+	}
+
+	if _, err := interpreter.EvalString(`c 1Sr 2Sr 3Sr 4Sr`); !errors.Is(err, ErrStackTooDeep) {
+		t.Fatalf(`expected the register to be kept to 3 values; found %v`, err)
+	}
+	if interpreter.Register('r').Len() != 3 || interpreter.Stack.Len() != 1 {
+		t.Fatalf(`expected the refused value to stay on the stack; found %d in r and %d on the stack`,
+			interpreter.Register('r').Len(), interpreter.Stack.Len())
+	}
+
+	// So does a named stack that {moveto} adds to.
+	src := `c 1[s]{moveto} 2[s]{moveto} 3[s]{moveto} 4[s]{moveto}`
+	if _, err := interpreter.EvalString(src); !errors.Is(err, ErrStackTooDeep) {
+		t.Fatalf(`expected the named stack to be kept to 3 values; found %v`, err)
+	}
+	if s := interpreter.namedStack(`s`); s.Len() != 3 || interpreter.Stack.Len() != 2 || interpreter.Stack.Peek().String() != `s` {
+		t.Fatalf(`expected the refused value and the name to stay on the stack; found %d in s and %v on the stack`,
+			s.Len(), interpreter.Stack.Values())
+	}
+}
+
+func TestDigitLimits(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.MaxNumberBits = 64
+	for _, src := range []string{`20k`, `20{precision}`, `20{sci}`, `1e20`, `1e_20`, `50{exp}`} {
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); !errors.Is(err, ErrNumberTooLarge) {
+			t.Fatalf(`expected %q to be refused; found %v`, src, err)
+		}
+	}
+	if interpreter.Scale != 0 || interpreter.SeparatePrecision {
+		t.Fatalf(`expected refused settings to leave the interpreter alone`)
+	}
+	if _, err := interpreter.EvalString(`c 40{exp} 19k 19{precision} 1e19 1e_19`); err != nil {
+		t.Fatalf(`expected settings within the limit to work; found %v`, err)
+	}
+
+	// A refused argument is put back as it was, fraction and all.
+	for _, src := range []string{`20.5k`, `20.5{precision}`, `20.5{sci}`} {
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); !errors.Is(err, ErrNumberTooLarge) {
+			t.Fatalf(`expected %q to be refused; found %v`, src, err)
+		}
+		if v := interpreter.Stack.Peek(); v == nil || v.String() != `20.5` {
+			t.Fatalf(`expected %q to leave 20.5 on the stack; found %v`, src, interpreter.Stack.Values())
+		}
+	}
+
+	// Counts too big for an int64 are refused, even without a limit,
+	// rather than wrapping round to a small one.
+	unlimited := NewInterpreter()
+	for _, src := range []string{`2 64^5+k`, `2 64^5+{precision}`, `2 64^5+{sci}`} {
+		unlimited.Stack.Clear()
+		if _, err := unlimited.EvalString(src); !errors.Is(err, ErrNumberTooLarge) {
+			t.Fatalf(`expected %q to be refused; found %v`, src, err)
+		}
+		if v := unlimited.Stack.Peek(); v == nil || v.String() != `18446744073709551621` {
+			t.Fatalf(`expected %q to leave its argument on the stack; found %v`, src, unlimited.Stack.Values())
+		}
+	}
+	if unlimited.Scale != 0 || unlimited.SeparatePrecision || unlimited.Notation != NotationFixed {
+		t.Fatalf(`expected refused settings to leave the interpreter alone`)
+	}
+}
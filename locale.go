@@ -0,0 +1,115 @@
+package godc
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// skipRune is what numberRune reads a group separator as, so that the
+// NumberBuilder can skip it.
+const skipRune rune = -1
+
+// separatorRune returns the rune sep is made of, or 0 if it's longer
+// than one rune, or white space, which can't be read inside a number.
+func separatorRune(sep string) rune {
+	r, size := utf8.DecodeRuneInString(sep)
+	if size == 0 || size != len(sep) || unicode.IsSpace(r) {
+		return 0
+	}
+	return r
+}
+
+// numberRune returns the rune r is read as in a number: a point for
+// the DecimalSeparator, and skipRune for the GroupSeparator, so numbers
+// can be typed the way they're printed. Separators are only read when
+// they're one rune and not white space.
+func (i *Interpreter) numberRune(r rune) rune {
+	if i.DecimalSeparator == `` && i.GroupSeparator == `` {
+		return r
+	}
+	if d := separatorRune(i.DecimalSeparator); d != 0 && r == d {
+		return '.'
+	}
+	if g := separatorRune(i.GroupSeparator); g != 0 && r == g {
+		return skipRune
+	}
+	return r
+}
+
+// localize writes a number printed as str with the DecimalSeparator
+// for its point, and GroupSeparator between each three digits of its
+// whole part if it's printed in decimal, as in 1.234.567,89.
+func (i *Interpreter) localize(str string) string {
+	group := i.GroupSeparator
+	if i.OutputRadix != 10 {
+		group = ``
+	}
+	return localizeNumber(str, i.DecimalSeparator, group)
+}
+
+// localizeNumber writes a number printed as str with decimal for its
+// point, if it isn't empty, and group between each three digits of its
+// whole part.
+func localizeNumber(str, decimal, group string) string {
+	if decimal == `` && group == `` {
+		return str
+	}
+	sign := ``
+	if strings.HasPrefix(str, `-`) {
+		sign, str = `-`, str[1:]
+	}
+	whole, fraction := str, ``
+	if point := strings.IndexAny(str, `.e`); point >= 0 {
+		whole, fraction = str[:point], str[point:]
+	}
+	if decimal != `` && strings.HasPrefix(fraction, `.`) {
+		fraction = decimal + fraction[1:]
+	}
+	b := new(strings.Builder)
+	b.WriteString(sign)
+	if group == `` || len(whole) <= 3 {
+		b.WriteString(whole)
+	} else {
+		first := len(whole) % 3
+		if first == 0 {
+			first = 3
+		}
+		b.WriteString(whole[:first])
+		for n := first; n < len(whole); n += 3 {
+			b.WriteString(group)
+			b.WriteString(whole[n : n+3])
+		}
+	}
+	b.WriteString(fraction)
+	return b.String()
+}
+
+// GroupOperation implements the {group} extension. It pops a string
+// to write between each three digits of whole numbers printed in
+// decimal, or an empty string not to group them.
+var GroupOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	if val.Type != VTString {
+		return nil, ErrValueNotString
+	}
+	i.GroupSeparator = string(val.strval)
+	return nil, nil
+})
+
+// DecimalOperation implements the {decimal} extension. It pops a string
+// to print for the point in numbers, such as a comma, or an empty
+// string for a point again.
+var DecimalOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	if val.Type != VTString {
+		return nil, ErrValueNotString
+	}
+	i.DecimalSeparator = string(val.strval)
+	return nil, nil
+})
+
+// LocaleExtensions print numbers, and read them, with the separators
+// used where the user lives.
+var LocaleExtensions = ExtensionSet{
+	`group`:   GroupOperation,
+	`decimal`: DecimalOperation,
+}
@@ -0,0 +1,66 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	interpreter := NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	interpreter.LineLength = 0
+	if _, err := interpreter.EvalString(`[,]{group} 2k 1 2 12 123 1234 12345 _123456 1234567.891 [abcd] f 16o 65535p`); err != nil {
+		t.Fatal(err)
+	}
+	expected := "abcd\n1,234,567.89\n-123,456.00\n12,345.00\n1,234.00\n123.00\n12.00\n2.00\n1.00\nFFFF.00\n"
+	if out.String() != expected {
+		t.Fatalf(`expected %q; found %q`, expected, out.String())
+	}
+	// Formatted values, such as those {str} makes, aren't grouped.
+	if values, _ := interpreter.EvalString(`c 10o 1234{str}`); string(values[0].strval) != `1234.00` {
+		t.Fatalf(`expected {str} not to group digits; found %q`, string(values[0].strval))
+	}
+	if _, err := interpreter.EvalString(`c []{group} 1234p`); err != nil || !strings.HasSuffix(out.String(), "\n1234.00\n") {
+		t.Fatalf(`expected an empty separator to stop grouping; found %q`, out.String())
+	}
+	if _, err := interpreter.EvalString(`1{group}`); !errors.Is(err, ErrValueNotString) {
+		t.Fatalf(`expected a number separator to be refused; found %v`, err)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	interpreter := NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	interpreter.EvalString(`[,]{decimal} [.]{group} 2k`)
+	expect := func(src, expected string) {
+		out.Reset()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if out.String() != expected {
+			t.Fatalf(`expected %q to print %q; found %q`, src, expected, out.String())
+		}
+	}
+
+	// Numbers are read the way they're printed, compiled or not.
+	expect(`1.234.567,891 p 2*p ,5p _1,5p`, "1.234.567,89\n2.469.135,78\n0,50\n-1,50\n")
+	expect(`[1.000,25 2*p]x [1.000,25 2*p]x`, "2.000,50\n2.000,50\n")
+	expect(`[-1.234,5]{num}p c`, "-1.234,50\n")
+	// Scientific notation uses the decimal separator too.
+	expect(`6{sci} 12345,6p {fixed}`, "1,23456e4\n")
+
+	// A program compiled with other separators is read again.
+	expect(`[1,5p]sa lax`, "1,50\n")
+	interpreter.EvalString(`[]{decimal} []{group}`)
+	expect(`lax`, "5.00\n")
+
+	// White space can be printed, but isn't read as part of a number.
+	expect(`[ ]{group} 1234567p 1 2+p`, "1 234 567.00\n3.00\n")
+
+	if _, err := interpreter.EvalString(`1{decimal}`); !errors.Is(err, ErrValueNotString) {
+		t.Fatalf(`expected a number separator to be refused; found %v`, err)
+	}
+}
@@ -0,0 +1,73 @@
+package godc
+
+import "math"
+
+// LnOperation implements the {ln} extension, the natural logarithm.
+// Like 'v', the result has the larger of the interpreter's scale and
+// the value's scale.
+var LnOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	scale := maxScale(i.Scale, val.scale)
+	if err := val.Ln(scale); err != nil {
+		return nil, err
+	}
+	val.Round(scale, i.RoundingMode)
+	return []*Value{val}, nil
+})
+
+// ExpOperation implements the {exp} extension, which raises e to the
+// power of the value. Its scale follows the same rule as {ln}.
+var ExpOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	scale := maxScale(i.Scale, val.scale)
+	// e^x has about x log2(e) bits before the point.
+	if val.Type == VTNumber && val.numval.Sign() > 0 {
+		f, _ := val.numval.Float64()
+		if err := i.checkGrowth(f * math.Log2E); err != nil {
+			return nil, err
+		}
+	}
+	if err := val.Exp(scale); err != nil {
+		return nil, err
+	}
+	val.Round(scale, i.RoundingMode)
+	return []*Value{val}, nil
+})
+
+// constantValue returns c to the interpreter's scale.
+func (i *Interpreter) constantValue(c *constant) *Value {
+	val := &Value{numval: c.at(i.Scale)}
+	val.Round(i.Scale, i.RoundingMode)
+	return val
+}
+
+// Pi returns pi to the interpreter's scale. It's computed the first
+// time it's needed, and again only when the scale grows.
+func (i *Interpreter) Pi() *Value {
+	return i.constantValue(i.pi)
+}
+
+// E returns e, the base of the natural logarithm, to the interpreter's
+// scale. Like Pi, it's cached.
+func (i *Interpreter) E() *Value {
+	return i.constantValue(i.e)
+}
+
+// PiOperation implements the {pi} extension.
+var PiOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Stack.Push(i.Pi())
+	return nil
+})
+
+// EOperation implements the {e} extension.
+var EOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Stack.Push(i.E())
+	return nil
+})
+
+// MathExtensions are native versions of the math library's functions,
+// which are much faster at large scales, and its constants.
+var MathExtensions = ExtensionSet{
+	`ln`:  LnOperation,
+	`exp`: ExpOperation,
+	`pi`:  PiOperation,
+	`e`:   EOperation,
+}
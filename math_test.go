@@ -0,0 +1,69 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMathExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`ln and exp`, func(t *testing.T) {
+		test(`20k2{ln}`)
+		expect(`0.69314718055994530941`)
+
+		test(`20k1{exp}`)
+		expect(`2.71828182845904523536`)
+	})
+
+	t.Run(`results have the scale of k`, func(t *testing.T) {
+		test(`0k1{exp}dX`)
+		expect(`0`, `2`)
+
+		test(`5k2{ln}dX`)
+		expect(`5.00000`, `0.69314`)
+	})
+
+	t.Run(`the value's own scale counts too`, func(t *testing.T) {
+		test(`0k1.000{exp}X`)
+		expect(`3`)
+	})
+
+	t.Run(`constants`, func(t *testing.T) {
+		test(`20k{pi}`)
+		expect(`3.14159265358979323846`)
+
+		test(`20k{e}`)
+		expect(`2.71828182845904523536`)
+
+		test(`0k{pi}{e}`)
+		expect(`2`, `3`)
+
+		test(`40k{pi}`)
+		expect(`3.1415926535897932384626433832795028841971`)
+	})
+
+	t.Run(`they agree with the math library`, func(t *testing.T) {
+		if err := interpreter.LoadMathLibrary(); err != nil {
+			t.Fatalf(`could not load the math library: %v`, err)
+		}
+		test(`30k3{exp}3lex-`)
+		expect(`0.` + strings.Repeat(`0`, 30))
+	})
+}
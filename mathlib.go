@@ -0,0 +1,28 @@
+package godc
+
+import (
+	_ "embed"
+)
+
+//go:embed mathlib.dc
+var mathLibrary string
+
+// LoadMathLibrary defines the functions in the math library: e^x,
+// ln, sine, cosine and arctangent, in the registers e, l, s, c and a.
+// It's the equivalent of bc -l.
+func (i *Interpreter) LoadMathLibrary() error {
+	radix := i.InputRadix
+	i.InputRadix = 10
+	defer func() { i.InputRadix = radix }()
+	return i.InterpretMacro([]rune(mathLibrary))
+}
+
+// MathLibraryOperation implements the {mathlib} extension.
+var MathLibraryOperation = OperationAdapter(func(i *Interpreter) error {
+	return i.LoadMathLibrary()
+})
+
+// MathLibraryExtensions load the math library at run time.
+var MathLibraryExtensions = ExtensionSet{
+	`mathlib`: MathLibraryOperation,
+}
@@ -0,0 +1,82 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMathLibrary(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	if err := interpreter.LoadMathLibrary(); err != nil {
+		t.Fatalf(`could not load the math library: %v`, err)
+	}
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`exp`, func(t *testing.T) {
+		test(`20k1lex`)
+		expect(`2.71828182845904523536`)
+
+		test(`20k_1lex`)
+		expect(`0.36787944117144232159`)
+
+		test(`20k0lex`)
+		expect(`1.00000000000000000000`)
+	})
+
+	t.Run(`ln`, func(t *testing.T) {
+		test(`20k2llx`)
+		expect(`0.69314718055994530941`)
+
+		test(`20k.1llx`)
+		expect(`-2.30258509299404568401`)
+
+		test(`20k1llx`)
+		expect(`0.00000000000000000000`)
+	})
+
+	t.Run(`trigonometry`, func(t *testing.T) {
+		test(`20k1lax4*`)
+		expect(`3.14159265358979323844`)
+
+		test(`20k1lsx`)
+		expect(`0.84147098480789650665`)
+
+		test(`20k100lsx`)
+		expect(`-0.50636564110975879365`)
+
+		test(`20k1lcx`)
+		expect(`0.54030230586813971740`)
+
+		test(`20k0lcx`)
+		expect(`1.00000000000000000000`)
+	})
+
+	t.Run(`other registers are left alone`, func(t *testing.T) {
+		test(`0k5sx6Sx1lexRlx`)
+		expect(`6`)
+
+		test(`LxRlx`)
+		expect(`5`)
+	})
+
+	t.Run(`the library doesn't depend on the input radix`, func(t *testing.T) {
+		test(`16i14k1lex`)
+		expect(`2.71828182845904523536`)
+		interpreter.InputRadix = 10
+	})
+}
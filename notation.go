@@ -0,0 +1,159 @@
+package godc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Notation decides how numbers are laid out when they're printed.
+type Notation int
+
+const (
+	// NotationFixed prints numbers with a fixed number of fractional
+	// digits, as dc does.
+	NotationFixed Notation = iota
+	// NotationScientific prints numbers as a significand between 1 and
+	// the radix, times a power of the radix.
+	NotationScientific
+	// NotationEngineering is like NotationScientific, but the exponent
+	// is always a multiple of three.
+	NotationEngineering
+)
+
+// DefaultSignificantDigits is how many digits of the significand are
+// printed in scientific and engineering notation unless told otherwise.
+const DefaultSignificantDigits = 10
+
+var notationNames = map[Notation]string{
+	NotationFixed:       `fixed`,
+	NotationScientific:  `scientific`,
+	NotationEngineering: `engineering`,
+}
+
+// String implements fmt.Stringer.
+func (n Notation) String() string {
+	if name, ok := notationNames[n]; ok {
+		return name
+	}
+	return fmt.Sprintf(`Notation(%d)`, int(n))
+}
+
+// ParseNotation converts the name of a notation, as returned by
+// String, back to a Notation.
+func ParseNotation(name string) (Notation, error) {
+	for notation, n := range notationNames {
+		if n == name {
+			return notation, nil
+		}
+	}
+	return NotationFixed, fmt.Errorf(`unknown notation %q`, name)
+}
+
+// radixPower returns radix^exp as a rational, so exp may be negative.
+func radixPower(radix, exp int64) *big.Rat {
+	abs := exp
+	if abs < 0 {
+		abs = -abs
+	}
+	p := new(big.Int).Exp(big.NewInt(radix), big.NewInt(abs), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+// floorLog returns the largest e such that radix^e <= x, for a
+// positive x.
+func floorLog(x *big.Rat, radix int64) int64 {
+	e := int64(math.Floor(log10Estimate(x) / math.Log10(float64(radix))))
+	for radixPower(radix, e).Cmp(x) > 0 {
+		e--
+	}
+	for radixPower(radix, e+1).Cmp(x) <= 0 {
+		e++
+	}
+	return e
+}
+
+// floorDiv divides, rounding towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// NotationText formats a number in scientific or engineering notation
+// with the given number of significant digits, rounding the digits
+// that don't fit according to mode. The exponent is written in
+// decimal after an e, and counts powers of the radix.
+func (n *Value) NotationText(radix int64, notation Notation, digits int64, mode RoundingMode) string {
+	if n.Type != VTNumber {
+		return n.Text(radix, 0)
+	}
+	if digits < 1 {
+		digits = 1
+	}
+	x := new(big.Rat).Abs(n.numval)
+	if x.Sign() == 0 {
+		return n.Text(radix, digits-1) + `e0`
+	}
+	e := floorLog(x, radix)
+	for {
+		exp := e
+		if notation == NotationEngineering {
+			exp = floorDiv(e, 3) * 3
+		}
+		intDigits := e - exp + 1
+		places := digits - intDigits
+		if places < 0 {
+			places = 0
+		}
+		significand := new(big.Rat).Quo(x, radixPower(radix, exp))
+		rounded := roundRat(significand, radix, places, mode)
+		if rounded.Cmp(radixPower(radix, intDigits)) >= 0 {
+			// Rounding carried into another digit, as 9.99 does to 10.0.
+			e++
+			continue
+		}
+		if n.numval.Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+		b := new(strings.Builder)
+		b.WriteString((&Value{numval: rounded}).Text(radix, places))
+		fmt.Fprintf(b, `e%d`, exp)
+		return b.String()
+	}
+}
+
+func makeSetNotation(notation Notation) Operation {
+	return OperationAdapter(func(i *Interpreter) error {
+		if notation == NotationFixed {
+			i.Notation = notation
+			return nil
+		}
+		if i.Stack.Len() < 1 {
+			return ErrStackTooShort
+		}
+		p := i.Stack.Pop()
+		err := ensureNumeric(p)
+		if err != nil {
+			i.Stack.Push(p)
+			return err
+		}
+		digits, err := digitCount(p)
+		if err == nil {
+			err = i.checkDigits(digits, i.OutputRadix)
+		}
+		if err != nil {
+			i.Stack.Push(p)
+			return err
+		}
+		i.Notation = notation
+		i.SignificantDigits = digits
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotationText(t *testing.T) {
+	test := func(num, denom int64, notation Notation, digits int64, expected string) {
+		val := newValue(num, denom)
+		if actual := val.NotationText(10, notation, digits, RoundTruncate); actual != expected {
+			t.Fatalf(`expected %d / %d in %v notation to %d digits to be %s; was %s`, num, denom, notation, digits, expected, actual)
+		}
+	}
+
+	t.Run(`scientific`, func(t *testing.T) {
+		test(1500000000, 1, NotationScientific, 3, `1.50e9`)
+		test(-3, 10000, NotationScientific, 2, `-3.0e-4`)
+		test(1, 3, NotationScientific, 4, `3.333e-1`)
+		test(7, 1, NotationScientific, 1, `7e0`)
+		test(0, 1, NotationScientific, 3, `0.00e0`)
+	})
+
+	t.Run(`engineering`, func(t *testing.T) {
+		test(1500000000, 1, NotationEngineering, 3, `1.50e9`)
+		test(15000000000, 1, NotationEngineering, 3, `15.0e9`)
+		test(150000, 1, NotationEngineering, 4, `150.0e3`)
+		test(3, 10000, NotationEngineering, 2, `300e-6`)
+		test(123456, 1, NotationEngineering, 2, `123e3`)
+	})
+
+	t.Run(`rounding carries into the exponent`, func(t *testing.T) {
+		val := newValue(9999, 1)
+		if actual := val.NotationText(10, NotationScientific, 2, RoundHalfUp); actual != `1.0e4` {
+			t.Fatalf(`expected 9999 to round to 1.0e4; was %s`, actual)
+		}
+		val = newValue(999999, 1)
+		if actual := val.NotationText(10, NotationEngineering, 3, RoundHalfUp); actual != `1.00e6` {
+			t.Fatalf(`expected 999999 to round to 1.00e6; was %s`, actual)
+		}
+	})
+
+	t.Run(`other radixes`, func(t *testing.T) {
+		val := newValue(384, 1)
+		if actual := val.NotationText(16, NotationScientific, 3, RoundTruncate); actual != `1.80e2` {
+			t.Fatalf(`expected 384 in hexadecimal to be 1.80e2; was %s`, actual)
+		}
+	})
+
+	t.Run(`parsing notation names`, func(t *testing.T) {
+		for _, notation := range []Notation{NotationFixed, NotationScientific, NotationEngineering} {
+			parsed, err := ParseNotation(notation.String())
+			if err != nil || parsed != notation {
+				t.Fatalf(`expected to parse %q as %d; got %d, %v`, notation.String(), notation, parsed, err)
+			}
+		}
+		if _, err := ParseNotation(`roman`); err == nil {
+			t.Fatalf(`expected an error parsing an unknown notation`)
+		}
+	})
+}
+
+func TestNotationExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`scientific`, func(t *testing.T) {
+		test(`4{sci}20k1 7/ 123456789`)
+		expect(`1.234e8`, `1.428e-1`)
+	})
+
+	t.Run(`engineering`, func(t *testing.T) {
+		test(`3{eng}20k1 7/ 123456789`)
+		expect(`123e6`, `142e-3`)
+	})
+
+	t.Run(`back to fixed`, func(t *testing.T) {
+		test(`{fixed}2k1 7/`)
+		expect(`0.14`)
+	})
+}
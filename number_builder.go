@@ -1,4 +1,4 @@
-package main
+package godc
 
 import (
 	"fmt"
@@ -9,37 +9,86 @@ import (
 // NumberBuilder handles creating a Value from a stream of
 // digits.
 type NumberBuilder struct {
-	buff    *strings.Builder
-	sign    bool
-	dotSeen bool
-	State   OperationState
+	buff     *strings.Builder
+	sign     bool
+	dotSeen  bool
+	exponent *strings.Builder
+	expSeen  bool
+	expSign  bool
+	State    OperationState
 }
 
-func isDigit(r rune) bool {
+// MaxInputRadix is the largest radix numbers can be read in: there's
+// a digit for every letter up to Z.
+const MaxInputRadix = 36
+
+// ErrDigitOutOfRange is returned when a number has a digit that's too
+// big for the input radix.
+var ErrDigitOutOfRange = fmt.Errorf(`digit out of range for the input radix`)
+
+// digitValue returns the value of r as a digit, or -1 if it isn't one.
+func digitValue(r rune) int64 {
+	if r >= '0' && r <= '9' {
+		return int64(r - '0')
+	}
+	if r >= 'A' && r <= 'Z' {
+		return int64(r-'A') + 10
+	}
+	return -1
+}
+
+// isDigit reports whether r can be part of a number in the given
+// input radix. As in dc, 0-9 and A-F are always digits, so that Ai
+// resets the radix from any base. The letters after F are only digits
+// when the radix needs them; otherwise they're commands.
+func isDigit(r rune, radix uint8) bool {
 	if r == '.' {
 		return true
 	}
 	if r == '_' {
 		return true
 	}
-	if r >= '0' && r <= '9' {
-		return true
-	}
-	if r >= 'A' && r <= 'H' {
-		return true
+	d := digitValue(r)
+	return d >= 0 && (d < 16 || d < int64(radix))
+}
+
+// parseDigits reads s as an integer in the given radix. A lone digit
+// is taken at face value, like dc, but in a longer number every digit
+// must be smaller than the radix.
+func parseDigits(s string, radix uint8) (*big.Int, error) {
+	n := new(big.Int)
+	base := big.NewInt(int64(radix))
+	for _, r := range s {
+		d := digitValue(r)
+		if d >= int64(radix) && len(s) > 1 {
+			return nil, fmt.Errorf(`%w: %c in %s is not a radix %d digit`, ErrDigitOutOfRange, r, s, radix)
+		}
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(d))
 	}
-	return false
+	return n, nil
 }
 
 // Operate implements the Operator interface
 func (n *NumberBuilder) Operate(i *Interpreter, r rune) (bool, error) {
-	if !isDigit(r) {
+	r = i.numberRune(r)
+	if r == skipRune {
+		return false, nil
+	}
+	if r == 'e' && n.State == OSHungry && !n.expSeen && !i.Strict {
+		n.expSeen = true
+		return false, nil
+	}
+	if !isDigit(r, i.InputRadix) {
 		err := n.Flush(i)
 		if err != nil {
 			return true, err
 		}
 		return true, ErrContinueProcessingRune
 	}
+	if n.expSeen {
+		return n.operateExponent(i, r)
+	}
 	if n.State == OSHungry && r == '_' {
 		err := n.Flush(i)
 		if err != nil {
@@ -66,44 +115,87 @@ func (n *NumberBuilder) Operate(i *Interpreter, r rune) (bool, error) {
 	return false, nil
 }
 
+// operateExponent collects the digits after the e in scientific
+// notation. The exponent is a whole number, and may be negative.
+func (n *NumberBuilder) operateExponent(i *Interpreter, r rune) (bool, error) {
+	if r == '_' && n.exponent.Len() == 0 && !n.expSign {
+		n.expSign = true
+		return false, nil
+	}
+	if r == '_' || r == '.' {
+		err := n.Flush(i)
+		if err != nil {
+			return true, err
+		}
+		return true, ErrContinueProcessingRune
+	}
+	n.exponent.WriteRune(r)
+	return false, nil
+}
+
 // NewNumberBuilder initializes internal structures in a NumberBuilder
 func NewNumberBuilder() *NumberBuilder {
 	return &NumberBuilder{
-		buff: new(strings.Builder),
+		buff:     new(strings.Builder),
+		exponent: new(strings.Builder),
 	}
 }
 
 func (n *NumberBuilder) reset() {
 	n.buff.Reset()
+	n.exponent.Reset()
 	n.dotSeen = false
 	n.sign = false
+	n.expSeen = false
+	n.expSign = false
 	n.State = OSNotHungry
 }
 
 // Flush finalizes the number and pushes it onto the stack.
 func (n *NumberBuilder) Flush(i *Interpreter) error {
 	var v Value
+	defer n.reset()
 	s := n.buff.String()
-	numerator := &big.Int{}
-	denominator := &big.Int{}
+	digits := s
+	var fracDigits int64
 
 	if n.dotSeen {
 		pointPos := strings.LastIndex(s, `.`) + 1
-		fracDigits := len(s) - pointPos
-		withoutPoints := strings.Replace(s, `.`, ``, 1)
-		_, ok := numerator.SetString(withoutPoints, int(i.InputRadix))
-		if !ok {
-			return fmt.Errorf(`could not parse %s as a radix %d integer`, s, i.InputRadix)
-		}
-		denominator.Exp(big.NewInt(int64(i.InputRadix)), big.NewInt(int64(fracDigits)), nil)
+		fracDigits = int64(len(s) - pointPos)
+		digits = strings.Replace(s, `.`, ``, 1)
+	}
+	numerator, err := parseDigits(digits, i.InputRadix)
+	if err != nil {
+		return err
+	}
+	exponent, err := parseDigits(n.exponent.String(), i.InputRadix)
+	if err != nil {
+		return err
+	}
+	if !exponent.IsInt64() {
+		return fmt.Errorf(`exponent %s is too large`, n.exponent.String())
+	}
+	// Scientific notation moves the radix point, so the exponent
+	// just shifts the count of fractional digits.
+	shift := fracDigits
+	if n.expSign {
+		shift += exponent.Int64()
 	} else {
-		_, ok := numerator.SetString(s, int(i.InputRadix))
-		if !ok {
-			return fmt.Errorf(`could not parse %s as a radix %d integer`, s, i.InputRadix)
-		}
-		denominator.SetInt64(1)
+		shift -= exponent.Int64()
+	}
+	if err := i.checkDigits(shift, int64(i.InputRadix)); err != nil {
+		return err
+	}
+	radix := big.NewInt(int64(i.InputRadix))
+	num := new(big.Rat)
+	if shift >= 0 {
+		denominator := new(big.Int).Exp(radix, big.NewInt(shift), nil)
+		num.SetFrac(numerator, denominator)
+		v.scale = shift
+	} else {
+		numerator.Mul(numerator, new(big.Int).Exp(radix, big.NewInt(-shift), nil))
+		num.SetInt(numerator)
 	}
-	num := (&big.Rat{}).SetFrac(numerator, denominator)
 
 	if n.sign {
 		num.Neg(num)
@@ -112,6 +204,5 @@ func (n *NumberBuilder) Flush(i *Interpreter) error {
 	v.numval = num
 
 	i.Stack.Push(&v)
-	n.reset()
 	return nil
 }
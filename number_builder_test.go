@@ -1,4 +1,4 @@
-package main
+package godc
 
 import (
 	"testing"
@@ -69,4 +69,47 @@ func TestNumberBuilder(t *testing.T) {
 
 	test(`12.34_56.78.90`)
 	expect(`0.90`, `-56.78`, `12.34`)
+
+	test(`1.5e9`)
+	expect(`1500000000.00`)
+
+	test(`3e_4`)
+	expect(`0.00`)
+
+	interp.Precision = 5
+	test(`_3e_4`)
+	expect(`-0.00030`)
+
+	test(`12.5e_1`)
+	expect(`1.25000`)
+
+	test(`1e2_3e1`)
+	expect(`-30.00000`, `100.00000`)
+
+	test(`2e1.5`)
+	expect(`0.50000`, `20.00000`)
+
+	interp.InputRadix = 16
+	test(`1.8e1`)
+	expect(`24.00000`)
+	interp.InputRadix = 10
+}
+
+func TestNumberBuilderScale(t *testing.T) {
+	interp := NewInterpreter()
+	for str, scale := range map[string]int64{
+		`1.5e9`:   0,
+		`1.5e_1`:  2,
+		`12.34e1`: 1,
+		`7e_3`:    3,
+	} {
+		for _, r := range str + ` ` {
+			if err := interp.Interpret(r); err != nil {
+				t.Fatalf(`error interpreting %q: %v`, str, err)
+			}
+		}
+		if actual := interp.Stack.Pop().Scale(); actual != scale {
+			t.Fatalf(`expected %s to have scale %d; was %d`, str, scale, actual)
+		}
+	}
 }
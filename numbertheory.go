@@ -0,0 +1,58 @@
+package godc
+
+import (
+	"math/big"
+)
+
+// GCDOperation implements the {gcd} extension. It pops two whole
+// numbers and pushes their greatest common divisor, which is never
+// negative. The GCD of 0 and 0 is 0.
+var GCDOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
+	err := ensureInteger(left, right)
+	if err != nil {
+		return nil, err
+	}
+	gcd := new(big.Int).GCD(nil, nil, left.numval.Num(), right.numval.Num())
+	return []*Value{{numval: new(big.Rat).SetInt(gcd)}}, nil
+})
+
+// LCMOperation implements the {lcm} extension. It pops two whole
+// numbers and pushes their least common multiple, which is never
+// negative. The LCM of 0 and anything is 0.
+var LCMOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
+	err := ensureInteger(left, right)
+	if err != nil {
+		return nil, err
+	}
+	a, b := left.numval.Num(), right.numval.Num()
+	lcm := new(big.Int)
+	if a.Sign() != 0 && b.Sign() != 0 {
+		gcd := new(big.Int).GCD(nil, nil, a, b)
+		lcm.Quo(a, gcd)
+		lcm.Mul(lcm, b)
+		lcm.Abs(lcm)
+	}
+	return []*Value{{numval: new(big.Rat).SetInt(lcm)}}, nil
+})
+
+// ModInverseOperation implements the {modinv} extension, the companion
+// of '|'. It pops a modulus m and a number n, and pushes the x for
+// which n·x ≡ 1 (mod m).
+var ModInverseOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
+	err := ensureInteger(left, right)
+	if err != nil {
+		return nil, err
+	}
+	inv := left.Dup()
+	if err = inv.ModInverse(right); err != nil {
+		return nil, err
+	}
+	return []*Value{inv}, nil
+})
+
+// NumberTheoryExtensions work with the divisibility of whole numbers.
+var NumberTheoryExtensions = ExtensionSet{
+	`gcd`:    GCDOperation,
+	`lcm`:    LCMOperation,
+	`modinv`: ModInverseOperation,
+}
@@ -0,0 +1,97 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNumberTheoryExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`gcd`, func(t *testing.T) {
+		test(`12 18{gcd}`)
+		expect(`6`)
+
+		test(`_12 18{gcd}`)
+		expect(`6`)
+
+		test(`17 5{gcd}`)
+		expect(`1`)
+
+		test(`0 7{gcd}`)
+		expect(`7`)
+
+		test(`0 0{gcd}`)
+		expect(`0`)
+	})
+
+	t.Run(`lcm`, func(t *testing.T) {
+		test(`4 6{lcm}`)
+		expect(`12`)
+
+		test(`_4 6{lcm}`)
+		expect(`12`)
+
+		test(`0 6{lcm}`)
+		expect(`0`)
+
+		test(`123456789012345678901234567890 987654321{lcm}`)
+		expect(`13548070124980948012498094801236261410`)
+	})
+
+	t.Run(`modular inverse`, func(t *testing.T) {
+		test(`3 11{modinv}`)
+		expect(`4`)
+
+		test(`_3 11{modinv}`)
+		expect(`7`)
+
+		test(`17 3120{modinv}`)
+		expect(`2753`)
+
+		test(`17 3120{modinv}17*3120%`)
+		expect(`1`)
+	})
+
+	t.Run(`no modular inverse`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `6 9{modinv}`)
+		if !errors.Is(err, ErrNoInverse) {
+			t.Fatalf(`expected a no inverse error; got %v`, err)
+		}
+		if interpreter.Stack.Len() != 2 {
+			t.Fatalf(`expected the operands to be left on the stack; found %d values`, interpreter.Stack.Len())
+		}
+		buff.Reset()
+
+		err = testWithInterpreter(interpreter, `6 0{modinv}`)
+		if !errors.Is(err, ErrDivideByZero) {
+			t.Fatalf(`expected a divide by zero error; got %v`, err)
+		}
+		buff.Reset()
+	})
+
+	t.Run(`fractions are rejected`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `1.5 3{gcd}`)
+		if !errors.Is(err, ErrIntegersOnly) {
+			t.Fatalf(`expected a whole number error; got %v`, err)
+		}
+		buff.Reset()
+	})
+}
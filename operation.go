@@ -1,18 +1,26 @@
-package main
+package godc
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"math/big"
+	"strings"
 )
 
-// ErrNotARegisterName is returned when a register operation
-// receives a rune that isn't a register name (a lowercase letter)
-var ErrNotARegisterName = fmt.Errorf(`not a register name`)
-
 // ErrNotImplemented occurs when the user tries to use an operation
 // that dc understands, but I haven't gotten to yet.
 var ErrNotImplemented = fmt.Errorf(`not implemented`)
 
+// ErrUnknownCommand is returned by a strict interpreter for a rune
+// that isn't one of dc's commands, where GNU dc would report it as
+// unimplemented. Other interpreters ignore such runes.
+var ErrUnknownCommand = fmt.Errorf(`unknown command`)
+
+// ErrNegativeScale is returned when the 'k' command is given a
+// negative scale.
+var ErrNegativeScale = fmt.Errorf(`scale can't be negative`)
+
 // ErrValueNotNumeric is returned when you try to do a number thing
 // with a string.
 var ErrValueNotNumeric = fmt.Errorf(`value is not numeric`)
@@ -21,6 +29,17 @@ var ErrValueNotNumeric = fmt.Errorf(`value is not numeric`)
 // with a number.
 var ErrValueNotString = fmt.Errorf(`value is not a string`)
 
+// ErrInputRadixOutOfRange is returned when the 'i' command is given
+// a radix smaller than 2 or larger than MaxInputRadix.
+var ErrInputRadixOutOfRange = fmt.Errorf(`input radix must be between 2 and %d`, MaxInputRadix)
+
+// ErrOutputRadixOutOfRange is returned when the 'o' command is given
+// a radix smaller than 2 or larger than MaxOutputRadix.
+var ErrOutputRadixOutOfRange = fmt.Errorf(`output radix must be between 2 and %d`, MaxOutputRadix)
+
+// MaxOutputRadix is the largest radix numbers can be printed in.
+const MaxOutputRadix = math.MaxInt32
+
 // ErrContinueProcessingRune is returned by operations that gobble
 // up input until they encounter something they don't recognize.
 // It indicates that the operation is completed, but the rune should
@@ -36,6 +55,22 @@ func ensureNumeric(vals ...*Value) error {
 	return nil
 }
 
+// ErrIntegersOnly is returned by operations that only make sense for
+// whole numbers.
+var ErrIntegersOnly = fmt.Errorf(`value is not a whole number`)
+
+func ensureInteger(vals ...*Value) error {
+	if err := ensureNumeric(vals...); err != nil {
+		return err
+	}
+	for _, val := range vals {
+		if !val.IsInt() {
+			return ErrIntegersOnly
+		}
+	}
+	return nil
+}
+
 // Operation consumes runes and manipulates stacks and registers.
 type Operation interface {
 	// Operate operates on a rune. It returns a bool indicating
@@ -52,16 +87,6 @@ const (
 	OSHungry    OperationState = true
 )
 
-func isRegister(r rune) bool {
-	if r < 'a' {
-		return false
-	}
-	if r > 'z' {
-		return false
-	}
-	return true
-}
-
 // An operation that takes a post-positional argument, that
 // is a register to operate on. This violates the backward-only
 // operation of most dc operations. You could implement e.g.
@@ -69,6 +94,10 @@ func isRegister(r rune) bool {
 type RegisterOperation struct {
 	State OperationState
 	Func  func(stack, register *Stack) error
+	// Level, if it's set, is called with the register's name after
+	// Func succeeds, for commands like S and L that add or remove a
+	// level of the register, with an array of its own.
+	Level func(i *Interpreter, register rune)
 }
 
 // Operate implements the Operator interface.
@@ -82,11 +111,40 @@ func (so *RegisterOperation) Operate(i *Interpreter, register rune) (bool, error
 	}
 	defer func() { so.State = OSNotHungry }()
 
-	if !isRegister(register) {
-		return true, ErrNotARegisterName
+	reg := i.Register(register)
+	if err := so.Func(i.Stack, reg); err != nil {
+		return true, err
+	}
+	if err := i.checkDepth(reg); err != nil {
+		i.Stack.Push(reg.Pop()) // only S adds to a register's stack
+		return true, err
+	}
+	if so.Level != nil {
+		so.Level(i, register)
 	}
+	return true, nil
+}
 
-	return true, so.Func(i.Stack, i.Registers[register])
+// ArrayOperation is like RegisterOperation, but operates on the
+// array belonging to the register named by its post-positional
+// argument.
+type ArrayOperation struct {
+	State OperationState
+	Func  func(stack *Stack, array *Array) error
+}
+
+// Operate implements the Operator interface.
+// It returns false on its first call to indicate that it's
+// waiting for a second rune, that specifies the register whose
+// array to operate on.
+func (ao *ArrayOperation) Operate(i *Interpreter, register rune) (bool, error) {
+	if ao.State == OSNotHungry {
+		ao.State = OSHungry
+		return false, nil
+	}
+	defer func() { ao.State = OSNotHungry }()
+
+	return true, ao.Func(i.Stack, i.Array(register))
 }
 
 // Most operations are not hungry, so the operator pattern helps
@@ -99,13 +157,13 @@ func (oa OperationAdapter) Operate(i *Interpreter, _ rune) (bool, error) {
 	return true, oa(i)
 }
 
-func makeUnaryOperation(op func(*Value) ([]*Value, error)) Operation {
+func makeUnaryOperation(op func(*Interpreter, *Value) ([]*Value, error)) Operation {
 	return OperationAdapter(func(i *Interpreter) error {
 		if i.Stack.Len() < 1 {
 			return ErrStackTooShort
 		}
 		val := i.Stack.Pop()
-		nums, err := op(val)
+		nums, err := op(i, val)
 		if err != nil {
 			i.Stack.Push(val)
 			return err
@@ -117,13 +175,13 @@ func makeUnaryOperation(op func(*Value) ([]*Value, error)) Operation {
 	})
 }
 
-func makeBinaryOperation(op func(*Value, *Value) ([]*Value, error)) Operation {
+func makeBinaryOperation(op func(*Interpreter, *Value, *Value) ([]*Value, error)) Operation {
 	return OperationAdapter(func(i *Interpreter) error {
 		if i.Stack.Len() < 2 {
 			return ErrStackTooShort
 		}
 		right, left := i.Stack.Pop(), i.Stack.Pop() // Note reverse order of left and right
-		nums, err := op(left, right)
+		nums, err := op(i, left, right)
 		if err != nil {
 			i.Stack.Push(left)
 			i.Stack.Push(right)
@@ -136,50 +194,87 @@ func makeBinaryOperation(op func(*Value, *Value) ([]*Value, error)) Operation {
 	})
 }
 
-// QuitOperation implements the 'q' command
+// QuitOperation implements the 'q' command. As in dc, it leaves the
+// macro it's in and the macro that called that, and quits if it isn't
+// in a macro at all, or is in one run from the top level. With
+// QuitOneLevel set, unless the interpreter is Strict, it only leaves
+// the macro it's in.
 var QuitOperation = OperationAdapter(func(i *Interpreter) error {
-	i.QuitLevel = 1
+	i.QuitLevel, i.macroQuit = 2, false
+	if i.quitOneLevel() {
+		i.QuitLevel = 1
+	}
 	return ErrExitRequested
 })
 
-// MacroQuitOperation implements the 'Q' command
+// MacroQuitOperation implements the 'Q' command. It pops a number, and
+// leaves that many macros. As in dc, it stops at the top level rather
+// than quit, so outside a macro it does nothing else.
 var MacroQuitOperation = OperationAdapter(func(i *Interpreter) error {
-	i.QuitLevel = 0
+	i.QuitLevel, i.macroQuit = 0, true
 	if i.Stack.Len() > 0 {
 		if i.Stack.Peek().Type == VTNumber {
 			quitLevel := i.Stack.Pop().Int()
 			i.QuitLevel = quitLevel
 		}
 	}
+	if !i.running {
+		i.QuitLevel = 0
+		return nil
+	}
 	return ErrExitRequested
 })
 
 // PrintOperation implements the 'p' command.
 var PrintOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
 	p := i.Stack.Peek().Dup()
-	i.println(p.Text(int64(i.OutputRadix), i.Precision))
+	if i.hookPrint('p', p) {
+		i.println(i.colorText(p))
+	}
 	return nil
 })
 
-// PrintOperation implements the 'P' command.
+// PrintRawOperation implements the 'P' command, which pops a value and
+// writes a string as it is, or a number as raw bytes.
 var PrintRawOperation = OperationAdapter(func(i *Interpreter) error {
 	if i.Stack.Len() < 1 {
 		return ErrStackTooShort
 	}
 	val := i.Stack.Pop()
-	if val.Type == VTString {
-		i.print(val)
+	if !i.hookPrint('P', val) {
 		return nil
 	}
-	biVal := val.numval
-	biVal.Abs(biVal)
-	iVal := (&big.Int{}).Div(biVal.Num(), biVal.Denom())
-	for _, b := range iVal.Bytes() {
-		i.printf(`%c`, b)
+	if val.Type == VTString {
+		i.out.Write(i.encodeString(val.strval))
+		return nil
 	}
+	// Like GNU dc, write the whole part of the number's magnitude,
+	// base 256, most significant byte first, so 0 writes nothing.
+	whole := new(big.Int).Quo(val.numval.Num(), val.numval.Denom())
+	i.out.Write(whole.Abs(whole).Bytes())
 	return nil
 })
 
+// ToCharacterOperation implements the 'a' command. As in GNU dc, it
+// pops a number and pushes a string of one character, the low-order
+// byte of the whole part of its magnitude, or pops a string and pushes
+// a string of its first character. When strings aren't bytes, the byte
+// is the rune with the same value.
+var ToCharacterOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+	if val.Type == VTString {
+		if len(val.strval) == 0 {
+			return []*Value{{Type: VTString}}, nil
+		}
+		return []*Value{{Type: VTString, strval: []rune{val.strval[0]}}}, nil
+	}
+	whole := new(big.Int).Quo(val.numval.Num(), val.numval.Denom())
+	low := whole.Abs(whole).Uint64() & 0xff
+	return []*Value{{Type: VTString, strval: []rune{rune(low)}}}, nil
+})
+
 // PopAndPrintOperation implements the 'n' command
 var PopAndPrintOperation = OperationAdapter(func(i *Interpreter) error {
 	if i.Stack.Len() < 1 {
@@ -187,7 +282,9 @@ var PopAndPrintOperation = OperationAdapter(func(i *Interpreter) error {
 	}
 	val := i.Stack.Pop()
 	dup := val.Dup()
-	i.print(dup.Text(int64(i.OutputRadix), i.Precision))
+	if i.hookPrint('n', dup) {
+		i.print(i.colorText(dup))
+	}
 	return nil
 })
 
@@ -197,13 +294,36 @@ var PushLengthOperation = OperationAdapter(func(i *Interpreter) error {
 	return nil
 })
 
+// LengthOperation implements the 'Z' command.
+var LengthOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	val := i.Stack.Pop()
+	i.Stack.Push(&Value{numval: big.NewRat(val.Length(i.Scale), 1)})
+	return nil
+})
+
+// ScaleOperation implements the 'X' command.
+var ScaleOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	val := i.Stack.Pop()
+	i.Stack.Push(&Value{numval: big.NewRat(val.Scale(), 1)})
+	return nil
+})
+
 // PrintStackOperation implements the 'f' command.
 var PrintStackOperation = OperationAdapter(func(i *Interpreter) error {
-	for _, num := range i.Stack.values {
-		dup := num.Dup()
-		// dc prints stack in reverse order, so top-of-stack is top-of-list
-		defer func(d *Value) { i.println(d.Text(int64(i.OutputRadix), i.Precision)) }(dup)
-	}
+	// dc prints stack in reverse order, so top-of-stack is top-of-list
+	i.Stack.Each(func(_ int, v *Value) bool {
+		d := v.Dup()
+		if i.hookPrint('f', d) {
+			i.println(i.colorText(d))
+		}
+		return true
+	})
 	return nil
 })
 
@@ -214,7 +334,7 @@ var ClearStackOperation = OperationAdapter(func(i *Interpreter) error {
 })
 
 // AdditionOperation implements the '+' command.
-var AdditionOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+var AdditionOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
@@ -227,7 +347,7 @@ var AdditionOperation = makeBinaryOperation(func(left, right *Value) ([]*Value,
 })
 
 // SubtrationOperation implements the '-' command.
-var SubtractionOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+var SubtractionOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
@@ -240,20 +360,27 @@ var SubtractionOperation = makeBinaryOperation(func(left, right *Value) ([]*Valu
 })
 
 // MultiplicationOperation implements the '*' command.
-var MultiplicationOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+// Like dc, the product is reduced to the sum of the
+// operands' scales, but no further than the larger of
+// the interpreter's scale and either operand's scale.
+var MultiplicationOperation = makeBinaryOperation(func(i *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
 	}
+	scale := minScale(left.scale+right.scale, maxScale(i.Scale, maxScale(left.scale, right.scale)))
 	err = left.Multiply(right)
 	if err != nil {
 		return nil, err
 	}
+	left.Round(scale, i.RoundingMode)
 	return []*Value{left}, nil
 })
 
 // DivisionOperation implements the "/" command.
-var DivisionOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+// Like dc, the quotient is reduced to the interpreter's
+// scale.
+var DivisionOperation = makeBinaryOperation(func(i *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
@@ -262,16 +389,17 @@ var DivisionOperation = makeBinaryOperation(func(left, right *Value) ([]*Value,
 	if err != nil {
 		return nil, err
 	}
+	left.Round(i.Scale, i.RoundingMode)
 	return []*Value{left}, nil
 })
 
 // ModuloOperation implements the '%' command.
-var ModuloOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+var ModuloOperation = makeBinaryOperation(func(i *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
 	}
-	_, r, err := left.QuotientRemainder(right)
+	_, r, err := left.QuotientRemainder(right, i.Scale)
 	if err != nil {
 		return nil, err
 	}
@@ -279,28 +407,62 @@ var ModuloOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, er
 })
 
 // QuotientRemainderOperation implements the '~' command.
-var QuotientRemainderOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+var QuotientRemainderOperation = makeBinaryOperation(func(i *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
 	}
-	q, r, err := left.QuotientRemainder(right)
+	q, r, err := left.QuotientRemainder(right, i.Scale)
 	if err != nil {
 		return nil, err
 	}
+	if i.Strict {
+		// GNU dc leaves the remainder on top.
+		return []*Value{q, r}, nil
+	}
 	return []*Value{r, q}, nil
 })
 
 // ExponentOperation implements the '^' command.
-var ExponentOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+// Like dc, the result of a positive whole exponent is
+// reduced to the base's scale times the exponent, but no
+// further than the larger of the interpreter's scale and
+// the base's scale, and the result of a negative one to
+// the interpreter's scale. Fractional exponents are
+// computed to the larger of the interpreter's scale and
+// the base's scale.
+var ExponentOperation = makeBinaryOperation(func(i *Interpreter, left, right *Value) ([]*Value, error) {
 	err := ensureNumeric(left, right)
 	if err != nil {
 		return nil, err
 	}
+	if i.MaxNumberBits > 0 {
+		// Work out how big the result will be, give or take a factor of
+		// two, before making it. The result itself is checked after.
+		e, _ := new(big.Rat).Abs(right.numval).Float64()
+		if err := i.checkGrowth(math.Max(float64(bits(left)-1), 0) * math.Ceil(e)); err != nil {
+			return nil, err
+		}
+	}
+	base := left.scale
+	if !right.IsInt() {
+		scale := maxScale(i.Scale, base)
+		if err = left.Power(right, scale); err != nil {
+			return nil, err
+		}
+		left.Round(scale, i.RoundingMode)
+		return []*Value{left}, nil
+	}
+	negative := right.numval.Sign() < 0
 	err = left.Exponent(right)
 	if err != nil {
 		return nil, err
 	}
+	if negative {
+		left.Round(i.Scale, i.RoundingMode)
+	} else {
+		left.Round(minScale(left.scale, maxScale(i.Scale, base)), i.RoundingMode)
+	}
 	return []*Value{left}, nil
 })
 
@@ -319,68 +481,109 @@ var ModExponentOperation = OperationAdapter(func(i *Interpreter) error {
 })
 
 // SqrtOperation implements the 'v' command.
-var SqrtOperation = makeUnaryOperation(func(val *Value) ([]*Value, error) {
-	err := val.Sqrt()
-	return []*Value{val}, err
+// Like dc, the root is reduced to the larger of the
+// interpreter's scale and the value's scale.
+var SqrtOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	scale := maxScale(i.Scale, val.scale)
+	if err := val.Sqrt(scale); err != nil {
+		return []*Value{val}, err
+	}
+	val.Round(scale, i.RoundingMode)
+	return []*Value{val}, nil
 })
 
 // DuplicationOperation implements the 'd' command.
-var DuplicationOperation = makeUnaryOperation(func(val *Value) ([]*Value, error) {
+var DuplicationOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
 	return []*Value{val, val.Dup()}, nil
 })
 
+// DropOperation implements the 'R' command.
+var DropOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+	return nil, nil
+})
+
 // ReverseOperation implements the 'r' command.
-var ReverseOperation = makeBinaryOperation(func(left, right *Value) ([]*Value, error) {
+var ReverseOperation = makeBinaryOperation(func(_ *Interpreter, left, right *Value) ([]*Value, error) {
 	return []*Value{right, left}, nil
 })
 
-// MoveToRegisterOperation implements the 's' (save) command.
-var MoveToRegisterOperation = &RegisterOperation{
-	Func: func(stack, register *Stack) error {
-		if stack.Len() < 1 {
-			return ErrStackTooShort
-		}
-		register.Clear()
-		register.Push(stack.Pop())
-		return nil
-	},
+// moveToRegister implements the 's' (save) command.
+func moveToRegister(stack, register *Stack) error {
+	if stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	register.Clear()
+	register.Push(stack.Pop())
+	return nil
 }
 
-// MoveFromRegister implements the 'l' (load) command.
-var MoveFromRegisterOperation = &RegisterOperation{
-	Func: func(stack, register *Stack) error {
-		if register.Len() < 1 {
-			return ErrStackTooShort
-		}
-		stack.Push(register.Peek())
-		return nil
-	},
+// moveFromRegister implements the 'l' (load) command.
+func moveFromRegister(stack, register *Stack) error {
+	if register.Len() < 1 {
+		return ErrStackTooShort
+	}
+	stack.Push(register.Peek().Dup())
+	return nil
 }
 
-// MoveToRegisterStackOperation implements the 'S' command.
-var MoveToRegisterStackOperation = &RegisterOperation{
-	Func: func(stack, register *Stack) error {
-		if stack.Len() < 1 {
-			return ErrStackTooShort
-		}
-		register.Push(stack.Pop())
-		return nil
-	},
+// moveToRegisterStack implements the 'S' command.
+func moveToRegisterStack(stack, register *Stack) error {
+	if stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	register.Push(stack.Pop())
+	return nil
 }
 
-// MoveFromRegisterStackOperation implements the 'L' command.
-var MoveFromRegisterStackOperation = &RegisterOperation{
-	Func: func(stack, register *Stack) error {
-		if register.Len() < 1 {
-			return ErrStackTooShort
-		}
-		stack.Push(register.Pop())
+// moveFromRegisterStack implements the 'L' command.
+func moveFromRegisterStack(stack, register *Stack) error {
+	if register.Len() < 1 {
+		return ErrStackTooShort
+	}
+	stack.Push(register.Pop())
+	return nil
+}
+
+// storeToArray implements the ':' command. It pops an index, then a
+// value, and stores the value in the register's array.
+func storeToArray(stack *Stack, array *Array) error {
+	if stack.Len() < 2 {
+		return ErrStackTooShort
+	}
+	index, err := arrayIndex(stack.Peek())
+	if err != nil {
+		return err
+	}
+	stack.Pop()
+	array.Set(index, stack.Pop())
+	return nil
+}
+
+// loadFromArray implements the ';' command. It pops an index and
+// pushes the value stored at that index in the register's array, or 0
+// if nothing has been stored there.
+func loadFromArray(stack *Stack, array *Array) error {
+	if stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	index, err := arrayIndex(stack.Peek())
+	if err != nil {
+		return err
+	}
+	stack.Pop()
+	val := array.Get(index)
+	if val == nil {
+		stack.Push(&Value{numval: new(big.Rat)})
 		return nil
-	},
+	}
+	stack.Push(val.Dup())
+	return nil
 }
 
-// SetPrecisionOperation implements the 'k' command.
-var SetPrecisionOperation = OperationAdapter(func(i *Interpreter) error {
+// SetScaleOperation implements the 'k' command. Unless the
+// display precision has been set separately, it also sets the
+// number of digits printed.
+var SetScaleOperation = OperationAdapter(func(i *Interpreter) error {
 	if i.Stack.Len() < 1 {
 		return ErrStackTooShort
 	}
@@ -389,13 +592,28 @@ var SetPrecisionOperation = OperationAdapter(func(i *Interpreter) error {
 	if err != nil {
 		return err
 	}
-	i.Precision = p.Int()
+	if p.numval.Sign() < 0 {
+		i.Stack.Push(p)
+		return ErrNegativeScale
+	}
+	scale, err := digitCount(p)
+	if err == nil {
+		err = i.checkDigits(scale, 10)
+	}
+	if err != nil {
+		i.Stack.Push(p)
+		return err
+	}
+	i.Scale = scale
+	if !i.SeparatePrecision {
+		i.Precision = i.Scale
+	}
 	return nil
 })
 
-// GetPrecisionOperation implements the 'K' command.
-var GetPrecisionOperation = OperationAdapter(func(i *Interpreter) error {
-	i.Stack.Push(&Value{numval: big.NewRat(int64(i.Precision), 1)})
+// GetScaleOperation implements the 'K' command.
+var GetScaleOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Stack.Push(&Value{numval: big.NewRat(i.Scale, 1)})
 	return nil
 })
 
@@ -409,10 +627,13 @@ var SetInputRadixOperation = OperationAdapter(func(i *Interpreter) error {
 	if err != nil {
 		return err
 	}
-	i.InputRadix = uint8(p.Int())
-	if i.InputRadix > 18 {
-		return fmt.Errorf(`warning: godc can't tell the difference between I as a digit and the I command`)
+	radix := p.Dup()
+	radix.IntVal()
+	if radix.numval.Cmp(big.NewRat(2, 1)) < 0 || radix.numval.Cmp(big.NewRat(MaxInputRadix, 1)) > 0 {
+		i.Stack.Push(p)
+		return ErrInputRadixOutOfRange
 	}
+	i.InputRadix = uint8(radix.Int())
 	return nil
 })
 
@@ -422,7 +643,7 @@ var GetInputRadixOperation = OperationAdapter(func(i *Interpreter) error {
 	return nil
 })
 
-// SetInputRadixOperation implements the 'o' command.
+// SetOutputRadixOperation implements the 'o' command.
 var SetOutputRadixOperation = OperationAdapter(func(i *Interpreter) error {
 	if i.Stack.Len() < 1 {
 		return ErrStackTooShort
@@ -432,13 +653,19 @@ var SetOutputRadixOperation = OperationAdapter(func(i *Interpreter) error {
 	if err != nil {
 		return err
 	}
-	i.OutputRadix = uint8(p.Int())
+	radix := p.Dup()
+	radix.IntVal()
+	if radix.numval.Cmp(big.NewRat(2, 1)) < 0 || radix.numval.Cmp(big.NewRat(MaxOutputRadix, 1)) > 0 {
+		i.Stack.Push(p)
+		return ErrOutputRadixOutOfRange
+	}
+	i.OutputRadix = radix.Int()
 	return nil
 })
 
-// GetOutputRedixOperation implements the 'I' command.
+// GetOutputRadixOperation implements the 'O' command.
 var GetOutputRadixOperation = OperationAdapter(func(i *Interpreter) error {
-	i.Stack.Push(&Value{numval: big.NewRat(int64(i.OutputRadix), 1)})
+	i.Stack.Push(&Value{numval: big.NewRat(i.OutputRadix, 1)})
 	return nil
 })
 
@@ -483,6 +710,7 @@ func (sb *StringBuilder) Operate(i *Interpreter, r rune) (bool, error) {
 		if sb.BracketLevel == 0 {
 			sb.OperationState = OSNotHungry
 			dup := (&sb.Value).Dup()
+			dup.code = new(macroCode)
 			i.Stack.Push(dup)
 			return true, nil
 		} else {
@@ -496,12 +724,6 @@ func (sb *StringBuilder) Operate(i *Interpreter, r rune) (bool, error) {
 	return false, nil
 }
 
-// StringBuilderOperation implements the '[' command.
-var StringBuilderOperation = new(StringBuilder)
-
-// NumberBuilderOperation gobbles up digits and builds a number.
-var NumberBuilderOperation = NewNumberBuilder()
-
 // ExecuteMacroOperation implements the 'x' command.
 var ExecuteMacroOperation = OperationAdapter(func(i *Interpreter) error {
 	if i.Stack.Len() < 1 {
@@ -512,13 +734,28 @@ var ExecuteMacroOperation = OperationAdapter(func(i *Interpreter) error {
 		i.Stack.Push(val)
 		return nil
 	}
-	return i.InterpretMacro(val.strval)
+	return i.callMacro(val.strval, i.compiled(val))
+})
+
+// ReadLineOperation implements the '?' command. It reads a line
+// from the interpreter's input and executes it.
+var ReadLineOperation = OperationAdapter(func(i *Interpreter) error {
+	i.out.Flush() // show any prompt
+	line, err := i.input.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if i.ByteStrings {
+		return i.callMacro(decodeBytes([]byte(line)), nil)
+	}
+	return i.callMacro([]rune(line), nil)
 })
 
 // MacroOperation supports execution of conditional macros.
 // Positive conditional macros (e.g. >) are supported directly, and
 // negative conditional macros (e.g. !>) are supported with the aid
-// of the NegativeMacroOperation type.
+// of the NegativeMacroOperation type. Two strings are compared by
+// their text, but a string can't be compared with a number.
 type MacroOperation struct {
 	State OperationState
 	// Whether the previous two values in the stack indicate the macro
@@ -533,17 +770,14 @@ func (so *MacroOperation) Operate(i *Interpreter, register rune) (bool, error) {
 		so.State = OSHungry
 		return false, nil
 	}
-	defer func() { so.State = OSNotHungry }()
-
-	if !isRegister(register) {
-		return true, ErrNotARegisterName
-	}
+	// Reset before running the macro, which may use this command too.
+	so.State = OSNotHungry
 
 	if i.Stack.Len() < 2 {
 		return true, ErrStackTooShort
 	}
 
-	reg := i.Registers[register]
+	reg := i.Register(register)
 	if reg.Len() < 1 {
 		return true, ErrStackTooShort
 	}
@@ -552,7 +786,7 @@ func (so *MacroOperation) Operate(i *Interpreter, register rune) (bool, error) {
 	}
 
 	left, right := i.Stack.Pop(), i.Stack.Pop()
-	if left.Type != VTNumber || right.Type != VTNumber {
+	if left.Type != right.Type || left.Type == VTString && i.Strict {
 		return true, ErrValueNotNumeric
 	}
 
@@ -560,30 +794,38 @@ func (so *MacroOperation) Operate(i *Interpreter, register rune) (bool, error) {
 		return true, nil
 	}
 
-	macro := reg.Pop().strval
+	macro := reg.Peek()
 	i.CurrentOperation = nil
-	return true, i.InterpretMacro(macro)
+	return true, i.callMacro(macro.strval, i.compiled(macro))
 }
 
-// ExecuteMacroIfGTOperation implements the '>' command.
-var ExecuteMacroIfGTOperation = &MacroOperation{
-	Predicate: func(left, right *Value) bool {
-		return left.numval.Cmp(right.numval) > 0
-	},
+// compareValues compares two numbers, or two strings by their text,
+// returning -1, 0 or 1 like big.Rat.Cmp.
+func compareValues(left, right *Value) int {
+	if left.Type == VTString {
+		return strings.Compare(string(left.strval), string(right.strval))
+	}
+	if left.numval.IsInt() && right.numval.IsInt() {
+		// Rat.Cmp cross-multiplies, which allocates even for whole
+		// numbers, as loop counters usually are.
+		return left.numval.Num().Cmp(right.numval.Num())
+	}
+	return left.numval.Cmp(right.numval)
+}
+
+// isGreater is the predicate of the '>' command.
+func isGreater(left, right *Value) bool {
+	return compareValues(left, right) > 0
 }
 
-// ExecuteMacroIfLTOperation implements the '<' command.
-var ExecuteMacroIfLTOperation = &MacroOperation{
-	Predicate: func(left, right *Value) bool {
-		return left.numval.Cmp(right.numval) < 0
-	},
+// isLess is the predicate of the '<' command.
+func isLess(left, right *Value) bool {
+	return compareValues(left, right) < 0
 }
 
-// ExecuteMacroIfEqOperation implements the '=' command.
-var ExecuteMacroIfEqOperation = &MacroOperation{
-	Predicate: func(left, right *Value) bool {
-		return left.numval.Cmp(right.numval) == 0
-	},
+// isEqual is the predicate of the '=' command.
+func isEqual(left, right *Value) bool {
+	return compareValues(left, right) == 0
 }
 
 // NegativeMacroOperation implements the negative conditional
@@ -617,25 +859,20 @@ func (so *NegativeMacroOperation) Operate(i *Interpreter, r rune) (bool, error)
 		so.Op = &MacroOperation{}
 		switch r {
 		case '<':
-			so.Op.Predicate = negate(ExecuteMacroIfLTOperation.Predicate)
+			so.Op.Predicate = negate(isLess)
 		case '>':
-			so.Op.Predicate = negate(ExecuteMacroIfGTOperation.Predicate)
+			so.Op.Predicate = negate(isGreater)
 		case '=':
-			so.Op.Predicate = negate(ExecuteMacroIfEqOperation.Predicate)
+			so.Op.Predicate = negate(isEqual)
 		default:
 			// TODO: read to newline and execute in subshell
 			return false, ErrNotImplemented
 		}
+		return so.Op.Operate(i, r)
 	}
-	finished, err := so.Op.Operate(i, r)
-	if finished {
-		so.State = OSNotHungry
-		so.Op = nil
-	} else {
-		so.State = OSHungry
-	}
-	return finished, err
+	// Reset before running the macro, which may use this command too.
+	op := so.Op
+	so.State = OSNotHungry
+	so.Op = nil
+	return op.Operate(i, r)
 }
-
-// This implements all multi-rune commands beginning with '!'
-var ExecuteMacroNegativeOperation = new(NegativeMacroOperation)
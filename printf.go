@@ -0,0 +1,231 @@
+package godc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrBadFormat is returned when a format string for {printf} can't be
+// understood.
+var ErrBadFormat = fmt.Errorf(`bad format`)
+
+// formatVerb is one % conversion in a format string.
+type formatVerb struct {
+	verb      rune
+	flags     string
+	width     int
+	precision int // or -1 for the default
+}
+
+// formatPart is either text to copy, or a verb to format a value with.
+type formatPart struct {
+	text string
+	verb *formatVerb
+}
+
+// parseFormat splits a format string into its text and its verbs.
+// The text may have the escapes \n, \t and \\, as bc's print does.
+func parseFormat(format []rune) ([]formatPart, error) {
+	var parts []formatPart
+	text := new(strings.Builder)
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, formatPart{text: text.String()})
+			text.Reset()
+		}
+	}
+	for n := 0; n < len(format); n++ {
+		r := format[n]
+		switch {
+		case r == '\\' && n+1 < len(format):
+			n++
+			switch format[n] {
+			case 'n':
+				text.WriteRune('\n')
+			case 't':
+				text.WriteRune('\t')
+			default:
+				text.WriteRune(format[n])
+			}
+		case r == '%' && n+1 < len(format) && format[n+1] == '%':
+			n++
+			text.WriteRune('%')
+		case r == '%':
+			v := &formatVerb{precision: -1}
+			for n++; n < len(format) && strings.ContainsRune(`-+0'`, format[n]); n++ {
+				v.flags += string(format[n])
+			}
+			for ; n < len(format) && format[n] >= '0' && format[n] <= '9'; n++ {
+				v.width = v.width*10 + int(format[n]-'0')
+			}
+			if n < len(format) && format[n] == '.' {
+				v.precision = 0
+				for n++; n < len(format) && format[n] >= '0' && format[n] <= '9'; n++ {
+					v.precision = v.precision*10 + int(format[n]-'0')
+				}
+			}
+			if n == len(format) {
+				return nil, fmt.Errorf(`%w: %q ends in the middle of a verb`, ErrBadFormat, string(format))
+			}
+			v.verb = format[n]
+			if !strings.ContainsRune(`sdfexXob`, v.verb) {
+				return nil, fmt.Errorf(`%w: unknown verb %%%c`, ErrBadFormat, v.verb)
+			}
+			flush()
+			parts = append(parts, formatPart{verb: v})
+		default:
+			text.WriteRune(r)
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// format writes v as the verb asks. Strings only go with %s. Numbers
+// are written in decimal, with the DecimalSeparator, except by %s,
+// which writes them as p would, and by %x, %X, %o and %b. The ' flag
+// groups their digits with the GroupSeparator.
+func (i *Interpreter) format(f *formatVerb, v *Value) (string, error) {
+	if v.Type == VTString {
+		if f.verb != 's' {
+			return ``, ErrValueNotNumeric
+		}
+		s := []rune(string(i.encodeString(v.strval)))
+		if f.precision >= 0 && f.precision < len(s) {
+			s = s[:f.precision]
+		}
+		return pad(f, ``, string(s)), nil
+	}
+	var digits string
+	decimal := true
+	switch f.verb {
+	case 's':
+		if f.precision >= 0 {
+			digits = v.RoundedText(i.OutputRadix, int64(f.precision), i.RoundingMode)
+		} else {
+			digits = i.Format(v)
+		}
+		digits = i.localize(digits)
+		decimal = false
+	case 'd':
+		digits = v.RoundedText(10, 0, i.RoundingMode)
+	case 'f':
+		precision := i.Precision
+		if f.precision >= 0 {
+			precision = int64(f.precision)
+		}
+		digits = v.RoundedText(10, precision, i.RoundingMode)
+	case 'e':
+		significant := i.SignificantDigits
+		if f.precision >= 0 {
+			significant = int64(f.precision) + 1
+		}
+		digits = v.NotationText(10, NotationScientific, significant, i.RoundingMode)
+	case 'x', 'X', 'o', 'b':
+		radix := map[rune]int64{'x': 16, 'X': 16, 'o': 8, 'b': 2}[f.verb]
+		digits = v.RoundedText(radix, 0, i.RoundingMode)
+		decimal = false
+		if f.verb == 'x' {
+			digits = strings.ToLower(digits)
+		}
+	}
+	sign := ``
+	if strings.HasPrefix(digits, `-`) {
+		sign, digits = `-`, digits[1:]
+	} else if strings.ContainsRune(f.flags, '+') {
+		sign = `+`
+	}
+	if decimal {
+		group := ``
+		if strings.ContainsRune(f.flags, '\'') {
+			group = i.GroupSeparator
+		}
+		digits = localizeNumber(digits, i.DecimalSeparator, group)
+	}
+	return pad(f, sign, digits), nil
+}
+
+// pad pads sign and digits out to the verb's width, with spaces on
+// the left, or on the right with the - flag, or with zeros after the
+// sign with the 0 flag.
+func pad(f *formatVerb, sign, digits string) string {
+	short := f.width - len([]rune(sign)) - len([]rune(digits))
+	if short <= 0 {
+		return sign + digits
+	}
+	switch {
+	case strings.ContainsRune(f.flags, '-'):
+		return sign + digits + strings.Repeat(` `, short)
+	case strings.ContainsRune(f.flags, '0') && f.verb != 's':
+		return sign + strings.Repeat(`0`, short) + digits
+	}
+	return strings.Repeat(` `, short) + sign + digits
+}
+
+// sprintf pops a format string, and the values for its verbs, the
+// first deepest, and returns them formatted. If it fails, the stack is
+// left as it was.
+func (i *Interpreter) sprintf() (string, error) {
+	if i.Stack.Len() < 1 {
+		return ``, ErrStackTooShort
+	}
+	format := i.Stack.Peek()
+	if format.Type != VTString {
+		return ``, ErrValueNotString
+	}
+	parts, err := parseFormat(format.strval)
+	if err != nil {
+		return ``, err
+	}
+	var verbs int
+	for _, part := range parts {
+		if part.verb != nil {
+			verbs++
+		}
+	}
+	if i.Stack.Len() < verbs+1 {
+		return ``, ErrStackTooShort
+	}
+	b := new(strings.Builder)
+	depth := verbs
+	for _, part := range parts {
+		if part.verb == nil {
+			b.WriteString(part.text)
+			continue
+		}
+		s, err := i.format(part.verb, i.Stack.At(depth))
+		if err != nil {
+			return ``, err
+		}
+		b.WriteString(s)
+		depth--
+	}
+	i.Stack.truncate(i.Stack.Len() - verbs - 1)
+	return b.String(), nil
+}
+
+// PrintfOperation implements the {printf} extension. It pops a format
+// string, and a value for each of its verbs, and prints them, much as
+// C's printf would. The verbs are %s, %d, %f, %e, %x, %X, %o and %b,
+// with the flags -, +, 0 and ', a width, and a precision; %% prints a
+// %. The values are taken in the order they were pushed, so
+// 1 2[%d and %d\n]{printf} prints "1 and 2".
+var PrintfOperation = OperationAdapter(func(i *Interpreter) error {
+	s, err := i.sprintf()
+	if err != nil {
+		return err
+	}
+	i.print(s)
+	return nil
+})
+
+// SprintfOperation implements the {sprintf} extension, which is like
+// {printf}, but pushes the string instead of printing it.
+var SprintfOperation = OperationAdapter(func(i *Interpreter) error {
+	s, err := i.sprintf()
+	if err != nil {
+		return err
+	}
+	i.Stack.Push(NewValueFromString(s))
+	return nil
+})
@@ -0,0 +1,64 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrintf(t *testing.T) {
+	interpreter := NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	expect := func(src, expected string) {
+		out.Reset()
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if out.String() != expected {
+			t.Fatalf(`expected %q to print %q; found %q`, src, expected, out.String())
+		}
+		if interpreter.Stack.Len() != 0 {
+			t.Fatalf(`expected %q to use up the stack; found %d values`, src, interpreter.Stack.Len())
+		}
+	}
+
+	expect(`1 2[%d and %d\n]{printf}`, "1 and 2\n")
+	expect(`[apples] 3 1.5 [%-8s|%4d|%8.2f|%%]{printf}`, "apples  |   3|    1.50|%")
+	expect(`255 255 255 255 [%x %X %o %b]{printf}`, "ff FF 377 11111111")
+	expect(`42 _7 _7 [%05d %+d %05d\t|]{printf}`, "00042 -7 -0007\t|")
+	expect(`2k 2 3/ d [%s %f]{printf}`, "0.66 0.66")
+	expect(`{halfeven} 2.5 3.5 0.125 [%d %d %.2f]{printf} {truncate}`, "2 4 0.12")
+	expect(`123456 d [%.3e|%e]{printf}`, "1.234e5|1.234560000e5")
+	expect(`[abcdef] d [%.3s|%-5.2s|]{printf}`, "abc|ab   |")
+	expect(`0k 16o 255 255 [%s %d]{printf} 10o`, "FF 255")
+
+	// The separators are used, but digits are only grouped by p, %s
+	// and the ' flag.
+	interpreter.EvalString(`[.]{group} [,]{decimal}`)
+	expect(`1k 1234567,5 d d [%s %'.1f %.1f]{printf}`, "1.234.567,5 1.234.567,5 1234567,5")
+	interpreter.EvalString(`[]{group} []{decimal}`)
+
+	expect(`1 2 [%d+%d=]{sprintf} [%s]{printf}`, "1+2=")
+}
+
+func TestPrintfErrors(t *testing.T) {
+	interpreter := NewInterpreter()
+	expect := func(src string, expected error, depth int) {
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); !errors.Is(err, expected) {
+			t.Fatalf(`expected %q to fail with %v; found %v`, src, expected, err)
+		}
+		if interpreter.Stack.Len() != depth {
+			t.Fatalf(`expected %q to leave %d values; found %d`, src, depth, interpreter.Stack.Len())
+		}
+	}
+
+	expect(`{printf}`, ErrStackTooShort, 0)
+	expect(`1 {printf}`, ErrValueNotString, 1)
+	expect(`1 [%d %d]{printf}`, ErrStackTooShort, 2)
+	expect(`[x] [%d]{printf}`, ErrValueNotNumeric, 2)
+	expect(`1 [%q]{printf}`, ErrBadFormat, 2)
+	expect(`1 [%5]{printf}`, ErrBadFormat, 2)
+}
@@ -0,0 +1,18 @@
+package godc
+
+// PrintHook is called with each value that the p, n, P and f commands
+// print, and the command printing it, so a program embedding the
+// interpreter can show values as they are, rather than parsing the
+// text. The value is a copy, so changing it doesn't change the stack.
+// If the hook returns false, the value isn't written to the output as
+// well.
+type PrintHook func(command rune, v *Value) bool
+
+// hookPrint passes a copy of v to the interpreter's PrintHook, if it
+// has one, and reports whether v should be written to the output too.
+func (i *Interpreter) hookPrint(command rune, v *Value) bool {
+	if i.PrintHook == nil {
+		return true
+	}
+	return i.PrintHook(command, v.Dup())
+}
@@ -0,0 +1,43 @@
+package godc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrintHook(t *testing.T) {
+	interpreter := NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	var printed []string
+	write := true
+	interpreter.PrintHook = func(command rune, v *Value) bool {
+		printed = append(printed, fmt.Sprintf(`%c %s`, command, interpreter.Format(v)))
+		v.strval = []rune(`changed`)
+		return write
+	}
+
+	if _, err := interpreter.EvalString(`[a]p 1 2f 3n [b]P`); err != nil {
+		t.Fatal(err)
+	}
+	expected := `p a,f 2,f 1,f a,n 3,P b`
+	if actual := strings.Join(printed, `,`); actual != expected {
+		t.Fatalf(`expected the hook to see %q; saw %q`, expected, actual)
+	}
+	if output.String() != "a\n2\n1\na\n3b" {
+		t.Fatalf(`expected the values to be printed as well; found %q`, output.String())
+	}
+	if bottom := interpreter.Stack.bottomUp()[0]; string(bottom.strval) != `a` {
+		t.Fatalf(`expected the hook to be given a copy; the stack has %q`, string(bottom.strval))
+	}
+
+	write, printed = false, nil
+	output.Reset()
+	if _, err := interpreter.EvalString(`p f`); err != nil {
+		t.Fatal(err)
+	}
+	if len(printed) != 4 || output.String() != `` {
+		t.Fatalf(`expected the hook to stop the values being printed; saw %v and printed %q`, printed, output.String())
+	}
+}
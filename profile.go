@@ -0,0 +1,176 @@
+package godc
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profile counts how many times each command and macro is run, and
+// how long they take. Set an interpreter's Profile to start counting.
+//
+// A command's time runs until the next command starts, so it doesn't
+// include the macros it calls. A macro's time does, except for a macro
+// it ends by calling, which takes its place.
+type Profile struct {
+	commands map[string]*ProfileEntry
+	macros   map[string]*ProfileEntry
+	pending  bool          // whether a command has started, and not been counted
+	elapsed  time.Duration // how long it's taken so far
+	since    time.Time     // when it was last timed from, if it's being timed
+}
+
+// ProfileEntry is what a Profile knows about one command or macro.
+type ProfileEntry struct {
+	Name  string // the command, like '+' add, or the macro's text
+	Count int64
+	Time  time.Duration
+}
+
+// NewProfile returns an empty Profile.
+func NewProfile() *Profile {
+	return &Profile{
+		commands: make(map[string]*ProfileEntry),
+		macros:   make(map[string]*ProfileEntry),
+	}
+}
+
+func (p *Profile) entry(entries map[string]*ProfileEntry, name string) *ProfileEntry {
+	e, ok := entries[name]
+	if !ok {
+		e = &ProfileEntry{Name: name}
+		entries[name] = e
+	}
+	return e
+}
+
+// command is called as a command starts. It counts the one before,
+// and starts timing this one.
+func (p *Profile) command(i *Interpreter) {
+	p.finish(i)
+	p.pending, p.since = true, time.Now()
+}
+
+// finish counts the last command to start, which is the one the
+// interpreter still describes.
+func (p *Profile) finish(i *Interpreter) {
+	if !p.pending {
+		return
+	}
+	p.pause()
+	e := p.entry(p.commands, i.commandLabel())
+	e.Count++
+	e.Time += p.elapsed
+	p.pending, p.elapsed = false, 0
+}
+
+// pause stops timing, while the interpreter waits for input.
+func (p *Profile) pause() {
+	if !p.since.IsZero() {
+		p.elapsed += time.Since(p.since)
+		p.since = time.Time{}
+	}
+}
+
+func (p *Profile) startMacro(f *frame) {
+	p.entry(p.macros, string(f.macro)).Count++
+	f.started = time.Now()
+}
+
+func (p *Profile) endMacro(f *frame) {
+	p.entry(p.macros, string(f.macro)).Time += time.Since(f.started)
+}
+
+// sorted returns copies of the entries, the slowest first.
+func sorted(entries map[string]*ProfileEntry) []ProfileEntry {
+	list := make([]ProfileEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Time != list[b].Time {
+			return list[a].Time > list[b].Time
+		}
+		return list[a].Name < list[b].Name
+	})
+	return list
+}
+
+// Commands returns what's known about each command, the slowest
+// first. Numbers are counted together, as are strings.
+func (p *Profile) Commands() []ProfileEntry {
+	return sorted(p.commands)
+}
+
+// Macros returns what's known about each macro, the slowest first.
+// Macros are told apart by their text.
+func (p *Profile) Macros() []ProfileEntry {
+	return sorted(p.macros)
+}
+
+// commandLabel names the command being run for a profile.
+func (i *Interpreter) commandLabel() string {
+	name := i.operationName()
+	if name == `number` || strings.HasPrefix(name, `{`) {
+		return name
+	}
+	if name == `` {
+		return fmt.Sprintf(`%q`, i.command)
+	}
+	return fmt.Sprintf(`%q %s`, i.command, name)
+}
+
+// PrintProfile writes the interpreter's profile to w: each command and
+// macro, the slowest first, with how many times it was run and how
+// long it took. Macros are named by the registers that hold them, if
+// any do.
+func (i *Interpreter) PrintProfile(w io.Writer) {
+	p := i.Profile
+	if p == nil {
+		fmt.Fprintln(w, `not profiling`)
+		return
+	}
+	p.finish(i)
+	fmt.Fprintf(w, "%-24s %10s %14s\n", `command`, `count`, `time`)
+	for _, e := range p.Commands() {
+		fmt.Fprintf(w, "%-24s %10d %14v\n", e.Name, e.Count, e.Time)
+	}
+	macros := p.Macros()
+	if len(macros) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%-24s %10s %14s\n", `macro`, `count`, `time`)
+	for _, e := range macros {
+		fmt.Fprintf(w, "%-24s %10d %14v\n", i.macroLabel(e.Name), e.Count, e.Time)
+	}
+}
+
+// macroLabel names a macro by the registers holding it, followed by
+// the start of its text.
+func (i *Interpreter) macroLabel(macro string) string {
+	var names []rune
+	for r, reg := range i.Registers {
+		if v := reg.Peek(); v != nil && v.Type == VTString && string(v.strval) == macro {
+			names = append(names, r)
+		}
+	}
+	sort.Slice(names, func(a, b int) bool { return names[a] < names[b] })
+	b := new(strings.Builder)
+	for _, r := range names {
+		fmt.Fprintf(b, `%q `, r)
+	}
+	text := []rune(strings.ReplaceAll(macro, "\n", ` `))
+	if len(text) > 16 {
+		text = append(text[:15], '…')
+	}
+	fmt.Fprintf(b, `[%s]`, string(text))
+	return b.String()
+}
+
+// PrintProfileOperation implements the {profile} extension.
+var PrintProfileOperation = OperationAdapter(func(i *Interpreter) error {
+	i.PrintProfile(i.out)
+	return nil
+})
@@ -0,0 +1,56 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	buff := new(strings.Builder)
+	interpreter.PrintProfile(buff)
+	if buff.String() != "not profiling\n" {
+		t.Fatalf(`expected no profile; found %q`, buff.String())
+	}
+
+	interpreter.Profile = NewProfile()
+	if err := interpreter.Run(strings.NewReader(`[d1-d0<a]sa 100lax [2*]sb 1 lbx lbx 12 18{gcd} [3]x`)); err != nil {
+		t.Fatalf(`could not run: %v`, err)
+	}
+	counts := make(map[string]int64)
+	for _, e := range interpreter.Profile.Commands() {
+		counts[e.Name] = e.Count
+	}
+	for name, expected := range map[string]int64{
+		`'<' execute if less`: 100,
+		`'d' duplicate`:       200,
+		`number`:              207,
+		`'x' execute`:         4,
+		`{gcd}`:               1,
+		`'s' store`:           2,
+	} {
+		if counts[name] != expected {
+			t.Fatalf(`expected %s to have run %d times; found %d in %v`, name, expected, counts[name], counts)
+		}
+	}
+	macros := make(map[string]int64)
+	for _, e := range interpreter.Profile.Macros() {
+		macros[e.Name] = e.Count
+	}
+	if macros[`d1-d0<a`] != 100 || macros[`2*`] != 2 || macros[`3`] != 1 {
+		t.Fatalf(`expected the macros to be counted; found %v`, macros)
+	}
+
+	buff.Reset()
+	interpreter.PrintProfile(buff)
+	profile := buff.String()
+	for _, expected := range []string{`'a' [d1-d0<a] `, `'b' [2*] `, "\n[3] ", `'<' execute if less `} {
+		if !strings.Contains(profile, expected) {
+			t.Fatalf("expected %q in the profile\n%s", expected, profile)
+		}
+	}
+	if actual := interpreter.macroLabel(`a very long macro indeed`); actual != `[a very long mac…]` {
+		t.Fatalf(`expected a long macro to be cut short; found %q`, actual)
+	}
+}
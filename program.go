@@ -0,0 +1,53 @@
+package godc
+
+import "sync"
+
+// Program is a dc program that's read once, to be run as many times as
+// needed, such as a formula evaluated against one stack after another.
+// It's compiled for an interpreter the first time it's run with it,
+// and after that only if the interpreter's input radix or commands
+// have changed. A Program may be run by several interpreters at once.
+type Program struct {
+	source string
+	mu     sync.Mutex
+	macro  *Value // keeps the program last compiled for it
+	bytes  *Value // the same, read a byte at a time, once it's needed
+}
+
+// Compile returns the Program for src. As dc's commands aren't checked
+// until they run, anything wrong with src is reported by Run.
+func Compile(src string) *Program {
+	return &Program{
+		source: src,
+		macro:  &Value{Type: VTString, strval: []rune(src), code: new(macroCode)},
+	}
+}
+
+// String returns the program's source.
+func (p *Program) String() string {
+	return p.source
+}
+
+// Run runs the program with i, much as EvalString would run its
+// source, but without reading it again. A q in it ends the program
+// without error.
+func (p *Program) Run(i *Interpreter) error {
+	p.mu.Lock()
+	macro := p.macro
+	if i.ByteStrings {
+		if p.bytes == nil {
+			p.bytes = &Value{Type: VTString, strval: decodeBytes([]byte(p.source)), code: new(macroCode)}
+		}
+		macro = p.bytes
+	}
+	prog := i.compiled(macro)
+	p.mu.Unlock()
+	if !i.running {
+		defer i.out.Flush()
+	}
+	err := i.runMacro(&frame{macro: macro.strval, prog: prog, depth: 1})
+	if err == ErrExitRequested {
+		return nil
+	}
+	return err
+}
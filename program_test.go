@@ -0,0 +1,83 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	program := Compile(`d*r2*+ lt1+st`)
+	if program.String() != `d*r2*+ lt1+st` {
+		t.Fatalf(`expected the program to keep its source; found %q`, program.String())
+	}
+	interpreter := NewInterpreter()
+	interpreter.Register('t').Push(NewValueFromInt64(0))
+	for _, tc := range []struct{ x, y, expected int64 }{{3, 4, 22}, {5, 1, 11}, {0, 0, 0}} {
+		interpreter.Stack = new(Stack)
+		interpreter.Stack.Push(NewValueFromInt64(tc.x))
+		interpreter.Stack.Push(NewValueFromInt64(tc.y))
+		if err := program.Run(interpreter); err != nil {
+			t.Fatal(err)
+		}
+		if v := interpreter.Stack.Pop(); v.Text(10, 0) != NewValueFromInt64(tc.expected).Text(10, 0) || interpreter.Stack.Len() != 0 {
+			t.Fatalf(`expected %d, %d to give %d; found %s`, tc.x, tc.y, tc.expected, v)
+		}
+	}
+	if stats := interpreter.MacroCacheStats(); stats.Misses != 1 || stats.Hits != 0 {
+		t.Fatalf(`expected the program to be compiled once; found %+v`, stats)
+	}
+	if v := interpreter.Register('t').Peek(); v.Text(10, 0) != `3` {
+		t.Fatalf(`expected the program to have counted its runs; found %s`, v)
+	}
+
+	// A change of input radix reads the program again.
+	interpreter.InputRadix = 16
+	interpreter.Stack = new(Stack)
+	interpreter.EvalString(`1 1`)
+	if err := program.Run(interpreter); err != nil {
+		t.Fatal(err)
+	}
+	if stats := interpreter.MacroCacheStats(); stats.Misses != 2 {
+		t.Fatalf(`expected the program to be compiled again for the new radix; found %+v`, stats)
+	}
+
+	// Errors and q are reported as EvalString would report them.
+	if err := Compile(`+`).Run(NewInterpreter()); !errors.Is(err, ErrStackTooShort) {
+		t.Fatalf(`expected the program's error; found %v`, err)
+	}
+	interpreter = NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	if err := Compile(`[one]p q [two]p`).Run(interpreter); err != nil {
+		t.Fatalf(`expected q to end the program without error; found %v`, err)
+	}
+	if out.String() != "one\n" {
+		t.Fatalf(`expected the program to print one, and the output to be flushed; found %q`, out.String())
+	}
+}
+
+func TestProgramConcurrently(t *testing.T) {
+	program := Compile(`0 10[r1+r1-d0<l]dslx R`)
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			interpreter := NewInterpreter()
+			for run := 0; run < 10; run++ {
+				interpreter.Stack.Clear()
+				if err := program.Run(interpreter); err != nil {
+					t.Error(err)
+					return
+				}
+				if v := interpreter.Stack.Pop(); v.Text(10, 0) != `10` {
+					t.Errorf(`expected the loop to count to 10; found %s`, v.Text(10, 0))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
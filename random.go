@@ -0,0 +1,57 @@
+package godc
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	mrand "math/rand"
+)
+
+// ErrRandomBound is returned when {rand} is asked for a number below
+// a bound that isn't positive.
+var ErrRandomBound = fmt.Errorf(`the bound for a random number must be positive`)
+
+// NewSeededRandom returns a source of random bytes that gives the same
+// sequence every time it's created with the same seed. It's suitable
+// for reproducible scripts, but not for anything that must be secret.
+func NewSeededRandom(seed int64) io.Reader {
+	return mrand.New(mrand.NewSource(seed))
+}
+
+// RandomOperation implements the {rand} extension. It pops a whole
+// number n and pushes a random whole number from 0 up to, but not
+// including, n. Every number in the range is equally likely.
+var RandomOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	err := ensureInteger(val)
+	if err != nil {
+		return nil, err
+	}
+	if val.numval.Sign() <= 0 {
+		return nil, ErrRandomBound
+	}
+	n, err := crand.Int(i.Random, val.numval.Num())
+	if err != nil {
+		return nil, err
+	}
+	return []*Value{{numval: new(big.Rat).SetInt(n)}}, nil
+})
+
+// SeedOperation implements the {seed} extension. It pops a whole
+// number and switches {rand} to a reproducible sequence seeded with it.
+var SeedOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	err := ensureInteger(val)
+	if err != nil {
+		return nil, err
+	}
+	i.Random = NewSeededRandom(val.numval.Num().Int64())
+	return nil, nil
+})
+
+// RandomExtensions generate random numbers. Unless a seed is given,
+// they draw from the operating system's cryptographically secure
+// source.
+var RandomExtensions = ExtensionSet{
+	`rand`: RandomOperation,
+	`seed`: SeedOperation,
+}
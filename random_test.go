@@ -0,0 +1,81 @@
+package godc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRandomExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	run := func(str string) {
+		interpreter.Interpret('c')
+		for _, r := range str {
+			if err := interpreter.Interpret(r); err != nil {
+				t.Fatalf(`error interpreting %q: %v`, str, err)
+			}
+		}
+	}
+	draw := func(count int) []string {
+		var values []string
+		for n := 0; n < count; n++ {
+			run(`1000000{rand}`)
+			values = append(values, interpreter.Stack.Pop().PrecisionString(0))
+		}
+		return values
+	}
+
+	t.Run(`numbers are in range`, func(t *testing.T) {
+		seen := make(map[string]bool)
+		for n := 0; n < 200; n++ {
+			run(`3{rand}`)
+			val := interpreter.Stack.Pop()
+			if !val.IsInt() || val.numval.Sign() < 0 || val.numval.Cmp(newValue(3, 1).numval) >= 0 {
+				t.Fatalf(`expected a whole number from 0 to 2; got %v`, val)
+			}
+			seen[val.PrecisionString(0)] = true
+		}
+		if len(seen) != 3 {
+			t.Fatalf(`expected to see all of 0, 1 and 2; saw %v`, seen)
+		}
+	})
+
+	t.Run(`seeded sequences repeat`, func(t *testing.T) {
+		run(`42{seed}`)
+		first := draw(5)
+		run(`42{seed}`)
+		second := draw(5)
+		for n := range first {
+			if first[n] != second[n] {
+				t.Fatalf(`expected the same sequence twice; got %v and %v`, first, second)
+			}
+		}
+		run(`43{seed}`)
+		third := draw(5)
+		same := true
+		for n := range first {
+			same = same && first[n] == third[n]
+		}
+		if same {
+			t.Fatalf(`expected a different seed to give a different sequence`)
+		}
+	})
+
+	t.Run(`bounds must be positive whole numbers`, func(t *testing.T) {
+		for str, expected := range map[string]error{
+			`0{rand}`:   ErrRandomBound,
+			`_5{rand}`:  ErrRandomBound,
+			`2.5{rand}`: ErrIntegersOnly,
+		} {
+			interpreter.Interpret('c')
+			var err error
+			for _, r := range str {
+				if err = interpreter.Interpret(r); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, expected) {
+				t.Fatalf(`expected %q to fail with %v; got %v`, str, expected, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package godc
+
+import (
+	"sort"
+)
+
+// UsedRegisters returns the names of the registers that hold anything
+// on their stacks or in their arrays, in order.
+func (i *Interpreter) UsedRegisters() []rune {
+	used := make(map[rune]bool)
+	for r, reg := range i.Registers {
+		if reg.Len() > 0 {
+			used[r] = true
+		}
+	}
+	for r, arr := range i.Arrays {
+		if arr.Len() > 0 {
+			used[r] = true
+		}
+	}
+	names := make([]rune, 0, len(used))
+	for r := range used {
+		names = append(names, r)
+	}
+	sort.Slice(names, func(a, b int) bool { return names[a] < names[b] })
+	return names
+}
+
+// PrintRegisters prints every register that holds anything, in order
+// of their names: the values on its stack, top first, like the 'f'
+// command, and then the entries of its array, by index.
+func (i *Interpreter) PrintRegisters() {
+	defer i.out.Flush()
+	i.printRegisters()
+}
+
+func (i *Interpreter) printRegisters() {
+	for _, r := range i.UsedRegisters() {
+		i.printf("register %q:\n", r)
+		if reg, ok := i.Registers[r]; ok {
+			reg.Each(func(_ int, v *Value) bool {
+				i.printf("  %s\n", i.colorText(v))
+				return true
+			})
+		}
+		if arr, ok := i.Arrays[r]; ok {
+			for _, index := range arr.Indexes() {
+				i.printf("  [%d] %s\n", index, i.colorText(arr.Get(index)))
+			}
+		}
+	}
+}
+
+// PrintRegistersOperation implements the {registers} extension.
+var PrintRegistersOperation = OperationAdapter(func(i *Interpreter) error {
+	i.PrintRegisters()
+	return nil
+})
+
+// ClearRegister empties the named register's stack and its arrays.
+func (i *Interpreter) ClearRegister(r rune) {
+	delete(i.Registers, r)
+	delete(i.Arrays, r)
+	delete(i.arrayLevels, r)
+}
+
+// ClearRegisterOperation implements the {clearreg} extension, which,
+// like the s command, takes the name of a register after it, and
+// empties that register's stack and array: {clearreg}a.
+type ClearRegisterOperation struct {
+	State OperationState
+}
+
+// Operate implements the Operation interface.
+func (co *ClearRegisterOperation) Operate(i *Interpreter, register rune) (bool, error) {
+	if co.State == OSNotHungry {
+		co.State = OSHungry
+		return false, nil
+	}
+	co.State = OSNotHungry
+	i.ClearRegister(register)
+	return true, nil
+}
@@ -0,0 +1,64 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintRegisters(t *testing.T) {
+	expect := func(src, expected string) {
+		interpreter := NewInterpreter()
+		buff := new(strings.Builder)
+		interpreter.SetOutput(buff)
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if actual := buff.String(); actual != expected {
+			t.Fatalf("expected %q to print\n%s\nfound\n%s", src, expected, actual)
+		}
+	}
+
+	expect(`{registers}`, ``)
+	expect(`1sb 2Sb [x]sa {registers}`, "register 'a':\n  x\nregister 'b':\n  2\n  1\n")
+	expect(`5 0:c 7 3:c {registers}`, "register 'c':\n  [0] 5\n  [3] 7\n")
+	expect(`1sa 2 1:a 1sb Lb {registers}`, "register 'a':\n  1\n  [1] 2\n")
+	expect("4s\n {registers}", "register '\\n':\n  4\n")
+
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	interpreter.Register('z').Push(NewValueFromInt64(9))
+	interpreter.PrintRegisters()
+	if actual := buff.String(); actual != "register 'z':\n  9\n" {
+		t.Fatalf(`expected PrintRegisters to print register z; found %q`, actual)
+	}
+}
+
+func TestUsedRegisters(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`1sc 2sa 3 4:b 5sd Ld`); err != nil {
+		t.Fatal(err)
+	}
+	interpreter.Register('e')
+	if actual := string(interpreter.UsedRegisters()); actual != `abc` {
+		t.Fatalf(`expected registers a, b and c to be used; found %q`, actual)
+	}
+}
+
+func TestClearRegister(t *testing.T) {
+	interpreter := NewInterpreter()
+	src := `1sa 2Sa 3 0:a 4sb {clearreg}a [5sc {clearreg}c 6 {clearreg}}]x`
+	if _, err := interpreter.EvalString(src); err != nil {
+		t.Fatalf(`could not run %q: %v`, src, err)
+	}
+	if actual := string(interpreter.UsedRegisters()); actual != `b` {
+		t.Fatalf(`expected only register b to be left; found %q`, actual)
+	}
+	if interpreter.Stack.Len() != 1 {
+		t.Fatalf(`expected {clearreg} to leave the stack alone; found %d values`, interpreter.Stack.Len())
+	}
+	interpreter.ClearRegister('b')
+	if len(interpreter.UsedRegisters()) != 0 {
+		t.Fatal(`expected ClearRegister to empty register b`)
+	}
+}
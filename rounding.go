@@ -0,0 +1,96 @@
+package godc
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// RoundingMode decides what happens to the digits past a number's
+// scale when it's reduced, or when it's printed.
+type RoundingMode int
+
+const (
+	// RoundTruncate discards the extra digits, as dc does.
+	RoundTruncate RoundingMode = iota
+	// RoundHalfUp rounds to the nearest digit, and ties away from zero.
+	RoundHalfUp
+	// RoundHalfEven rounds to the nearest digit, and ties to the even
+	// digit. This is also known as banker's rounding.
+	RoundHalfEven
+)
+
+var roundingModeNames = map[RoundingMode]string{
+	RoundTruncate: `truncate`,
+	RoundHalfUp:   `half-up`,
+	RoundHalfEven: `half-even`,
+}
+
+// String implements fmt.Stringer.
+func (m RoundingMode) String() string {
+	if name, ok := roundingModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf(`RoundingMode(%d)`, int(m))
+}
+
+// ParseRoundingMode converts the name of a rounding mode, as returned
+// by String, back to a RoundingMode.
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	for mode, n := range roundingModeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+	return RoundTruncate, fmt.Errorf(`unknown rounding mode %q`, name)
+}
+
+// scratchInts hold the temporary results of arithmetic that's done
+// for nearly every command, so they aren't allocated afresh each time.
+var scratchInts = sync.Pool{New: func() interface{} { return new(big.Int) }}
+
+// roundRat returns x with any digits past scale fractional digits in
+// the given radix rounded away according to mode. If there's nothing
+// to round, it returns x itself.
+func roundRat(x *big.Rat, radix, scale int64, mode RoundingMode) *big.Rat {
+	if x.IsInt() {
+		// There's nothing to round, whatever the scale.
+		return x
+	}
+	pow := new(big.Int).Exp(big.NewInt(radix), big.NewInt(scale), nil)
+	num, r := scratchInts.Get().(*big.Int), scratchInts.Get().(*big.Int)
+	defer scratchInts.Put(num)
+	defer scratchInts.Put(r)
+	num.Mul(x.Num(), pow)
+	q := new(big.Int)
+	q.QuoRem(num, x.Denom(), r)
+	if mode != RoundTruncate && r.Sign() != 0 {
+		// Compare the discarded fraction, r / denom, with one half.
+		half := r.Abs(r)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(x.Denom())
+		if cmp > 0 || (cmp == 0 && (mode == RoundHalfUp || q.Bit(0) == 1)) {
+			if x.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	return new(big.Rat).SetFrac(q, pow)
+}
+
+func makeSetRoundingMode(mode RoundingMode) Operation {
+	return OperationAdapter(func(i *Interpreter) error {
+		i.RoundingMode = mode
+		return nil
+	})
+}
+
+// RoundingExtensions choose the rounding mode used when results are
+// reduced to their scale, and when numbers are printed.
+var RoundingExtensions = ExtensionSet{
+	`truncate`: makeSetRoundingMode(RoundTruncate),
+	`halfup`:   makeSetRoundingMode(RoundHalfUp),
+	`halfeven`: makeSetRoundingMode(RoundHalfEven),
+}
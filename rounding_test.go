@@ -0,0 +1,119 @@
+package godc
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	test := func(num, denom, scale int64, mode RoundingMode, expected string) {
+		val := newValue(num, denom)
+		val.Round(scale, mode)
+		if actual := val.PrecisionString(scale); actual != expected {
+			t.Fatalf(`expected %d / %d rounded %v to %d digits to be %s; was %s`, num, denom, mode, scale, expected, actual)
+		}
+	}
+
+	t.Run(`truncate`, func(t *testing.T) {
+		test(2, 3, 2, RoundTruncate, `0.66`)
+		test(-2, 3, 2, RoundTruncate, `-0.66`)
+		test(125, 100, 1, RoundTruncate, `1.2`)
+	})
+
+	t.Run(`half up`, func(t *testing.T) {
+		test(2, 3, 2, RoundHalfUp, `0.67`)
+		test(-2, 3, 2, RoundHalfUp, `-0.67`)
+		test(125, 100, 1, RoundHalfUp, `1.3`)
+		test(-125, 100, 1, RoundHalfUp, `-1.3`)
+		test(124, 100, 1, RoundHalfUp, `1.2`)
+	})
+
+	t.Run(`half even`, func(t *testing.T) {
+		test(2, 3, 2, RoundHalfEven, `0.67`)
+		test(125, 100, 1, RoundHalfEven, `1.2`)
+		test(135, 100, 1, RoundHalfEven, `1.4`)
+		test(-135, 100, 1, RoundHalfEven, `-1.4`)
+		test(1251, 1000, 1, RoundHalfEven, `1.3`)
+	})
+
+	t.Run(`parsing mode names`, func(t *testing.T) {
+		for _, mode := range []RoundingMode{RoundTruncate, RoundHalfUp, RoundHalfEven} {
+			parsed, err := ParseRoundingMode(mode.String())
+			if err != nil || parsed != mode {
+				t.Fatalf(`expected to parse %q as %d; got %d, %v`, mode.String(), mode, parsed, err)
+			}
+		}
+		if _, err := ParseRoundingMode(`sideways`); err == nil {
+			t.Fatalf(`expected an error parsing an unknown mode`)
+		}
+	})
+}
+
+func TestRoundingExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`results are rounded to the scale`, func(t *testing.T) {
+		test(`{halfup}2k2 3/`)
+		expect(`0.67`)
+
+		test(`{halfup}2k2 3/3*`)
+		expect(`2.01`)
+
+		test(`{truncate}2k2 3/3*`)
+		expect(`1.98`)
+	})
+
+	t.Run(`printing is rounded to the precision`, func(t *testing.T) {
+		test(`{halfeven}2k0.125 0.135 0.145`)
+		expect(`0.14`, `0.14`, `0.12`)
+	})
+
+	t.Run(`printing in other radixes is rounded`, func(t *testing.T) {
+		test(`{halfup}1k16o0.96875`)
+		expect(`1.0`)
+		interpreter.OutputRadix = 10
+	})
+
+	interpreter.RoundingMode = RoundTruncate
+}
+
+func TestRoundWholeNumbers(t *testing.T) {
+	for _, mode := range []RoundingMode{RoundTruncate, RoundHalfUp, RoundHalfEven} {
+		x := big.NewRat(-42, 1)
+		if rounded := roundRat(x, 10, 3, mode); rounded.Cmp(big.NewRat(-42, 1)) != 0 {
+			t.Fatalf(`expected %s to round -42 to itself; found %s`, mode, rounded.RatString())
+		}
+	}
+	// Comparisons of whole numbers take a shortcut; check they still
+	// agree with fractions.
+	for _, c := range []struct {
+		left, right *Value
+		expected    int
+	}{
+		{NewValueFromInt64(3), NewValueFromInt64(-4), 1},
+		{NewValueFromInt64(-4), NewValueFromInt64(3), -1},
+		{NewValueFromInt64(3), NewValueFromBigRat(big.NewRat(7, 2)), -1},
+		{NewValueFromBigRat(big.NewRat(6, 2)), NewValueFromInt64(3), 0},
+	} {
+		if actual := compareValues(c.left, c.right); actual != c.expected {
+			t.Fatalf(`expected %s compared with %s to be %d; found %d`, c.left.String(), c.right.String(), c.expected, actual)
+		}
+	}
+}
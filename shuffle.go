@@ -0,0 +1,111 @@
+package godc
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ErrStackCountOutOfRange is returned when a count of values on the
+// stack, for a command such as {pick}, is negative, or zero where that
+// makes no sense.
+var ErrStackCountOutOfRange = fmt.Errorf(`stack count out of range`)
+
+// popCount pops a count of the values below it on the stack, which
+// must be at least min, and no more than there are.
+func popCount(i *Interpreter, min int64) (int, error) {
+	if i.Stack.Len() < 1 {
+		return 0, ErrStackTooShort
+	}
+	v := i.Stack.Peek()
+	if err := ensureInteger(v); err != nil {
+		return 0, err
+	}
+	if v.numval.Cmp(big.NewRat(min, 1)) < 0 {
+		return 0, ErrStackCountOutOfRange
+	}
+	if v.numval.Cmp(big.NewRat(int64(i.Stack.Len()-1), 1)) > 0 {
+		return 0, ErrStackTooShort
+	}
+	return int(i.Stack.Pop().Int()), nil
+}
+
+// RollOperation implements the {roll} extension. It pops a count, n,
+// and moves the nth value on the stack, counting the top as 1, to the
+// top, as an HP calculator's ROLL does. 2{roll} is the same as r.
+var RollOperation = OperationAdapter(func(i *Interpreter) error {
+	n, err := popCount(i, 0)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	v := i.Stack.At(n - 1)
+	for depth := n - 1; depth > 0; depth-- {
+		i.Stack.set(depth, i.Stack.At(depth-1))
+	}
+	i.Stack.set(0, v)
+	return nil
+})
+
+// RollDownOperation implements the {rolld} extension. It pops a count,
+// n, and moves the value on top of the stack down to be the nth,
+// undoing {roll}.
+var RollDownOperation = OperationAdapter(func(i *Interpreter) error {
+	n, err := popCount(i, 0)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	v := i.Stack.At(0)
+	for depth := 0; depth < n-1; depth++ {
+		i.Stack.set(depth, i.Stack.At(depth+1))
+	}
+	i.Stack.set(n-1, v)
+	return nil
+})
+
+// PickOperation implements the {pick} extension. It pops a count, n,
+// and pushes a copy of the nth value on the stack, counting the top as
+// 1. 1{pick} is the same as d.
+var PickOperation = OperationAdapter(func(i *Interpreter) error {
+	n, err := popCount(i, 1)
+	if err != nil {
+		return err
+	}
+	i.Stack.Push(i.Stack.At(n - 1).Dup())
+	return nil
+})
+
+// OverOperation implements the {over} extension. It pushes a copy of
+// the value under the top of the stack.
+var OverOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 2 {
+		return ErrStackTooShort
+	}
+	i.Stack.Push(i.Stack.At(1).Dup())
+	return nil
+})
+
+// DropNOperation implements the {dropn} extension. It pops a count,
+// n, and then drops that many values from the top of the stack.
+var DropNOperation = OperationAdapter(func(i *Interpreter) error {
+	n, err := popCount(i, 0)
+	if err != nil {
+		return err
+	}
+	i.Stack.truncate(i.Stack.Len() - n)
+	return nil
+})
+
+// ShuffleExtensions rearrange the stack, in the spirit of an HP
+// calculator's stack commands, where r and d don't go far enough.
+var ShuffleExtensions = ExtensionSet{
+	`roll`:  RollOperation,
+	`rolld`: RollDownOperation,
+	`pick`:  PickOperation,
+	`over`:  OverOperation,
+	`dropn`: DropNOperation,
+}
@@ -0,0 +1,52 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShuffleExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	expect := func(src string, expected ...string) {
+		interpreter.Stack.Clear()
+		values, err := interpreter.EvalString(src)
+		if err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		actual := make([]string, len(values))
+		for n, v := range values {
+			actual[n] = interpreter.Format(v)
+		}
+		if strings.Join(actual, ` `) != strings.Join(expected, ` `) {
+			t.Fatalf(`expected %q to leave %v; left %v`, src, expected, actual)
+		}
+	}
+	fail := func(src string, expected error) {
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); !errors.Is(err, expected) {
+			t.Fatalf(`expected %q to fail with %v; found %v`, src, expected, err)
+		}
+	}
+
+	expect(`1 2 3 4 3{roll}`, `1`, `3`, `4`, `2`)
+	expect(`1 2 3 4 4{roll}`, `2`, `3`, `4`, `1`)
+	expect(`1 2 0{roll} 1{roll}`, `1`, `2`)
+	expect(`1 2 3 4 3{rolld}`, `1`, `4`, `2`, `3`)
+	expect(`1 2 3 4 3{roll} 3{rolld}`, `1`, `2`, `3`, `4`)
+	expect(`1 [a] 3 3{pick}`, `1`, `a`, `3`, `1`)
+	expect(`1 2 1{pick}`, `1`, `2`, `2`)
+	expect(`1 2{over}`, `1`, `2`, `1`)
+	expect(`1 2 3 4 2{dropn}`, `1`, `2`)
+	expect(`1 2 0{dropn}`, `1`, `2`)
+
+	fail(`1 2 3{pick}`, ErrStackTooShort)
+	fail(`1 2 0{pick}`, ErrStackCountOutOfRange)
+	fail(`1 2 _1{roll}`, ErrStackCountOutOfRange)
+	fail(`1 2 3{dropn}`, ErrStackTooShort)
+	fail(`1 2 .5{dropn}`, ErrIntegersOnly)
+	fail(`1{over}`, ErrStackTooShort)
+	if values, _ := interpreter.EvalString(``); len(values) != 1 {
+		t.Fatalf(`expected a failed {over} to leave the stack alone; left %v`, values)
+	}
+}
@@ -1,43 +1,192 @@
-package main
+package godc
+
+// StackBackend holds the values of a Stack, so a stack can keep them
+// somewhere other than in memory, such as on disk for huge datasets,
+// or count what's done to them for testing. Depths count from the top
+// of the stack, at depth 0.
+type StackBackend interface {
+	// Len returns the number of values held.
+	Len() int
+	// Push adds a value to the top.
+	Push(v *Value)
+	// Pop removes the value on top and returns it, or returns nil if
+	// there aren't any.
+	Pop() *Value
+	// At returns the value at a depth from 0 to Len()-1.
+	At(depth int) *Value
+	// Set replaces the value at a depth from 0 to Len()-1.
+	Set(depth int, v *Value)
+	// Truncate drops values from the top, until there are length left.
+	Truncate(length int)
+}
 
 // Stack is a pretty simple stack of Value pointers.
 // It is used both for the main program Stack and for
-// registers.
+// registers. Its values are kept in a slice, unless it's
+// made with NewStack to keep them in a StackBackend.
 type Stack struct {
-	values []*Value
+	values  []*Value
+	backend StackBackend
+	low     int // the fewest values held since mark
+}
+
+// NewStack returns a stack that keeps its values in backend. A Stack
+// that's just declared keeps them in memory.
+func NewStack(backend StackBackend) *Stack {
+	return &Stack{backend: backend}
+}
+
+// newStack returns an empty stack for a register or a named stack,
+// with a backend from NewStackBackend if it's set.
+func (i *Interpreter) newStack() *Stack {
+	if i.NewStackBackend != nil {
+		return NewStack(i.NewStackBackend())
+	}
+	return new(Stack)
 }
 
 // Len returns the length of the stack.
 func (s *Stack) Len() int {
+	if s.backend != nil {
+		return s.backend.Len()
+	}
 	return len(s.values)
 }
 
 // Push pushes a new *Value onto the stack.
 func (s *Stack) Push(n *Value) {
+	if s.backend != nil {
+		s.backend.Push(n)
+		return
+	}
 	s.values = append(s.values, n)
 }
 
 // Peek returns the last *Value on the stack without altering the stack.
 func (s *Stack) Peek() *Value {
-	l := len(s.values)
-	if l == 0 {
+	return s.At(0)
+}
+
+// At returns the *Value at a depth in the stack, counting the top as
+// 0, without altering the stack, or nil if the stack isn't that deep.
+func (s *Stack) At(depth int) *Value {
+	l := s.Len()
+	if depth < 0 || depth >= l {
 		return nil
 	}
-	return s.values[l-1]
+	if s.backend != nil {
+		return s.backend.At(depth)
+	}
+	return s.values[l-1-depth]
+}
+
+// mark starts keeping track of how low the stack gets, so unmark can
+// tell how many values have been pushed since. It returns what was
+// being kept track of for an earlier mark, for unmark to go back to.
+func (s *Stack) mark() int {
+	low := s.low
+	s.low = s.Len()
+	return low
+}
+
+// unmark returns the number of values on the stack pushed since the
+// last mark, or replaced since, and goes back to keeping track for the
+// mark before that one, whose low it returned.
+func (s *Stack) unmark(low int) int {
+	pushed := s.Len() - s.low
+	if low < s.low {
+		s.low = low
+	}
+	return pushed
+}
+
+// lowered notes that the stack has been down to length values.
+func (s *Stack) lowered(length int) {
+	if length < s.low {
+		s.low = length
+	}
+}
+
+// set replaces the *Value at a depth that's in the stack.
+func (s *Stack) set(depth int, v *Value) {
+	s.lowered(s.Len() - 1 - depth)
+	if s.backend != nil {
+		s.backend.Set(depth, v)
+		return
+	}
+	s.values[len(s.values)-1-depth] = v
 }
 
 // Pop returns the last *Value of the stack, removing it.
 func (s *Stack) Pop() *Value {
+	if s.backend != nil {
+		val := s.backend.Pop()
+		s.lowered(s.backend.Len())
+		return val
+	}
 	l := len(s.values)
 	if l == 0 {
 		return nil
 	}
-	val := s.Peek()
+	val := s.values[l-1]
 	s.values = s.values[:l-1]
+	s.lowered(l - 1)
 	return val
 }
 
+// truncate drops values from the top of the stack until there are
+// length left.
+func (s *Stack) truncate(length int) {
+	if length >= s.Len() {
+		return
+	}
+	s.lowered(length)
+	if s.backend != nil {
+		s.backend.Truncate(length)
+		return
+	}
+	s.values = s.values[:length]
+}
+
 // Clear removes all *Value from the stack.
 func (s *Stack) Clear() {
+	s.low = 0
+	if s.backend != nil {
+		s.backend.Truncate(0)
+		return
+	}
 	s.values = nil
 }
+
+// Values returns copies of the *Value on the stack, from the top of
+// the stack to the bottom.
+func (s *Stack) Values() []*Value {
+	values := make([]*Value, 0, s.Len())
+	s.Each(func(_ int, v *Value) bool {
+		values = append(values, v.Dup())
+		return true
+	})
+	return values
+}
+
+// Each calls f with each *Value on the stack and its depth, from the
+// top of the stack, at depth 0, to the bottom, until f returns false.
+// The values aren't copied, so f mustn't change them, or the stack.
+func (s *Stack) Each(f func(depth int, v *Value) bool) {
+	for depth, l := 0, s.Len(); depth < l; depth++ {
+		if !f(depth, s.At(depth)) {
+			return
+		}
+	}
+}
+
+// bottomUp returns copies of the *Value on the stack, from the bottom
+// of the stack to the top.
+func (s *Stack) bottomUp() []*Value {
+	l := s.Len()
+	values := make([]*Value, l)
+	for depth := 0; depth < l; depth++ {
+		values[l-1-depth] = s.At(depth).Dup()
+	}
+	return values
+}
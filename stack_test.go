@@ -1,6 +1,7 @@
-package main
+package godc
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -47,3 +48,108 @@ func TestStack(t *testing.T) {
 	testNum(nil, s.Pop())
 	testLen(0)
 }
+
+func TestStackValues(t *testing.T) {
+	s := new(Stack)
+	s.Push(NewValueFromString(`a`))
+	s.Push(NewValueFromString(`b`))
+	values := s.Values()
+	if len(values) != 2 || values[0].String() != `b` || values[1].String() != `a` {
+		t.Fatalf(`expected [b a]; was %v`, values)
+	}
+	if values[0] == s.Peek() {
+		t.Fatalf(`expected Values to return copies`)
+	}
+	if s.Len() != 2 {
+		t.Fatalf(`expected Values to leave the stack alone`)
+	}
+
+	s.Push(NewValueFromString(`c`))
+	var seen []string
+	s.Each(func(depth int, v *Value) bool {
+		if depth != len(seen) {
+			t.Fatalf(`expected depth %d; was %d`, len(seen), depth)
+		}
+		seen = append(seen, v.String())
+		return depth < 1
+	})
+	if strings.Join(seen, ` `) != `c b` {
+		t.Fatalf(`expected Each to stop after c b; saw %v`, seen)
+	}
+	new(Stack).Each(func(int, *Value) bool {
+		t.Fatalf(`expected Each not to call f for an empty stack`)
+		return false
+	})
+}
+
+// countingBackend keeps a stack's values in a map, and counts the
+// calls made to it.
+type countingBackend struct {
+	values map[int]*Value
+	calls  int
+}
+
+func newCountingBackend() StackBackend {
+	return &countingBackend{values: make(map[int]*Value)}
+}
+
+func (b *countingBackend) Len() int {
+	b.calls++
+	return len(b.values)
+}
+
+func (b *countingBackend) Push(v *Value) {
+	b.calls++
+	b.values[len(b.values)] = v
+}
+
+func (b *countingBackend) Pop() *Value {
+	b.calls++
+	v, ok := b.values[len(b.values)-1]
+	if !ok {
+		return nil
+	}
+	delete(b.values, len(b.values)-1)
+	return v
+}
+
+func (b *countingBackend) At(depth int) *Value {
+	b.calls++
+	return b.values[len(b.values)-1-depth]
+}
+
+func (b *countingBackend) Set(depth int, v *Value) {
+	b.calls++
+	b.values[len(b.values)-1-depth] = v
+}
+
+func (b *countingBackend) Truncate(length int) {
+	b.calls++
+	for n := len(b.values) - 1; n >= length; n-- {
+		delete(b.values, n)
+	}
+}
+
+func TestStackBackend(t *testing.T) {
+	interpreter := NewInterpreter()
+	output := new(strings.Builder)
+	interpreter.SetOutput(output)
+	backend := newCountingBackend().(*countingBackend)
+	interpreter.Stack = NewStack(backend)
+	interpreter.NewStackBackend = newCountingBackend
+	if _, err := interpreter.EvalString(`1 2 3 4 3{roll} 2{dropn} f 5Sa 6Sa La f [other]{stack} 7 f`); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "3\n1\n6\n3\n1\n7\n" {
+		t.Fatalf(`expected the stack to work the same with a backend; printed %q`, output.String())
+	}
+	if backend.calls == 0 || len(backend.values) != 3 {
+		t.Fatalf(`expected the values to be kept in the backend; found %d calls and %d values`, backend.calls, len(backend.values))
+	}
+	if _, ok := interpreter.Register('a').backend.(*countingBackend); !ok {
+		t.Fatalf(`expected registers to be made with NewStackBackend`)
+	}
+	if _, ok := interpreter.Stack.backend.(*countingBackend); !ok {
+		t.Fatalf(`expected named stacks to be made with NewStackBackend`)
+	}
+}
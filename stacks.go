@@ -0,0 +1,143 @@
+package godc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultStackName is the name of the main stack an interpreter starts
+// with.
+const DefaultStackName = `default`
+
+// ErrStackInUse is returned by {dropstack} when it's asked to drop the
+// stack that's in use.
+var ErrStackInUse = fmt.Errorf(`can't drop the stack in use`)
+
+// StackName returns the name of the main stack in use.
+func (i *Interpreter) StackName() string {
+	if i.stackName == `` {
+		return DefaultStackName
+	}
+	return i.stackName
+}
+
+// StackNames returns the names of all the main stacks, including the
+// one in use, in order.
+func (i *Interpreter) StackNames() []string {
+	names := []string{i.StackName()}
+	for name := range i.stacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchStack makes the main stack named name the one in use, starting
+// a new, empty one if there isn't one by that name. The stack that was
+// in use is kept under its own name, to be switched back to.
+func (i *Interpreter) SwitchStack(name string) {
+	current := i.StackName()
+	if name == current {
+		return
+	}
+	if i.stacks == nil {
+		i.stacks = make(map[string]*Stack)
+	}
+	i.stacks[current] = i.Stack
+	stack, ok := i.stacks[name]
+	if !ok {
+		stack = i.newStack()
+	}
+	delete(i.stacks, name)
+	i.Stack, i.stackName = stack, name
+}
+
+// namedStack returns the main stack named name, starting it if need be.
+func (i *Interpreter) namedStack(name string) *Stack {
+	if name == i.StackName() {
+		return i.Stack
+	}
+	if i.stacks == nil {
+		i.stacks = make(map[string]*Stack)
+	}
+	stack, ok := i.stacks[name]
+	if !ok {
+		stack = i.newStack()
+		i.stacks[name] = stack
+	}
+	return stack
+}
+
+// popStackName pops the name of a stack, which must be a string.
+func popStackName(i *Interpreter) (string, error) {
+	if i.Stack.Len() < 1 {
+		return ``, ErrStackTooShort
+	}
+	if i.Stack.Peek().Type != VTString {
+		return ``, ErrValueNotString
+	}
+	return string(i.Stack.Pop().strval), nil
+}
+
+// SwitchStackOperation implements the {stack} extension. It pops the
+// name of a stack, and switches to it.
+var SwitchStackOperation = OperationAdapter(func(i *Interpreter) error {
+	name, err := popStackName(i)
+	if err != nil {
+		return err
+	}
+	i.SwitchStack(name)
+	return nil
+})
+
+// StackNameOperation implements the {stackname} extension. It pushes
+// the name of the stack in use.
+var StackNameOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Stack.Push(NewValueFromString(i.StackName()))
+	return nil
+})
+
+// MoveToStackOperation implements the {moveto} extension. It pops the
+// name of a stack, and then moves the value under it to the top of
+// that stack, unless that stack already holds as many values as it
+// may.
+var MoveToStackOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 2 {
+		return ErrStackTooShort
+	}
+	name, err := popStackName(i)
+	if err != nil {
+		return err
+	}
+	stack := i.namedStack(name)
+	stack.Push(i.Stack.Pop())
+	if err := i.checkDepth(stack); err != nil {
+		i.Stack.Push(stack.Pop())
+		i.Stack.Push(NewValueFromString(name))
+		return err
+	}
+	return nil
+})
+
+// DropStackOperation implements the {dropstack} extension. It pops the
+// name of a stack that isn't in use, and throws it away.
+var DropStackOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() > 0 && i.Stack.Peek().Type == VTString && string(i.Stack.Peek().strval) == i.StackName() {
+		return ErrStackInUse
+	}
+	name, err := popStackName(i)
+	if err != nil {
+		return err
+	}
+	delete(i.stacks, name)
+	return nil
+})
+
+// StackExtensions keep several main stacks, by name, and switch
+// between them.
+var StackExtensions = ExtensionSet{
+	`stack`:     SwitchStackOperation,
+	`stackname`: StackNameOperation,
+	`moveto`:    MoveToStackOperation,
+	`dropstack`: DropStackOperation,
+}
@@ -0,0 +1,49 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNamedStacks(t *testing.T) {
+	interpreter := NewInterpreter()
+	expect := func(src string, expected ...string) {
+		values, err := interpreter.EvalString(src)
+		if err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		actual := make([]string, len(values))
+		for n, v := range values {
+			actual[n] = interpreter.Format(v)
+		}
+		if strings.Join(actual, ` `) != strings.Join(expected, ` `) {
+			t.Fatalf(`expected %q to leave %v; left %v`, src, expected, actual)
+		}
+	}
+
+	expect(`{stackname}`, `default`)
+	expect(`c 1 2 [work]{stack}`)
+	expect(`{stackname} 3`, `work`, `3`)
+	expect(`[default]{moveto}`, `work`)
+	expect(`[default]{stack}`, `1`, `2`, `3`)
+	if names := strings.Join(interpreter.StackNames(), ` `); names != `default work` {
+		t.Fatalf(`expected two stacks; found %s`, names)
+	}
+	expect(`[work]{stack}`, `work`)
+
+	interpreter.SwitchStack(DefaultStackName)
+	if interpreter.Stack.Len() != 3 || interpreter.StackName() != DefaultStackName {
+		t.Fatalf(`expected SwitchStack to go back to the default stack`)
+	}
+	if _, err := interpreter.EvalString(`[default]{dropstack}`); !errors.Is(err, ErrStackInUse) {
+		t.Fatalf(`expected the stack in use not to be dropped; found %v`, err)
+	}
+	expect(`c [work]{dropstack}`)
+	if names := strings.Join(interpreter.StackNames(), ` `); names != `default` {
+		t.Fatalf(`expected the work stack to be dropped; found %s`, names)
+	}
+	if _, err := interpreter.EvalString(`5{stack}`); !errors.Is(err, ErrValueNotString) {
+		t.Fatalf(`expected a stack to be named by a string; found %v`, err)
+	}
+}
@@ -0,0 +1,368 @@
+package godc
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ErrBadState is returned by Load when what it reads isn't an
+// interpreter's state saved by Save.
+var ErrBadState = fmt.Errorf(`not a saved interpreter state`)
+
+// stateVersion is changed whenever savedState is, so old saves aren't
+// misread.
+const stateVersion = 1
+
+// savedState is what Save writes, with gob. Stacks are bottom first.
+type savedState struct {
+	Version           int
+	Stack             []savedValue
+	Registers         map[rune][]savedValue
+	Arrays            map[rune]map[int]savedValue
+	ArrayLevels       map[rune][]map[int]savedValue // the arrays S has put aside, lowest level first
+	Scale             int64
+	Precision         int64
+	SeparatePrecision bool
+	InputRadix        uint8
+	OutputRadix       int64
+	RoundingMode      RoundingMode
+	Notation          Notation
+	SignificantDigits int64
+	FixedScale        bool
+	GroupSeparator    string
+	DecimalSeparator  string
+}
+
+type savedValue struct {
+	IsString bool
+	Rat      *big.Rat
+	Scale    int64
+	Runes    []rune
+}
+
+func saveValue(v *Value) savedValue {
+	if v.Type == VTString {
+		return savedValue{IsString: true, Runes: v.strval}
+	}
+	// Arithmetic changes numbers in place, so a snapshot needs a copy.
+	return savedValue{Rat: new(big.Rat).Set(v.numval), Scale: v.scale}
+}
+
+func (s savedValue) value() (*Value, error) {
+	if s.IsString {
+		str := make([]rune, len(s.Runes))
+		copy(str, s.Runes)
+		return &Value{Type: VTString, strval: str}, nil
+	}
+	if s.Rat == nil || s.Scale < 0 {
+		return nil, ErrBadState
+	}
+	return &Value{numval: new(big.Rat).Set(s.Rat), scale: s.Scale}, nil
+}
+
+func saveStack(s *Stack) []savedValue {
+	values := make([]savedValue, s.Len())
+	s.Each(func(depth int, v *Value) bool {
+		values[len(values)-1-depth] = saveValue(v)
+		return true
+	})
+	return values
+}
+
+func (i *Interpreter) loadStack(values []savedValue) (*Stack, error) {
+	s := i.newStack()
+	for _, saved := range values {
+		v, err := saved.value()
+		if err != nil {
+			return nil, err
+		}
+		s.Push(v)
+	}
+	return s, nil
+}
+
+// Save writes the interpreter's stack, registers and arrays, and the
+// settings that change how numbers are read and printed, so Load can
+// pick up where it left off. The format is only meant to be read by
+// Load; SaveJSON writes one meant for other programs.
+func (i *Interpreter) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(i.state())
+}
+
+// Load replaces the interpreter's stack, registers, arrays and
+// settings with those written by Save. If it fails, the interpreter
+// is left as it was.
+func (i *Interpreter) Load(r io.Reader) error {
+	var state savedState
+	if err := gob.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf(`%w: %v`, ErrBadState, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf(`%w: version %d`, ErrBadState, state.Version)
+	}
+	return i.restore(state)
+}
+
+// SaveRegisters writes the stacks and arrays of the named registers,
+// and nothing else, for LoadRegisters to read back in another session.
+// Registers that are empty are left out.
+func (i *Interpreter) SaveRegisters(w io.Writer, names []rune) error {
+	all := i.state()
+	state := savedState{
+		Version:   stateVersion,
+		Registers: make(map[rune][]savedValue),
+		Arrays:    make(map[rune]map[int]savedValue),
+	}
+	for _, r := range names {
+		if values, ok := all.Registers[r]; ok {
+			state.Registers[r] = values
+		}
+		if entries, ok := all.Arrays[r]; ok {
+			state.Arrays[r] = entries
+		}
+	}
+	return gob.NewEncoder(w).Encode(state)
+}
+
+// LoadRegisters replaces the registers written by SaveRegisters, and
+// leaves the rest of the interpreter as it is. If it fails, nothing
+// is changed.
+func (i *Interpreter) LoadRegisters(r io.Reader) error {
+	var state savedState
+	if err := gob.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf(`%w: %v`, ErrBadState, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf(`%w: version %d`, ErrBadState, state.Version)
+	}
+	registers := make(map[rune]*Stack)
+	for r, values := range state.Registers {
+		reg, err := i.loadStack(values)
+		if err != nil {
+			return err
+		}
+		registers[r] = reg
+	}
+	arrays := make(map[rune]*Array)
+	for r, entries := range state.Arrays {
+		arr, err := loadArray(entries)
+		if err != nil {
+			return err
+		}
+		arrays[r] = arr
+	}
+	for r := range registers {
+		i.ClearRegister(r)
+	}
+	for r := range arrays {
+		i.ClearRegister(r)
+	}
+	for r, reg := range registers {
+		i.Registers[r] = reg
+	}
+	for r, arr := range arrays {
+		if arr != nil {
+			i.Arrays[r] = arr
+		}
+	}
+	return nil
+}
+
+// state copies what Save saves.
+func (i *Interpreter) state() savedState {
+	state := savedState{
+		Version:           stateVersion,
+		Stack:             saveStack(i.Stack),
+		Registers:         make(map[rune][]savedValue),
+		Arrays:            make(map[rune]map[int]savedValue),
+		Scale:             i.Scale,
+		Precision:         i.Precision,
+		SeparatePrecision: i.SeparatePrecision,
+		InputRadix:        i.InputRadix,
+		OutputRadix:       i.OutputRadix,
+		RoundingMode:      i.RoundingMode,
+		Notation:          i.Notation,
+		SignificantDigits: i.SignificantDigits,
+		FixedScale:        i.FixedScale,
+		GroupSeparator:    i.GroupSeparator,
+		DecimalSeparator:  i.DecimalSeparator,
+	}
+	for r, reg := range i.Registers {
+		if reg.Len() > 0 {
+			state.Registers[r] = saveStack(reg)
+		}
+	}
+	for r, arr := range i.Arrays {
+		if entries := saveArray(arr); len(entries) > 0 {
+			state.Arrays[r] = entries
+		}
+	}
+	for r, levels := range i.arrayLevels {
+		if state.ArrayLevels == nil {
+			state.ArrayLevels = make(map[rune][]map[int]savedValue)
+		}
+		for _, arr := range levels {
+			state.ArrayLevels[r] = append(state.ArrayLevels[r], saveArray(arr))
+		}
+	}
+	return state
+}
+
+// saveArray returns the entries of an array, which may be nil, by index.
+func saveArray(arr *Array) map[int]savedValue {
+	entries := make(map[int]savedValue)
+	if arr == nil {
+		return entries
+	}
+	for index, v := range arr.values {
+		entries[index] = saveValue(v)
+	}
+	return entries
+}
+
+// loadArray is the reverse of saveArray. It returns nil if there are
+// no entries.
+func loadArray(entries map[int]savedValue) (*Array, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	arr := new(Array)
+	for index, saved := range entries {
+		if index < 0 || index > MaxArrayIndex {
+			return nil, ErrArrayIndexOutOfRange
+		}
+		v, err := saved.value()
+		if err != nil {
+			return nil, err
+		}
+		arr.Set(index, v)
+	}
+	return arr, nil
+}
+
+// restore replaces the interpreter's state with a saved one, if it
+// makes sense.
+func (i *Interpreter) restore(state savedState) error {
+	if state.InputRadix < 2 || state.InputRadix > MaxInputRadix {
+		return ErrInputRadixOutOfRange
+	}
+	if state.OutputRadix < 2 || state.OutputRadix > MaxOutputRadix {
+		return ErrOutputRadixOutOfRange
+	}
+
+	stack, err := i.loadStack(state.Stack)
+	if err != nil {
+		return err
+	}
+	registers := make(map[rune]*Stack)
+	for r, values := range state.Registers {
+		if registers[r], err = i.loadStack(values); err != nil {
+			return err
+		}
+	}
+	arrays := make(map[rune]*Array)
+	for r, entries := range state.Arrays {
+		arr, err := loadArray(entries)
+		if err != nil {
+			return err
+		}
+		if arr != nil {
+			arrays[r] = arr
+		}
+	}
+	var levels map[rune][]*Array
+	for r, saved := range state.ArrayLevels {
+		if levels == nil {
+			levels = make(map[rune][]*Array)
+		}
+		for _, entries := range saved {
+			arr, err := loadArray(entries)
+			if err != nil {
+				return err
+			}
+			levels[r] = append(levels[r], arr)
+		}
+	}
+
+	i.Stack.Clear()
+	for depth := stack.Len() - 1; depth >= 0; depth-- {
+		i.Stack.Push(stack.At(depth))
+	}
+	i.Registers, i.Arrays, i.arrayLevels = registers, arrays, levels
+	i.Scale, i.Precision, i.SeparatePrecision = state.Scale, state.Precision, state.SeparatePrecision
+	i.InputRadix, i.OutputRadix = state.InputRadix, state.OutputRadix
+	i.RoundingMode, i.Notation, i.SignificantDigits = state.RoundingMode, state.Notation, state.SignificantDigits
+	i.FixedScale, i.GroupSeparator, i.DecimalSeparator = state.FixedScale, state.GroupSeparator, state.DecimalSeparator
+	return nil
+}
+
+// Snapshot is a copy of what Save would save, kept in memory, so the
+// interpreter can be put back as it was with RestoreSnapshot.
+type Snapshot struct {
+	state     savedState
+	stackName string
+	stacks    map[string][]savedValue // the main stacks not in use, by name
+}
+
+// Snapshot copies the interpreter's stack, registers, arrays and
+// settings. Unlike Save, it copies every main stack, not just the one
+// in use.
+func (i *Interpreter) Snapshot() *Snapshot {
+	s := &Snapshot{state: i.state(), stackName: i.StackName()}
+	for name, stack := range i.stacks {
+		if s.stacks == nil {
+			s.stacks = make(map[string][]savedValue)
+		}
+		s.stacks[name] = saveStack(stack)
+	}
+	return s
+}
+
+// RestoreSnapshot puts the interpreter back as it was when the
+// snapshot was taken, with the main stacks it had then, and the one
+// that was in use then in use again. The snapshot can be restored
+// again.
+func (i *Interpreter) RestoreSnapshot(s *Snapshot) error {
+	var stacks map[string]*Stack
+	for name, values := range s.stacks {
+		stack, err := i.loadStack(values)
+		if err != nil {
+			return err
+		}
+		if stacks == nil {
+			stacks = make(map[string]*Stack)
+		}
+		stacks[name] = stack
+	}
+	i.SwitchStack(s.stackName)
+	if err := i.restore(s.state); err != nil {
+		return err
+	}
+	i.stacks = stacks
+	return nil
+}
+
+// Reset puts the interpreter back as NewInterpreter made it: it empties
+// the stack and every register, drops the other main stacks, and sets
+// everything Save would save back to its default. The output, limits,
+// hooks, extensions and the rest of how the interpreter was set up are
+// left as they are, as is the count of Steps.
+func (i *Interpreter) Reset() {
+	i.Stack.Clear()
+	i.stacks, i.stackName = nil, ``
+	i.Registers = make(map[rune]*Stack)
+	i.Arrays, i.arrayLevels = make(map[rune]*Array), nil
+	i.Scale, i.Precision, i.SeparatePrecision = 0, 0, false
+	i.InputRadix, i.OutputRadix = 10, 10
+	i.RoundingMode, i.Notation, i.SignificantDigits = RoundTruncate, NotationFixed, DefaultSignificantDigits
+	i.FixedScale, i.GroupSeparator, i.DecimalSeparator = false, ``, ``
+	i.QuitLevel, i.LastError = 0, nil
+}
+
+// ResetOperation implements the {reset} extension.
+var ResetOperation = OperationAdapter(func(i *Interpreter) error {
+	i.Reset()
+	return nil
+})
@@ -0,0 +1,187 @@
+package godc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	saved := NewInterpreter()
+	saved.SetOutput(new(strings.Builder))
+	if _, err := saved.EvalString(`1 2.50 [hi]sa 4sb 5Sb 7 3:c [x] 1:c 16o 3k 8{sci} 12i`); err != nil {
+		t.Fatalf(`could not set up the interpreter: %v`, err)
+	}
+	buff := new(bytes.Buffer)
+	if err := saved.Save(buff); err != nil {
+		t.Fatalf(`could not save: %v`, err)
+	}
+
+	loaded := NewInterpreter()
+	loaded.Register('z').Push(NewValueFromInt64(1))
+	if err := loaded.Load(buff); err != nil {
+		t.Fatalf(`could not load: %v`, err)
+	}
+	if loaded.Register('z').Len() != 0 {
+		t.Fatalf(`expected Load to replace the registers`)
+	}
+	if loaded.Scale != 3 || loaded.InputRadix != 12 || loaded.OutputRadix != 16 || loaded.Notation != NotationScientific || loaded.SignificantDigits != 8 {
+		t.Fatalf(`expected the settings to be restored; found scale %d, radixes %d and %d, notation %v`,
+			loaded.Scale, loaded.InputRadix, loaded.OutputRadix, loaded.Notation)
+	}
+	check := func(name string, actual []*Value, expected ...string) {
+		if len(actual) != len(expected) {
+			t.Fatalf(`expected %s to be %v; found %v`, name, expected, actual)
+		}
+		for n, v := range actual {
+			if v.String() != expected[n] {
+				t.Fatalf(`expected %s to be %v; found %v`, name, expected, actual)
+			}
+		}
+	}
+	check(`the stack`, loaded.Stack.Values(), `2.50`, `1`)
+	check(`register a`, loaded.Register('a').Values(), `hi`)
+	check(`register b`, loaded.Register('b').Values(), `5`, `4`)
+	check(`array c`, []*Value{loaded.Array('c').Get(1), loaded.Array('c').Get(3)}, `x`, `7`)
+	if loaded.Array('c').Get(2) != nil {
+		t.Fatalf(`expected nothing to be stored at c[2]`)
+	}
+
+	if err := loaded.Load(strings.NewReader(`1 2+p`)); !errors.Is(err, ErrBadState) {
+		t.Fatalf(`expected ErrBadState; found %v`, err)
+	}
+	if loaded.Stack.Len() != 2 || loaded.InputRadix != 12 {
+		t.Fatalf(`expected a failed Load to leave the interpreter alone`)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`1 2 3sa 2k`); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := interpreter.Snapshot()
+	if _, err := interpreter.EvalString(`+ 4sa 5k [other]{stack} 6`); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 2; n++ {
+		if err := interpreter.RestoreSnapshot(snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if interpreter.StackName() != DefaultStackName || interpreter.Stack.Len() != 2 || interpreter.Scale != 2 {
+			t.Fatalf(`expected the snapshot to be restored; found stack %s, %v, scale %d`,
+				interpreter.StackName(), interpreter.Stack.Values(), interpreter.Scale)
+		}
+		if a := interpreter.Register('a').Peek(); a.String() != `3` {
+			t.Fatalf(`expected register a to be restored; found %v`, a)
+		}
+		// Changing what's restored mustn't change the snapshot.
+		if _, err := interpreter.EvalString(`d1+`); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSnapshotStacks(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`1 2[a]{moveto} [b]{stack} 3`); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := interpreter.Snapshot()
+	if _, err := interpreter.EvalString(`4[a]{moveto} [c]{stack} 5 [default]{dropstack}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := interpreter.RestoreSnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if names := interpreter.StackNames(); interpreter.StackName() != `b` || !reflect.DeepEqual(names, []string{`a`, `b`, DefaultStackName}) {
+		t.Fatalf(`expected the stacks to be restored, with b in use; found %v, %s`, names, interpreter.StackName())
+	}
+	for name, expected := range map[string]string{`a`: `[2]`, `b`: `[3]`, DefaultStackName: `[1]`} {
+		if values := fmt.Sprint(interpreter.namedStack(name).Values()); values != expected {
+			t.Fatalf(`expected stack %s to be %s; found %s`, name, expected, values)
+		}
+	}
+}
+
+func TestSnapshotArrayLevels(t *testing.T) {
+	interpreter := NewInterpreter()
+	if _, err := interpreter.EvalString(`1 0:a 2Sa 3 0:a`); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := interpreter.Snapshot()
+	if _, err := interpreter.EvalString(`La 5 0:a`); err != nil {
+		t.Fatal(err)
+	}
+	if err := interpreter.RestoreSnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	values, err := interpreter.EvalString(`c 0;a La 0;a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 || values[0].String() != `3` || values[2].String() != `1` {
+		t.Fatalf(`expected the array under register a's top level to be restored; found %v`, values)
+	}
+}
+
+func TestReset(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.SetOutput(new(strings.Builder))
+	interpreter.StepLimit = 1000
+	src := `1 2.50 [hi]sa 7 3:c 16o 3k 8{sci} {halfeven} [,]{decimal} 2{money} [other]{stack} 9 12i {reset} 10 20`
+	if _, err := interpreter.EvalString(src); err != nil {
+		t.Fatalf(`could not run %q: %v`, src, err)
+	}
+	if values := interpreter.Stack.Values(); len(values) != 2 || values[0].String() != `20` {
+		t.Fatalf(`expected the program to carry on in decimal after {reset}; found %v`, values)
+	}
+	interpreter.Stack.Clear()
+	if !reflect.DeepEqual(interpreter.state(), NewInterpreter().state()) {
+		t.Fatalf(`expected {reset} to restore a new interpreter's state; found %+v`, interpreter.state())
+	}
+	if names := interpreter.StackNames(); len(names) != 1 || interpreter.StackName() != DefaultStackName {
+		t.Fatalf(`expected {reset} to drop the other stacks; found %v`, names)
+	}
+	if interpreter.StepLimit != 1000 {
+		t.Fatal(`expected {reset} to leave the step limit alone`)
+	}
+}
+
+func TestSaveLoadRegisters(t *testing.T) {
+	saved := NewInterpreter()
+	if _, err := saved.EvalString(`[2*]sd 3.14sp 7 0:p 1sa 2 0:b 9`); err != nil {
+		t.Fatal(err)
+	}
+	buff := new(bytes.Buffer)
+	if err := saved.SaveRegisters(buff, []rune(`dpbz`)); err != nil {
+		t.Fatalf(`could not save: %v`, err)
+	}
+
+	loaded := NewInterpreter()
+	if _, err := loaded.EvalString(`5 sp 6 1:p 4sc 16o 8`); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadRegisters(buff); err != nil {
+		t.Fatalf(`could not load: %v`, err)
+	}
+	if actual := string(loaded.UsedRegisters()); actual != `bcdp` {
+		t.Fatalf(`expected registers b, c, d and p; found %q`, actual)
+	}
+	if p := loaded.Register('p').Values(); len(p) != 1 || p[0].String() != `3.14` {
+		t.Fatalf(`expected register p to be replaced; found %v`, p)
+	}
+	if arr := loaded.Array('p'); arr.Len() != 1 || arr.Get(0).String() != `7` {
+		t.Fatalf(`expected the array of p to be replaced; found %v`, arr.values)
+	}
+	if loaded.Stack.Len() != 1 || loaded.OutputRadix != 16 || loaded.Register('c').Len() != 1 {
+		t.Fatal(`expected the rest of the interpreter to be left alone`)
+	}
+
+	if err := loaded.LoadRegisters(strings.NewReader(`1 2+p`)); !errors.Is(err, ErrBadState) {
+		t.Fatalf(`expected ErrBadState; found %v`, err)
+	}
+}
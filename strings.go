@@ -0,0 +1,160 @@
+package godc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrStringIndexOutOfRange is returned when a substring is asked for
+// with a negative start or length.
+var ErrStringIndexOutOfRange = fmt.Errorf(`string positions can't be negative`)
+
+// ErrInvalidNumber is returned when a string that should hold a
+// number doesn't.
+var ErrInvalidNumber = fmt.Errorf(`string is not a number`)
+
+// StringLengthOperation implements the {strlen} extension. It pops a
+// string and pushes the number of characters in it. Unlike 'Z', it
+// won't count the digits of a number.
+var StringLengthOperation = makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+	if val.Type != VTString {
+		return nil, ErrValueNotString
+	}
+	return []*Value{{numval: big.NewRat(int64(len(val.strval)), 1)}}, nil
+})
+
+// SubstringOperation implements the {substr} extension. It pops a
+// length, a start position and a string, and pushes the part of the
+// string that many characters long from that position, counting from
+// 0. The substring stops early at the end of the string.
+var SubstringOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 3 {
+		return ErrStackTooShort
+	}
+	length, start, str := i.Stack.Pop(), i.Stack.Pop(), i.Stack.Pop()
+	sub, err := substring(str, start, length)
+	if err != nil {
+		i.Stack.Push(str)
+		i.Stack.Push(start)
+		i.Stack.Push(length)
+		return err
+	}
+	i.Stack.Push(sub)
+	return nil
+})
+
+func substring(str, start, length *Value) (*Value, error) {
+	if str.Type != VTString {
+		return nil, ErrValueNotString
+	}
+	if err := ensureInteger(start, length); err != nil {
+		return nil, err
+	}
+	if start.numval.Sign() < 0 || length.numval.Sign() < 0 {
+		return nil, ErrStringIndexOutOfRange
+	}
+	size := big.NewInt(int64(len(str.strval)))
+	from := new(big.Int).Set(start.numval.Num())
+	if from.Cmp(size) > 0 {
+		from.Set(size)
+	}
+	to := new(big.Int).Add(from, length.numval.Num())
+	if to.Cmp(size) > 0 {
+		to.Set(size)
+	}
+	runes := str.strval[from.Int64():to.Int64()]
+	return &Value{Type: VTString, strval: append([]rune(nil), runes...)}, nil
+}
+
+// ToStringOperation implements the {str} extension. It pops a number
+// and pushes a string of it, written out just as p would print it,
+// but without wrapping long numbers.
+var ToStringOperation = makeUnaryOperation(func(i *Interpreter, val *Value) ([]*Value, error) {
+	if err := ensureNumeric(val); err != nil {
+		return nil, err
+	}
+	return []*Value{{Type: VTString, strval: []rune(i.Format(val))}}, nil
+})
+
+// ToNumberOperation implements the {num} extension. It pops a string
+// and pushes the number written in it, read in the input radix. As
+// well as dc's _, a - sign is accepted, so {num} can read what {str}
+// writes.
+var ToNumberOperation = OperationAdapter(func(i *Interpreter) error {
+	if i.Stack.Len() < 1 {
+		return ErrStackTooShort
+	}
+	str := i.Stack.Pop()
+	if str.Type != VTString {
+		i.Stack.Push(str)
+		return ErrValueNotString
+	}
+	if err := parseNumber(i, string(str.strval)); err != nil {
+		i.Stack.Push(str)
+		return err
+	}
+	return nil
+})
+
+// parseNumber reads s with a NumberBuilder of its own, and pushes the
+// number if s holds exactly one.
+func parseNumber(i *Interpreter, s string) error {
+	s = strings.ReplaceAll(strings.TrimSpace(s), `-`, `_`)
+	if s == `` {
+		return fmt.Errorf(`%w: %q`, ErrInvalidNumber, s)
+	}
+	builder := NewNumberBuilder()
+	for _, r := range s {
+		if d := i.numberRune(r); r != 'e' && d != skipRune && !isDigit(d, i.InputRadix) {
+			return fmt.Errorf(`%w: %q`, ErrInvalidNumber, s)
+		}
+		if _, err := builder.Operate(i, r); err != nil {
+			if err == ErrContinueProcessingRune {
+				// The number ended early, so s holds more than one.
+				i.Stack.Pop()
+				return fmt.Errorf(`%w: %q`, ErrInvalidNumber, s)
+			}
+			return err
+		}
+	}
+	return builder.Flush(i)
+}
+
+// decodeBytes turns each byte into the rune with the same value, the
+// way strings are held when the interpreter works in bytes.
+func decodeBytes(b []byte) []rune {
+	runes := make([]rune, len(b))
+	for n, c := range b {
+		runes[n] = rune(c)
+	}
+	return runes
+}
+
+// encodeString is the reverse of decodeBytes when the interpreter
+// works in bytes, and UTF-8 otherwise. Runes that don't fit in a byte
+// are written in UTF-8 either way.
+func (i *Interpreter) encodeString(runes []rune) []byte {
+	if !i.ByteStrings {
+		return []byte(string(runes))
+	}
+	b := make([]byte, 0, len(runes))
+	for _, r := range runes {
+		if r < 0x100 {
+			b = append(b, byte(r))
+		} else {
+			b = append(b, string(r)...)
+		}
+	}
+	return b
+}
+
+// StringExtensions help macros work with text.
+var StringExtensions = ExtensionSet{
+	`strlen`:  StringLengthOperation,
+	`substr`:  SubstringOperation,
+	`str`:     ToStringOperation,
+	`num`:     ToNumberOperation,
+	`printf`:  PrintfOperation,
+	`sprintf`: SprintfOperation,
+}
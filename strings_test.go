@@ -0,0 +1,155 @@
+package godc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStringExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`string length`, func(t *testing.T) {
+		test(`[hello]{strlen}`)
+		expect(`5`)
+
+		test(`[héllo]{strlen}`)
+		expect(`5`)
+
+		test(`[]{strlen}`)
+		expect(`0`)
+	})
+
+	t.Run(`substrings`, func(t *testing.T) {
+		test(`[hello world]6 5{substr}`)
+		expect(`world`)
+
+		test(`[hello]0 1{substr}`)
+		expect(`h`)
+
+		test(`[héllo]1 3{substr}`)
+		expect(`éll`)
+	})
+
+	t.Run(`substrings stop at the end of the string`, func(t *testing.T) {
+		test(`[hello]3 10{substr}`)
+		expect(`lo`)
+
+		test(`[hello]10 1{substr}{strlen}`)
+		expect(`0`)
+	})
+
+	t.Run(`numbers to strings`, func(t *testing.T) {
+		test(`2k1 3/{str}0k{strlen}`)
+		expect(`4`)
+
+		test(`16o255{str}[0x]r`)
+		expect(`FF`, `0x`)
+		interpreter.OutputRadix = 10
+
+		test(`0k_5{str}`)
+		expect(`-5`)
+	})
+
+	t.Run(`strings to numbers`, func(t *testing.T) {
+		test(`[12.5]{num}2*`)
+		expect(`25`)
+
+		test(`[-3]{num}[_3]{num}+`)
+		expect(`-6`)
+
+		test(`[ 1.5e3 ]{num}`)
+		expect(`1500`)
+
+		test(`16i[FF]{num}`)
+		expect(`255`)
+		interpreter.InputRadix = 10
+	})
+
+	t.Run(`numbers survive a round trip`, func(t *testing.T) {
+		test(`5k_22 7/d{str}{num}-`)
+		expect(`0.00000`)
+		interpreter.Scale, interpreter.Precision = 0, 0
+	})
+
+	t.Run(`errors`, func(t *testing.T) {
+		for str, expected := range map[string]error{
+			`5{strlen}`:            ErrValueNotString,
+			`5 0 1{substr}`:        ErrValueNotString,
+			`[hello]_1 2{substr}`:  ErrStringIndexOutOfRange,
+			`[hello]1.5 2{substr}`: ErrIntegersOnly,
+			`[abc]{num}`:           ErrInvalidNumber,
+			`[1 2]{num}`:           ErrInvalidNumber,
+			`[]{num}`:              ErrInvalidNumber,
+			`5{num}`:               ErrValueNotString,
+			`[5]{str}`:             ErrValueNotNumeric,
+		} {
+			err := testWithInterpreter(interpreter, str)
+			if !errors.Is(err, expected) {
+				t.Fatalf(`expected %q to fail with %v; got %v`, str, expected, err)
+			}
+			buff.Reset()
+		}
+	})
+}
+
+func TestByteStrings(t *testing.T) {
+	interpreter := NewInterpreter()
+	interpreter.ByteStrings = true
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(input []byte, expected []byte) {
+		buff.Reset()
+		for _, r := range decodeBytes(input) {
+			if err := interpreter.Interpret(r); err != nil {
+				t.Fatalf(`error interpreting %q: %v`, input, err)
+			}
+		}
+		if actual := buff.String(); actual != string(expected) {
+			t.Fatalf(`expected %q to print %q; printed %q`, input, expected, actual)
+		}
+	}
+
+	t.Run(`raw bytes round trip`, func(t *testing.T) {
+		test([]byte("[\xff\x00\xc3]P"), []byte("\xff\x00\xc3"))
+	})
+
+	t.Run(`through registers and macros`, func(t *testing.T) {
+		test([]byte("[[\xfe\xff]P]sa lax"), []byte("\xfe\xff"))
+		test([]byte("[\x80\x81]sb lbp"), []byte("\x80\x81\n"))
+	})
+
+	t.Run(`lengths count bytes`, func(t *testing.T) {
+		test([]byte("[h\xc3\xa9llo]{strlen}p"), []byte("6\n"))
+	})
+
+	t.Run(`EvalString and programs read bytes`, func(t *testing.T) {
+		src := "[\xff\xc3\xa9]d{strlen}nP"
+		buff.Reset()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compile(src).Run(interpreter); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "3\xff\xc3\xa9"; buff.String() != expected+expected {
+			t.Fatalf(`expected %q to print %q twice; printed %q`, src, expected, buff.String())
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package godc
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// SyncInterpreter makes one Interpreter safe to share between
+// goroutines, as a server might for clients that take turns with the
+// same stack and registers. Each call has the interpreter to itself
+// until it returns, so what it prints isn't mixed up with what another
+// call prints, and what it prints is flushed before it returns. A Run
+// holds the interpreter until its input runs out.
+type SyncInterpreter struct {
+	mu sync.Mutex
+	i  *Interpreter
+}
+
+// NewSyncInterpreter wraps i. Once it's wrapped, i should only be used
+// through the SyncInterpreter.
+func NewSyncInterpreter(i *Interpreter) *SyncInterpreter {
+	return &SyncInterpreter{i: i}
+}
+
+// Do calls f with the interpreter to itself, for anything the other
+// methods don't do, such as reading the stack or changing a setting.
+// What f printed is flushed afterwards. The interpreter mustn't be kept
+// once f returns.
+func (s *SyncInterpreter) Do(f func(*Interpreter) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := f(s.i)
+	if flushErr := s.i.FlushOutput(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// Interpret is the interpreter's Interpret.
+func (s *SyncInterpreter) Interpret(r rune) error {
+	return s.Do(func(i *Interpreter) error {
+		return i.Interpret(r)
+	})
+}
+
+// InterpretContext is the interpreter's InterpretContext.
+func (s *SyncInterpreter) InterpretContext(ctx context.Context, r rune) error {
+	return s.Do(func(i *Interpreter) error {
+		return i.InterpretContext(ctx, r)
+	})
+}
+
+// InterpretMacro is the interpreter's InterpretMacro.
+func (s *SyncInterpreter) InterpretMacro(macro []rune) error {
+	return s.Do(func(i *Interpreter) error {
+		return i.InterpretMacro(macro)
+	})
+}
+
+// EvalString is the interpreter's EvalString.
+func (s *SyncInterpreter) EvalString(src string) ([]*Value, error) {
+	var values []*Value
+	err := s.Do(func(i *Interpreter) (err error) {
+		values, err = i.EvalString(src)
+		return err
+	})
+	return values, err
+}
+
+// Run is the interpreter's Run.
+func (s *SyncInterpreter) Run(r io.Reader) error {
+	return s.Do(func(i *Interpreter) error {
+		return i.Run(r)
+	})
+}
+
+// RunContext is the interpreter's RunContext.
+func (s *SyncInterpreter) RunContext(ctx context.Context, r io.Reader) error {
+	return s.Do(func(i *Interpreter) error {
+		return i.RunContext(ctx, r)
+	})
+}
+
+// RunProgram runs p with the interpreter.
+func (s *SyncInterpreter) RunProgram(p *Program) error {
+	return s.Do(func(i *Interpreter) error {
+		return p.Run(i)
+	})
+}
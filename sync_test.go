@@ -0,0 +1,56 @@
+package godc
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncInterpreter(t *testing.T) {
+	interpreter := NewInterpreter()
+	out := new(strings.Builder)
+	interpreter.SetOutput(out)
+	shared := NewSyncInterpreter(interpreter)
+	if _, err := shared.EvalString(`0st`); err != nil {
+		t.Fatal(err)
+	}
+	program := Compile(`lt1+st`)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for run := 0; run < 50; run++ {
+				var err error
+				switch n % 4 {
+				case 0:
+					_, err = shared.EvalString(`lt1+st [ab]P`)
+				case 1:
+					err = shared.RunProgram(program)
+				case 2:
+					err = shared.Run(strings.NewReader(`lt1+st`))
+				case 3:
+					err = shared.InterpretMacro([]rune(`lt1+st`))
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	var total string
+	shared.Do(func(i *Interpreter) error {
+		total = i.Register('t').Peek().String()
+		return nil
+	})
+	if total != `400` {
+		t.Fatalf(`expected every run to add 1 to register t; found %s`, total)
+	}
+	if printed := out.String(); printed != strings.Repeat(`ab`, 100) {
+		t.Fatalf(`expected what's printed not to be mixed up; found %q`, printed)
+	}
+}
@@ -0,0 +1,23 @@
+package godc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// trace writes the command that's just been run, and the stack it
+// left, to the error output, indented by how many macros deep it is.
+// Each command is traced once, even if it runs a macro.
+func (i *Interpreter) trace() {
+	if i.traced {
+		return
+	}
+	i.traced = true
+	top := `empty`
+	if v := i.Stack.Peek(); v != nil {
+		top = describeValue(v, i.OutputRadix, i.Precision)
+	}
+	i.out.Flush() // keep the trace in step with what's printed
+	fmt.Fprintf(i.errorOutput, "%s%q %s: depth %d, top %s\n",
+		strings.Repeat(`  `, i.commandDepth), i.command, i.operationName(), i.Stack.Len(), top)
+}
@@ -0,0 +1,47 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	interpreter := NewInterpreter()
+	trace := new(strings.Builder)
+	interpreter.SetOutput(new(strings.Builder))
+	interpreter.SetErrorOutput(trace)
+	expect := func(src, expected string) {
+		trace.Reset()
+		interpreter.Stack.Clear()
+		if _, err := interpreter.EvalString(src); err != nil {
+			t.Fatalf(`could not run %q: %v`, src, err)
+		}
+		if actual := trace.String(); actual != expected {
+			t.Fatalf("expected %q to trace\n%s\nfound\n%s", src, expected, actual)
+		}
+	}
+
+	expect(`1 2+`, ``)
+	interpreter.Trace = true
+	expect(`12 3+`, `'1' number: depth 1, top 12 (number)
+'3' number: depth 2, top 3 (number)
+'+' add: depth 1, top 15 (number)
+`)
+	expect(`[2[x]sb 3*]x`, `'[' string: depth 1, top [2[x]sb 3*] (string)
+'x' execute: depth 0, top empty
+  '2' number: depth 1, top 2 (number)
+  '[' string: depth 2, top [x] (string)
+  's' store: depth 1, top 2 (number)
+  '3' number: depth 2, top 3 (number)
+  '*' multiply: depth 1, top 6 (number)
+`)
+	expect(`[[4]x]x 6 9{gcd}`, `'[' string: depth 1, top [[4]x] (string)
+'x' execute: depth 0, top empty
+  '[' string: depth 1, top [4] (string)
+  'x' execute: depth 0, top empty
+  '4' number: depth 1, top 4 (number)
+'6' number: depth 2, top 6 (number)
+'9' number: depth 3, top 9 (number)
+'{' {gcd}: depth 2, top 3 (number)
+`)
+}
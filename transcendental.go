@@ -0,0 +1,212 @@
+package godc
+
+import (
+	"math"
+	"math/big"
+)
+
+// The functions in this file work in fixed point: a number x is
+// represented by the big.Int x * unit, where unit is a power of ten
+// comfortably larger than the precision the caller needs.
+
+// guardDigits are carried past the requested scale so that rounding
+// errors in the series below don't reach the digits that are kept.
+const guardDigits = 10
+
+func decimalUnit(digits int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(digits), nil)
+}
+
+// toFixed converts an exact rational to fixed point, truncating.
+func toFixed(x *big.Rat, unit *big.Int) *big.Int {
+	f := new(big.Int).Mul(x.Num(), unit)
+	return f.Quo(f, x.Denom())
+}
+
+func mulFixed(a, b, unit *big.Int) *big.Int {
+	p := new(big.Int).Mul(a, b)
+	return p.Quo(p, unit)
+}
+
+// log10Estimate is a rough base-10 logarithm of a positive rational,
+// good enough to decide how many digits a result will need.
+func log10Estimate(x *big.Rat) float64 {
+	f, _ := x.Float64()
+	if f > 0 && !math.IsInf(f, 0) {
+		return math.Log10(f)
+	}
+	bits := x.Num().BitLen() - x.Denom().BitLen()
+	return float64(bits) * math.Log10(2)
+}
+
+// atanhFixed sums the series atanh(z) = z + z³/3 + z⁵/5 + ..., which
+// converges quickly for small |z|.
+func atanhFixed(z, unit *big.Int) *big.Int {
+	sum := new(big.Int).Set(z)
+	z2 := mulFixed(z, z, unit)
+	term := new(big.Int).Set(z)
+	t := new(big.Int)
+	for n := int64(3); ; n += 2 {
+		term = mulFixed(term, z2, unit)
+		t.Quo(term, big.NewInt(n))
+		if t.Sign() == 0 {
+			return sum
+		}
+		sum.Add(sum, t)
+	}
+}
+
+// ln2Fixed computes ln(2) = 2 atanh(1/3).
+func ln2Fixed(unit *big.Int) *big.Int {
+	third := new(big.Int).Quo(unit, big.NewInt(3))
+	return new(big.Int).Lsh(atanhFixed(third, unit), 1)
+}
+
+// lnFixed computes the natural logarithm of a positive rational. It
+// divides x by a power of two, y = x / 2^k, so that y is close to 1,
+// then uses ln(x) = k ln(2) + 2 atanh((y-1)/(y+1)).
+func lnFixed(x *big.Rat, unit *big.Int) *big.Int {
+	k := int64(x.Num().BitLen() - x.Denom().BitLen())
+	y := new(big.Rat).Set(x)
+	if k > 0 {
+		y.Quo(y, new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(k))))
+	} else if k < 0 {
+		y.Mul(y, new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(-k))))
+	}
+	one := big.NewRat(1, 1)
+	z := new(big.Rat).Quo(
+		new(big.Rat).Sub(y, one),
+		new(big.Rat).Add(y, one),
+	)
+	ln := new(big.Int).Lsh(atanhFixed(toFixed(z, unit), unit), 1)
+	if k != 0 {
+		ln.Add(ln, new(big.Int).Mul(ln2Fixed(unit), big.NewInt(k)))
+	}
+	return ln
+}
+
+// expFixed computes e^x. It halves x until it's small, sums the Taylor
+// series, then squares the result back up. Negative arguments use
+// e^-x = 1 / e^x.
+func expFixed(x, unit *big.Int) *big.Int {
+	if x.Sign() < 0 {
+		pos := expFixed(new(big.Int).Neg(x), unit)
+		r := new(big.Int).Mul(unit, unit)
+		return r.Quo(r, pos)
+	}
+	half := new(big.Int).Rsh(unit, 1)
+	r := new(big.Int).Set(x)
+	halvings := 0
+	for r.Cmp(half) > 0 {
+		r.Rsh(r, 1)
+		halvings++
+	}
+	sum := new(big.Int).Set(unit)
+	term := new(big.Int).Set(unit)
+	for n := int64(1); ; n++ {
+		term = mulFixed(term, r, unit)
+		term.Quo(term, big.NewInt(n))
+		if term.Sign() == 0 {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for ; halvings > 0; halvings-- {
+		sum = mulFixed(sum, sum, unit)
+	}
+	return sum
+}
+
+// powRat computes x^y for a positive x and any rational y as
+// e^(y ln x), accurate to about scale fractional digits. The result
+// carries a few extra digits, so the caller should reduce it to scale.
+func powRat(x, y *big.Rat, scale int64) *big.Rat {
+	yf, _ := y.Float64()
+	magnitude := yf * log10Estimate(x)
+	digits := scale + guardDigits + int64(len(new(big.Int).Quo(y.Num(), y.Denom()).Text(10)))
+	if magnitude > 0 {
+		digits += int64(math.Ceil(magnitude))
+	}
+	unit := decimalUnit(digits)
+	exponent := new(big.Int).Mul(lnFixed(x, unit), y.Num())
+	exponent.Quo(exponent, y.Denom())
+	return new(big.Rat).SetFrac(expFixed(exponent, unit), unit)
+}
+
+// lnRat computes the natural logarithm of a positive rational,
+// accurate to about scale fractional digits. Like powRat, the result
+// carries a few extra digits.
+func lnRat(x *big.Rat, scale int64) *big.Rat {
+	unit := decimalUnit(scale + guardDigits)
+	return new(big.Rat).SetFrac(lnFixed(x, unit), unit)
+}
+
+// expRat computes e^x, accurate to about scale fractional digits.
+// Large results need more digits before the point, so the working
+// precision grows with x.
+func expRat(x *big.Rat, scale int64) *big.Rat {
+	digits := scale + guardDigits
+	if xf, _ := x.Float64(); xf > 0 {
+		digits += int64(math.Ceil(xf * math.Log10E))
+	}
+	unit := decimalUnit(digits)
+	return new(big.Rat).SetFrac(expFixed(toFixed(x, unit), unit), unit)
+}
+
+// acotFixed sums the series acot(x) = 1/x - 1/3x³ + 1/5x⁵ - ... for a
+// whole number x > 1.
+func acotFixed(x int64, unit *big.Int) *big.Int {
+	x2 := big.NewInt(x * x)
+	power := new(big.Int).Quo(unit, big.NewInt(x))
+	sum := new(big.Int).Set(power)
+	t := new(big.Int)
+	for n := int64(3); ; n += 2 {
+		power.Quo(power, x2)
+		t.Quo(power, big.NewInt(n))
+		if t.Sign() == 0 {
+			return sum
+		}
+		if n%4 == 3 {
+			sum.Sub(sum, t)
+		} else {
+			sum.Add(sum, t)
+		}
+	}
+}
+
+// piFixed computes pi with Machin's formula,
+// pi = 16 acot(5) - 4 acot(239).
+func piFixed(unit *big.Int) *big.Int {
+	pi := new(big.Int).Mul(acotFixed(5, unit), big.NewInt(16))
+	return pi.Sub(pi, new(big.Int).Mul(acotFixed(239, unit), big.NewInt(4)))
+}
+
+// eFixed computes e by summing 1/n!.
+func eFixed(unit *big.Int) *big.Int {
+	sum := new(big.Int).Set(unit)
+	term := new(big.Int).Set(unit)
+	for n := int64(1); term.Sign() != 0; n++ {
+		term.Quo(term, big.NewInt(n))
+		sum.Add(sum, term)
+	}
+	return sum
+}
+
+// constant caches a mathematical constant, so that it's only
+// recomputed when it's needed to more digits than before.
+type constant struct {
+	compute func(unit *big.Int) *big.Int
+	digits  int64
+	value   *big.Rat
+}
+
+// at returns the constant accurate to at least scale digits. It
+// carries a few extra digits, so the caller should reduce it to scale.
+func (c *constant) at(scale int64) *big.Rat {
+	if c.value == nil || c.digits < scale+guardDigits {
+		c.digits = scale + guardDigits
+		unit := decimalUnit(c.digits)
+		c.value = new(big.Rat).SetFrac(c.compute(unit), unit)
+	}
+	return new(big.Rat).Set(c.value)
+}
@@ -0,0 +1,61 @@
+package godc
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTranscendentalFunctions(t *testing.T) {
+	unit := decimalUnit(30)
+	fixed := func(str string) *big.Int {
+		r, ok := new(big.Rat).SetString(str)
+		if !ok {
+			t.Fatalf(`could not parse %q`, str)
+		}
+		return toFixed(r, unit)
+	}
+	// Callers carry guard digits, so allow for errors in the last few.
+	near := func(name string, expected, actual *big.Int) {
+		diff := new(big.Int).Sub(expected, actual)
+		if diff.Abs(diff).Cmp(big.NewInt(10000)) > 0 {
+			t.Fatalf(`expected %s to be %v; was %v`, name, expected, actual)
+		}
+	}
+
+	t.Run(`ln`, func(t *testing.T) {
+		near(`ln 2`, fixed(`0.693147180559945309417232121458`), lnFixed(big.NewRat(2, 1), unit))
+		near(`ln 10`, fixed(`2.302585092994045684017991454684`), lnFixed(big.NewRat(10, 1), unit))
+		near(`ln 0.1`, fixed(`-2.302585092994045684017991454684`), lnFixed(big.NewRat(1, 10), unit))
+		near(`ln 1`, fixed(`0`), lnFixed(big.NewRat(1, 1), unit))
+	})
+
+	t.Run(`exp`, func(t *testing.T) {
+		near(`e^1`, fixed(`2.718281828459045235360287471352`), expFixed(fixed(`1`), unit))
+		near(`e^-1`, fixed(`0.367879441171442321595523770161`), expFixed(fixed(`-1`), unit))
+		near(`e^0`, fixed(`1`), expFixed(fixed(`0`), unit))
+		near(`e^2`, fixed(`7.389056098930650227230427460575`), expFixed(fixed(`2`), unit))
+	})
+
+	t.Run(`constants`, func(t *testing.T) {
+		near(`pi`, fixed(`3.141592653589793238462643383279`), piFixed(unit))
+		near(`e`, fixed(`2.718281828459045235360287471352`), eFixed(unit))
+	})
+}
+
+func TestConstantCache(t *testing.T) {
+	calls := 0
+	c := &constant{compute: func(unit *big.Int) *big.Int {
+		calls++
+		return piFixed(unit)
+	}}
+	c.at(20)
+	c.at(10)
+	c.at(20)
+	if calls != 1 {
+		t.Fatalf(`expected the constant to be computed once; was computed %d times`, calls)
+	}
+	c.at(30)
+	if calls != 2 {
+		t.Fatalf(`expected the constant to be recomputed for more digits; was computed %d times`, calls)
+	}
+}
@@ -0,0 +1,94 @@
+package godc
+
+import (
+	"math/big"
+)
+
+// unit describes how to convert a measurement into its dimension's
+// base unit: base = value * factor + offset. The factors are exact,
+// so conversions don't pick up any rounding error.
+type unit struct {
+	name   string
+	factor *big.Rat
+	offset *big.Rat
+}
+
+func linearUnit(name string, num, denom int64) unit {
+	return unit{name: name, factor: big.NewRat(num, denom), offset: new(big.Rat)}
+}
+
+// lengthUnits are based on the meter.
+var lengthUnits = []unit{
+	linearUnit(`m`, 1, 1),
+	linearUnit(`km`, 1000, 1),
+	linearUnit(`cm`, 1, 100),
+	linearUnit(`mm`, 1, 1000),
+	linearUnit(`in`, 254, 10000),
+	linearUnit(`ft`, 3048, 10000),
+	linearUnit(`yd`, 9144, 10000),
+	linearUnit(`mi`, 1609344, 1000),
+	linearUnit(`nmi`, 1852, 1),
+}
+
+// massUnits are based on the kilogram.
+var massUnits = []unit{
+	linearUnit(`kg`, 1, 1),
+	linearUnit(`g`, 1, 1000),
+	linearUnit(`mg`, 1, 1000000),
+	linearUnit(`t`, 1000, 1),
+	linearUnit(`lb`, 45359237, 100000000),
+	linearUnit(`oz`, 45359237, 1600000000),
+	linearUnit(`st`, 14*45359237, 100000000),
+}
+
+// temperatureUnits are based on the kelvin.
+var temperatureUnits = []unit{
+	linearUnit(`K`, 1, 1),
+	{name: `C`, factor: big.NewRat(1, 1), offset: big.NewRat(27315, 100)},
+	{name: `F`, factor: big.NewRat(5, 9), offset: big.NewRat(45967, 180)},
+	linearUnit(`R`, 5, 9),
+}
+
+// durationUnits are based on the second.
+var durationUnits = []unit{
+	linearUnit(`s`, 1, 1),
+	linearUnit(`ns`, 1, 1000000000),
+	linearUnit(`us`, 1, 1000000),
+	linearUnit(`ms`, 1, 1000),
+	linearUnit(`min`, 60, 1),
+	linearUnit(`h`, 3600, 1),
+	linearUnit(`d`, 86400, 1),
+	linearUnit(`wk`, 604800, 1),
+}
+
+// makeConversion returns an operation that converts the top of the
+// stack from one unit to another of the same dimension.
+func makeConversion(from, to unit) Operation {
+	return makeUnaryOperation(func(_ *Interpreter, val *Value) ([]*Value, error) {
+		if err := ensureNumeric(val); err != nil {
+			return nil, err
+		}
+		base := new(big.Rat).Mul(val.numval, from.factor)
+		base.Add(base, from.offset)
+		base.Sub(base, to.offset)
+		val.numval = base.Quo(base, to.factor)
+		return []*Value{val}, nil
+	})
+}
+
+// UnitExtensions converts between units of length, mass, temperature
+// and duration. Each conversion is named {from>to}, e.g. 5{mi>km}.
+var UnitExtensions = func() ExtensionSet {
+	set := make(ExtensionSet)
+	for _, dimension := range [][]unit{lengthUnits, massUnits, temperatureUnits, durationUnits} {
+		for _, from := range dimension {
+			for _, to := range dimension {
+				if from.name == to.name {
+					continue
+				}
+				set[from.name+`>`+to.name] = makeConversion(from, to)
+			}
+		}
+	}
+	return set
+}()
@@ -0,0 +1,71 @@
+package godc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnitExtensions(t *testing.T) {
+	interpreter := NewInterpreter()
+	buff := new(strings.Builder)
+	interpreter.SetOutput(buff)
+	test := func(str string) {
+		err := testWithInterpreter(interpreter, str)
+		if err != nil {
+			t.Fatalf(`could not set up test %q: %v`, str, err)
+		}
+	}
+
+	expect := func(values ...string) {
+		err := expectWithInterpreter(buff, values...)
+		if err != nil {
+			t.Fatalf(`test failed: %v`, err)
+		}
+		interpreter.Interpret('c')
+	}
+
+	t.Run(`length`, func(t *testing.T) {
+		test(`4k1{mi>km}`)
+		expect(`1.6093`)
+
+		test(`0k12{in>ft}`)
+		expect(`1`)
+	})
+
+	t.Run(`mass`, func(t *testing.T) {
+		test(`5k1{kg>lb}`)
+		expect(`2.20462`)
+
+		test(`0k1{st>oz}`)
+		expect(`224`)
+	})
+
+	t.Run(`temperature`, func(t *testing.T) {
+		test(`0k212{F>C}`)
+		expect(`100`)
+
+		test(`2k_40{C>F}`)
+		expect(`-40.00`)
+
+		test(`2k0{C>K}`)
+		expect(`273.15`)
+	})
+
+	t.Run(`duration`, func(t *testing.T) {
+		test(`0k1{wk>min}`)
+		expect(`10080`)
+	})
+
+	t.Run(`conversions round trip exactly`, func(t *testing.T) {
+		test(`20k1{km>mi}{mi>km}`)
+		expect(`1.00000000000000000000`)
+	})
+
+	t.Run(`unknown extension`, func(t *testing.T) {
+		err := testWithInterpreter(interpreter, `1{km>kg}`)
+		if err == nil {
+			t.Fatalf(`expected an error converting between dimensions`)
+		}
+		buff.Reset()
+	})
+}
@@ -1,8 +1,9 @@
-package main
+package godc
 
 import (
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -16,10 +17,18 @@ var ErrDivideByZero = fmt.Errorf(`divide by zero`)
 // ErrNoImaginaryNumbers is thrown if you try to take the square root of a negative number.
 var ErrNoImaginaryNumbers = fmt.Errorf(`no imaginary numbers allowed`)
 
-// ErrWholeExponentsOnly is thrown if you try to raise a number to an exponent
-// that is smaller than 1
+// ErrWholeExponentsOnly is thrown if you try to take a modular exponent
+// with an exponent that is smaller than 1
 var ErrWholeExponentsOnly = fmt.Errorf(`only whole numbers are supported as exponents`)
 
+// ErrNoInverse is thrown if you try to take the modular inverse of a
+// number that shares a factor with the modulus.
+var ErrNoInverse = fmt.Errorf(`no modular inverse exists`)
+
+// ErrLogOfNonPositive is thrown if you try to take the logarithm of
+// zero or a negative number.
+var ErrLogOfNonPositive = fmt.Errorf(`logarithms are only defined for positive numbers`)
+
 // ValueType indicates whether the value is a string or a number
 type ValueType bool
 
@@ -28,32 +37,147 @@ const (
 	VTString ValueType = true
 )
 
-// Value can be either a number, represented as an integer and a base-10 precision,
-// or a string.
+// Value can be either a number, represented as an exact rational and a base-10
+// scale (the number of fractional digits it carries), or a string.
 type Value struct {
 	numval *big.Rat
 	strval []rune
+	scale  int64
 	Type   ValueType
+	code   *macroCode // the string compiled as a macro
+}
+
+// NewValueFromInt64 returns a number Value holding x, with a scale of
+// 0.
+func NewValueFromInt64(x int64) *Value {
+	return &Value{numval: new(big.Rat).SetInt64(x)}
+}
+
+// NewValueFromBigRat returns a number Value holding a copy of x. Its
+// scale is the number of fractional digits needed to write x out
+// exactly, or 0 if it would never end.
+func NewValueFromBigRat(x *big.Rat) *Value {
+	n := &Value{numval: new(big.Rat).Set(x)}
+	n.scale = n.DecimalScale(0)
+	return n
+}
+
+// NewValueFromDecimalString reads a decimal number the way dc would,
+// so "1.50" has a scale of 2. A leading - may be used for _, as may
+// an e for an exponent. It returns ErrInvalidNumber if s isn't exactly
+// one number.
+func NewValueFromDecimalString(s string) (*Value, error) {
+	scratch := &Interpreter{Stack: new(Stack), InputRadix: 10}
+	if err := parseNumber(scratch, s); err != nil {
+		return nil, err
+	}
+	return scratch.Stack.Pop(), nil
+}
+
+// NewValueFromString returns a string Value.
+func NewValueFromString(s string) *Value {
+	return &Value{Type: VTString, strval: []rune(s)}
+}
+
+// Rat returns a copy of a number's value, or nil for a string.
+func (n *Value) Rat() *big.Rat {
+	if n.Type != VTNumber {
+		return nil
+	}
+	return new(big.Rat).Set(n.numval)
+}
+
+// Float64 returns the float64 nearest a number's value, and whether
+// the value is a number at all.
+func (n *Value) Float64() (float64, bool) {
+	if n.Type != VTNumber {
+		return 0, false
+	}
+	f, _ := n.numval.Float64()
+	return f, true
+}
+
+// IsString reports whether the value is a string.
+func (n *Value) IsString() bool {
+	return n.Type == VTString
+}
+
+// Runes returns a copy of a string's runes, or nil for a number.
+func (n *Value) Runes() []rune {
+	if n.Type != VTString {
+		return nil
+	}
+	runes := make([]rune, len(n.strval))
+	copy(runes, n.strval)
+	return runes
 }
 
+// String implements fmt.Stringer. Strings are returned as they are,
+// and numbers are written in decimal to their own scale.
+func (n *Value) String() string {
+	if n.Type == VTString {
+		return string(n.strval)
+	}
+	return n.Text(10, n.scale)
+}
+
+// Scale returns the number of fractional digits the value carries, as
+// reported by the X command. Strings have a scale of 0.
+func (n *Value) Scale() int64 {
+	if n.Type != VTNumber {
+		return 0
+	}
+	return n.scale
+}
+
+func maxScale(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minScale(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Text formats the value in the given radix with precision fractional
+// digits, truncating the rest. Radixes up to 16 use the digits 0-9 and
+// A-F. Like dc, larger radixes write each digit as a space-separated
+// decimal number, zero-padded to the width of the largest digit.
 func (n *Value) Text(radix, precision int64) string {
 	// If the value is a string, print the string
 	if n.Type == VTString {
 		return string(n.strval)
 	}
 
-	val := n.numval
+	val := new(big.Rat).Abs(n.numval)
 	strSign := ``
-	if val.Sign() < 0 {
+	if n.numval.Sign() < 0 {
 		strSign = `-`
 	}
-	val = val.Abs(val)
 	intPart := (&big.Int{}).Div(val.Num(), val.Denom())
 	fracPart := (&big.Rat{}).Sub(val, (&big.Rat{}).SetInt(intPart))
-	strVal := intPart.Text(int(radix))
+
+	writeDigit := func(b *strings.Builder, digit int64) {
+		b.WriteByte(hexDigits[digit])
+	}
+	var strVal string
+	if radix > 16 {
+		width := len(strconv.FormatInt(radix-1, 10))
+		writeDigit = func(b *strings.Builder, digit int64) {
+			fmt.Fprintf(b, ` %0*d`, width, digit)
+		}
+		strVal = bigRadixText(intPart, radix, writeDigit)
+	} else {
+		strVal = strings.ToUpper(intPart.Text(int(radix)))
+	}
 
 	if precision == 0 {
-		return strings.ToUpper(fmt.Sprintf(`%s%s`, strSign, strVal))
+		return strSign + strVal
 	}
 
 	// get the fractional part
@@ -61,17 +185,49 @@ func (n *Value) Text(radix, precision int64) string {
 	b := &strings.Builder{}
 	for p := precision; p > 0; p-- {
 		if fracPart.Sign() == 0 {
-			b.WriteRune('0')
+			writeDigit(b, 0)
 			continue
 		}
 		fracPart.Mul(fracPart, r)
 		intPart.Div(fracPart.Num(), fracPart.Denom())
 		fracPart.Sub(fracPart, (&big.Rat{}).SetInt(intPart))
-		digit := intPart.Text(int(radix))
-		b.WriteString(digit)
+		writeDigit(b, intPart.Int64())
 	}
-	strFrac := b.String()
-	return strings.ToUpper(fmt.Sprintf(`%s%s.%s`, strSign, strVal, strFrac))
+	return fmt.Sprintf(`%s%s.%s`, strSign, strVal, b.String())
+}
+
+const hexDigits = `0123456789ABCDEF`
+
+// bigRadixText writes out a non-negative integer in a radix too large
+// for big.Int.Text, most significant digit first.
+func bigRadixText(i *big.Int, radix int64, writeDigit func(*strings.Builder, int64)) string {
+	var digits []int64
+	r := big.NewInt(radix)
+	rem := new(big.Int)
+	q := new(big.Int).Set(i)
+	for q.Sign() > 0 {
+		q.QuoRem(q, r, rem)
+		digits = append(digits, rem.Int64())
+	}
+	if len(digits) == 0 {
+		digits = append(digits, 0)
+	}
+	b := &strings.Builder{}
+	for d := len(digits) - 1; d >= 0; d-- {
+		writeDigit(b, digits[d])
+	}
+	return b.String()
+}
+
+// RoundedText is like Text, but rounds the last digit printed
+// according to mode rather than truncating it.
+func (n *Value) RoundedText(radix, precision int64, mode RoundingMode) string {
+	if n.Type != VTNumber || mode == RoundTruncate {
+		return n.Text(radix, precision)
+	}
+	rounded := n.Dup()
+	rounded.numval = roundRat(n.numval, radix, precision, mode)
+	return rounded.Text(radix, precision)
 }
 
 func (n *Value) PrecisionString(precision int64) string {
@@ -116,18 +272,30 @@ func (n *Value) CrossMultiply(m *Value) (*big.Int, *big.Int, *big.Int, error) {
 	return nnum, mnum, denom, nil
 }
 
+// numberValue is a Value allocated along with its number, so making
+// one costs one allocation rather than two.
+type numberValue struct {
+	Value
+	rat big.Rat
+}
+
 // Dup makes a copy of the value
 func (n *Value) Dup() *Value {
-	dup := new(Value)
-	dup.Type = n.Type
+	var dup *Value
 	if n.numval != nil {
-		dup.numval = &big.Rat{}
-		dup.numval.Set(n.numval)
+		nv := new(numberValue)
+		dup = &nv.Value
+		dup.numval = nv.rat.Set(n.numval)
+	} else {
+		dup = new(Value)
 	}
+	dup.Type = n.Type
+	dup.scale = n.scale
 	if n.strval != nil {
 		dup.strval = make([]rune, len(n.strval))
 		copy(dup.strval, n.strval)
 	}
+	dup.code = n.code
 	return dup
 }
 
@@ -141,6 +309,7 @@ func (n *Value) Add(m *Value) error {
 		return ErrNotANumber
 	}
 	n.numval.Add(n.numval, m.numval)
+	n.scale = maxScale(n.scale, m.scale)
 	return nil
 }
 
@@ -154,6 +323,7 @@ func (n *Value) Subtract(m *Value) error {
 		return ErrNotANumber
 	}
 	n.numval.Sub(n.numval, m.numval)
+	n.scale = maxScale(n.scale, m.scale)
 	return nil
 }
 
@@ -169,6 +339,7 @@ func (n *Value) Multiply(m *Value) error {
 		return ErrNotANumber
 	}
 	n.numval.Mul(n.numval, m.numval)
+	n.scale += m.scale
 	return nil
 }
 
@@ -186,8 +357,8 @@ func (n *Value) Divide(m *Value) error {
 	if m.numval.Sign() == 0 {
 		return ErrDivideByZero
 	}
-	// Do the math
-	n.numval.Mul(n.numval, (&big.Rat{}).Inv(m.numval))
+	n.numval.Quo(n.numval, m.numval)
+	n.scale = maxScale(n.scale, m.scale)
 	return nil
 }
 
@@ -199,6 +370,7 @@ func (n *Value) IntVal() error {
 	}
 	ival := (&big.Int{}).Div(n.numval.Num(), n.numval.Denom())
 	n.numval.SetInt(ival)
+	n.scale = 0
 	return nil
 }
 
@@ -229,45 +401,107 @@ func (n *Value) IsInt() bool {
 	return n.numval.IsInt()
 }
 
-// QutotientRemainder divides n by m (or m into n) and returns
-// an integer quotient and the modulo.
+// DecimalScale returns the number of fractional decimal digits needed
+// to write the number out exactly, or precision if it would never end,
+// like 1/3.
+func (n *Value) DecimalScale(precision int64) int64 {
+	if n.Type != VTNumber {
+		return 0
+	}
+	denom := new(big.Int).Set(n.numval.Denom())
+	var twos, fives int64
+	for denom.Bit(0) == 0 {
+		denom.Rsh(denom, 1)
+		twos++
+	}
+	five, q, r := big.NewInt(5), new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(denom, five, r)
+		if r.Sign() != 0 {
+			break
+		}
+		denom.Set(q)
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return precision
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
+
+// Length returns what dc's Z command reports: the number of characters
+// in a string, or the number of significant decimal digits in a
+// number, not counting the sign, the radix point, or leading zeros, but
+// at least 1. Numbers are counted to their scale, or further if that's
+// needed to write them out exactly.
+func (n *Value) Length(precision int64) int64 {
+	if n.Type == VTString {
+		return int64(len(n.strval))
+	}
+	scale := maxScale(n.scale, n.DecimalScale(precision))
+	digits := new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil)
+	digits.Mul(digits, n.numval.Num())
+	digits.Quo(digits, n.numval.Denom())
+	digits.Abs(digits)
+	if digits.Sign() == 0 {
+		return 1
+	}
+	return int64(len(digits.Text(10)))
+}
+
+// QuotientRemainder divides n by m, truncating the quotient to scale
+// fractional digits, and returns the quotient and the remainder
+// n - quotient * m, the way dc's ~ command does. The remainder's scale
+// is the larger of n's scale and scale plus m's scale.
 // Returns an error if either value is not a number, or if m == 0
-func (n *Value) QuotientRemainder(m *Value) (*Value, *Value, error) {
+func (n *Value) QuotientRemainder(m *Value, scale int64) (*Value, *Value, error) {
 	if n.Type != VTNumber {
 		return nil, nil, ErrNotANumber
 	}
 	if m.Type != VTNumber {
 		return nil, nil, ErrNotANumber
 	}
-	if m.numval.Sign() == 0 {
-		return nil, nil, ErrDivideByZero
-	}
-	q := &big.Int{}
-	r := &big.Int{}
-	if n.IsInt() && m.IsInt() {
-		ni := n.numval.Num()
-		mi := m.numval.Num()
-		q.QuoRem(ni, mi, r)
-		return &Value{numval: (&big.Rat{}).SetInt(q)},
-			&Value{numval: (&big.Rat{}).SetInt(r)},
-			nil
-	}
-	// Use integer math to drop fractional parts.
-	// x / y = q + r/y => x % y = (q, r)
-	// (x/c) / (y/c) = (q/c) + (rc/y) => (x/c) % (y/c) = ((q/c), rc)
-	x, y, c, err := n.CrossMultiply(m)
-	if err != nil {
+	quotient := n.Dup()
+	if err := quotient.Divide(m); err != nil {
 		return nil, nil, err
 	}
-	q.QuoRem(x, y, r)
-	quotient := &Value{numval: (&big.Rat{}).SetFrac(q, c)}
-	remainder := &Value{numval: (&big.Rat{}).SetInt(r.Mul(r, c))}
+	quotient.Truncate(scale)
+	product := quotient.Dup()
+	product.Multiply(m)
+	remainder := n.Dup()
+	remainder.Subtract(product)
+	remainder.Truncate(maxScale(n.scale, scale+m.scale))
 	return quotient, remainder, nil
 }
 
-// Exponent raises n to the integer value of m.
-// Fractional or negative exponents are not
-// supported.
+// Truncate discards any digits past scale fractional decimal digits,
+// the way dc does to the result of each arithmetic operation, and
+// sets the value's scale.
+func (n *Value) Truncate(scale int64) error {
+	return n.Round(scale, RoundTruncate)
+}
+
+// Round is like Truncate, but decides what to do with the discarded
+// digits according to mode.
+func (n *Value) Round(scale int64, mode RoundingMode) error {
+	if n.Type != VTNumber {
+		return ErrNotANumber
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	n.numval = roundRat(n.numval, 10, scale, mode)
+	n.scale = scale
+	return nil
+}
+
+// Exponent raises n to the integer value of m. A
+// negative exponent gives the reciprocal. Any
+// fractional part of m is discarded; use Power to
+// raise n to a fractional power.
 func (n *Value) Exponent(m *Value) error {
 	if n.Type != VTNumber {
 		return ErrNotANumber
@@ -275,17 +509,75 @@ func (n *Value) Exponent(m *Value) error {
 	if m.Type != VTNumber {
 		return ErrNotANumber
 	}
-	if m.numval.Sign() <= 0 {
-		return ErrWholeExponentsOnly
-	}
 	if err := m.IntVal(); err != nil {
 		return err
 	}
-	num := n.numval.Num()
-	denom := n.numval.Denom()
-	num.Exp(num, m.numval.Num(), nil)
-	denom.Exp(denom, m.numval.Num(), nil)
+	e := new(big.Int).Abs(m.numval.Num())
+	if m.numval.Sign() < 0 && n.numval.Sign() == 0 {
+		return ErrDivideByZero
+	}
+	num := new(big.Int).Exp(n.numval.Num(), e, nil)
+	denom := new(big.Int).Exp(n.numval.Denom(), e, nil)
+	if m.numval.Sign() < 0 {
+		num, denom = denom, num
+	}
 	n.numval.SetFrac(num, denom)
+	n.scale *= e.Int64()
+	return nil
+}
+
+// Power raises n to the power of m, which may be
+// fractional, accurate to scale fractional digits.
+// Whole exponents are handled exactly by Exponent.
+func (n *Value) Power(m *Value, scale int64) error {
+	if n.Type != VTNumber {
+		return ErrNotANumber
+	}
+	if m.Type != VTNumber {
+		return ErrNotANumber
+	}
+	if m.IsInt() {
+		return n.Exponent(m)
+	}
+	switch n.numval.Sign() {
+	case -1:
+		return ErrNoImaginaryNumbers
+	case 0:
+		if m.numval.Sign() < 0 {
+			return ErrDivideByZero
+		}
+		n.scale = scale
+		return nil
+	}
+	n.numval = powRat(n.numval, m.numval, scale)
+	n.scale = scale
+	return nil
+}
+
+// Ln replaces n with its natural logarithm, accurate to scale
+// fractional digits. The result carries a few extra digits, so it
+// should be reduced to scale afterward.
+func (n *Value) Ln(scale int64) error {
+	if n.Type != VTNumber {
+		return ErrNotANumber
+	}
+	if n.numval.Sign() <= 0 {
+		return ErrLogOfNonPositive
+	}
+	n.numval = lnRat(n.numval, scale)
+	n.scale = scale
+	return nil
+}
+
+// Exp replaces n with e^n, accurate to scale fractional digits.
+// The result carries a few extra digits, so it should be reduced
+// to scale afterward.
+func (n *Value) Exp(scale int64) error {
+	if n.Type != VTNumber {
+		return ErrNotANumber
+	}
+	n.numval = expRat(n.numval, scale)
+	n.scale = scale
 	return nil
 }
 
@@ -317,8 +609,31 @@ func (n *Value) ModExponent(e, m *Value) error {
 	return nil
 }
 
-// Sqrt returns the square root of the number.
-func (n *Value) Sqrt() error {
+// ModInverse replaces n with its multiplicative inverse modulo m: the
+// number x between 0 and |m| for which n·x ≡ 1 (mod m). Both must be
+// whole numbers.
+func (n *Value) ModInverse(m *Value) error {
+	if n.Type != VTNumber || m.Type != VTNumber {
+		return ErrNotANumber
+	}
+	if m.numval.Sign() == 0 {
+		return ErrDivideByZero
+	}
+	modulus := new(big.Int).Abs(m.numval.Num())
+	inv := new(big.Int).ModInverse(n.numval.Num(), modulus)
+	if inv == nil {
+		return ErrNoInverse
+	}
+	n.numval.SetInt(inv)
+	n.scale = 0
+	return nil
+}
+
+// Sqrt replaces n with its square root, accurate to scale
+// fractional digits. Roots that can be written out exactly,
+// like that of 2.25, are exact. The result carries a few
+// extra digits, so it should be reduced to scale afterward.
+func (n *Value) Sqrt(scale int64) error {
 	if n.Type != VTNumber {
 		return ErrNotANumber
 	}
@@ -327,8 +642,20 @@ func (n *Value) Sqrt() error {
 	}
 	num := n.numval.Num()
 	denom := n.numval.Denom()
-	num.Sqrt(num)
-	denom.Sqrt(denom)
-	n.numval.SetFrac(num, denom)
+	numRoot := new(big.Int).Sqrt(num)
+	denomRoot := new(big.Int).Sqrt(denom)
+	n.scale = scale
+	if new(big.Int).Mul(numRoot, numRoot).Cmp(num) == 0 && new(big.Int).Mul(denomRoot, denomRoot).Cmp(denom) == 0 {
+		n.numval.SetFrac(numRoot, denomRoot)
+		return nil
+	}
+	// sqrt(x) * unit = sqrt(x * unit²), and big.Int.Sqrt finds the
+	// integer part of that by Newton's method.
+	unit := decimalUnit(scale + guardDigits)
+	root := new(big.Int).Mul(num, unit)
+	root.Mul(root, unit)
+	root.Quo(root, denom)
+	root.Sqrt(root)
+	n.numval.SetFrac(root, unit)
 	return nil
 }
@@ -1,6 +1,7 @@
-package main
+package godc
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 )
@@ -35,9 +36,9 @@ func TestValueString(t *testing.T) {
 }
 
 func TestValueText(t *testing.T) {
-	test := func(num, denom, precision int64, radix uint8, expected string) {
+	test := func(num, denom, precision, radix int64, expected string) {
 		val := newValue(num, denom)
-		actual := val.Text(int64(radix), precision)
+		actual := val.Text(radix, precision)
 		if actual != expected {
 			t.Fatalf(`expected %d / %d radix %d, precision %d to be %q; was %q`, num, denom, radix, precision, expected, actual)
 		}
@@ -65,6 +66,15 @@ func TestValueText(t *testing.T) {
 		test(640625, 10000, 4, 16, `40.1000`) // 0x40.1 = 64.0625
 		test(3, 10, 1, 16, `0.4`)             // 0x0.48 = 0.3 Values are truncated.
 	})
+
+	t.Run(`radixes above 16`, func(t *testing.T) {
+		test(12345, 1, 0, 100, ` 01 23 45`)
+		test(-12345, 1, 0, 100, `- 01 23 45`)
+		test(20, 1, 0, 17, ` 01 03`)
+		test(0, 1, 0, 1000, ` 000`)
+		test(21, 2, 2, 20, ` 10. 10 00`)
+		test(1<<40, 1, 0, 1<<20, ` 0000001 0000000 0000000`)
+	})
 }
 
 func TestAdd(t *testing.T) {
@@ -213,3 +223,202 @@ func TestValueDup(t *testing.T) {
 		}
 	})
 }
+
+func TestValueLength(t *testing.T) {
+	test := func(num, denom, precision, expected int64) {
+		val := newValue(num, denom)
+		if actual := val.Length(precision); actual != expected {
+			t.Fatalf(`expected length of %d / %d at precision %d to be %d; was %d`, num, denom, precision, expected, actual)
+		}
+	}
+
+	test(0, 1, 0, 1)
+	test(5, 1, 0, 1)
+	test(-123, 1, 0, 3)
+	test(1234, 100, 0, 4) // 12.34
+	test(5, 100, 0, 1)    // 0.05
+	test(-1, 8, 0, 3)     // -0.125
+	test(1, 3, 5, 5)      // 0.33333
+	test(100, 3, 2, 4)    // 33.33
+
+	str := &Value{Type: VTString, strval: []rune(`héllo`)}
+	if actual := str.Length(10); actual != 5 {
+		t.Fatalf(`expected string length 5; was %d`, actual)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	test := func(num, denom, scale int64, expected string) {
+		val := newValue(num, denom)
+		val.Truncate(scale)
+		if actual := val.PrecisionString(scale); actual != expected {
+			t.Fatalf(`expected %d / %d truncated to %d digits to be %s; was %s`, num, denom, scale, expected, actual)
+		}
+		if actual := val.Scale(); actual != scale {
+			t.Fatalf(`expected scale %d; was %d`, scale, actual)
+		}
+	}
+	test(1, 3, 2, `0.33`)
+	test(2, 3, 2, `0.66`)
+	test(-2, 3, 2, `-0.66`)
+	test(12345, 100, 1, `123.4`)
+	test(12345, 100, 0, `123`)
+}
+
+func TestQuotientRemainder(t *testing.T) {
+	test := func(n, m *Value, scale int64, quotient, remainder string) {
+		q, r, err := n.QuotientRemainder(m, scale)
+		if err != nil {
+			t.Fatalf(`unexpected error: %v`, err)
+		}
+		if actual := q.PrecisionString(q.Scale()); actual != quotient {
+			t.Fatalf(`expected quotient %s; was %s`, quotient, actual)
+		}
+		if actual := r.PrecisionString(r.Scale()); actual != remainder {
+			t.Fatalf(`expected remainder %s; was %s`, remainder, actual)
+		}
+	}
+	test(newValue(365, 1), newValue(7, 1), 0, `52`, `1`)
+	test(newValue(-365, 1), newValue(7, 1), 0, `-52`, `-1`)
+	sevenAndAHalf := &Value{numval: big.NewRat(75, 10), scale: 1}
+	test(sevenAndAHalf, newValue(2, 1), 0, `3`, `1.5`)
+	test(sevenAndAHalf, newValue(2, 1), 1, `3.7`, `0.1`)
+
+	_, _, err := newValue(1, 1).QuotientRemainder(newValue(0, 1), 0)
+	if err != ErrDivideByZero {
+		t.Fatalf(`expected divide by zero error: received %v`, err)
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	test := func(num, denom, scale int64, expected string) {
+		val := newValue(num, denom)
+		if err := val.Sqrt(scale); err != nil {
+			t.Fatalf(`unexpected error: %v`, err)
+		}
+		if actual := val.PrecisionString(scale); actual != expected {
+			t.Fatalf(`expected sqrt(%d / %d) to %d digits to be %s; was %s`, num, denom, scale, expected, actual)
+		}
+	}
+	test(256, 1, 0, `16`)
+	test(225, 100, 2, `1.50`)
+	test(2, 1, 10, `1.4142135623`)
+	test(1, 2, 10, `0.7071067811`)
+	test(10, 1, 15, `3.162277660168379`)
+
+	if err := newValue(-1, 1).Sqrt(0); err != ErrNoImaginaryNumbers {
+		t.Fatalf(`expected no imaginary numbers error: received %v`, err)
+	}
+}
+
+func TestModInverse(t *testing.T) {
+	test := func(n, m int64, expected string) {
+		val := newValue(n, 1)
+		if err := val.ModInverse(newValue(m, 1)); err != nil {
+			t.Fatalf(`unexpected error: %v`, err)
+		}
+		if actual := val.PrecisionString(0); actual != expected {
+			t.Fatalf(`expected the inverse of %d mod %d to be %s; was %s`, n, m, expected, actual)
+		}
+	}
+	test(3, 11, `4`)
+	test(3, -11, `4`)
+	test(10, 17, `12`)
+
+	if err := newValue(4, 1).ModInverse(newValue(8, 1)); err != ErrNoInverse {
+		t.Fatalf(`expected no inverse error: received %v`, err)
+	}
+}
+
+func TestLnExp(t *testing.T) {
+	test := func(name string, op func(*Value, int64) error, num, denom, scale int64, expected string) {
+		val := newValue(num, denom)
+		if err := op(val, scale); err != nil {
+			t.Fatalf(`unexpected error: %v`, err)
+		}
+		if actual := val.PrecisionString(scale); actual != expected {
+			t.Fatalf(`expected %s(%d / %d) to %d digits to be %s; was %s`, name, num, denom, scale, expected, actual)
+		}
+	}
+	test(`ln`, (*Value).Ln, 2, 1, 20, `0.69314718055994530941`)
+	test(`ln`, (*Value).Ln, 1, 10, 20, `-2.30258509299404568401`)
+	test(`ln`, (*Value).Ln, 1, 1, 5, `0.00000`)
+	test(`exp`, (*Value).Exp, 1, 1, 20, `2.71828182845904523536`)
+	test(`exp`, (*Value).Exp, -1, 1, 20, `0.36787944117144232159`)
+	test(`exp`, (*Value).Exp, 10, 1, 20, `22026.46579480671651695790`)
+	test(`exp`, (*Value).Exp, 0, 1, 5, `1.00000`)
+
+	if err := newValue(0, 1).Ln(0); err != ErrLogOfNonPositive {
+		t.Fatalf(`expected a logarithm error: received %v`, err)
+	}
+	if err := newValue(-1, 1).Ln(0); err != ErrLogOfNonPositive {
+		t.Fatalf(`expected a logarithm error: received %v`, err)
+	}
+}
+
+func TestValueConstructors(t *testing.T) {
+	num := NewValueFromBigRat(big.NewRat(5, 4))
+	if num.Type != VTNumber || num.Scale() != 2 || num.String() != `1.25` {
+		t.Fatalf(`expected 5/4 to be the number 1.25 with scale 2; was %v with scale %d`, num, num.Scale())
+	}
+	if num.Rat().Cmp(big.NewRat(5, 4)) != 0 {
+		t.Fatalf(`expected Rat to return 5/4; was %v`, num.Rat())
+	}
+	num.Rat().SetInt64(0)
+	if num.String() != `1.25` {
+		t.Fatalf(`expected Rat to return a copy`)
+	}
+
+	if num := NewValueFromInt64(-42); num.Scale() != 0 || num.String() != `-42` {
+		t.Fatalf(`expected -42 with scale 0; was %v with scale %d`, num, num.Scale())
+	}
+
+	decimal := func(s, expected string, scale int64) {
+		num, err := NewValueFromDecimalString(s)
+		if err != nil {
+			t.Fatalf(`expected %q to be read; found %v`, s, err)
+		}
+		if num.String() != expected || num.Scale() != scale {
+			t.Fatalf(`expected %q to read as %s with scale %d; was %s with scale %d`, s, expected, scale, num.String(), num.Scale())
+		}
+	}
+	decimal(`1.50`, `1.50`, 2)
+	decimal(`-3`, `-3`, 0)
+	decimal(` _.25 `, `-0.25`, 2)
+	decimal(`12e2`, `1200`, 0)
+	for _, s := range []string{``, `1 2`, `abc`, `1/2`} {
+		if _, err := NewValueFromDecimalString(s); !errors.Is(err, ErrInvalidNumber) {
+			t.Fatalf(`expected %q to be rejected as ErrInvalidNumber; found %v`, s, err)
+		}
+	}
+
+	str := NewValueFromString(`hello`)
+	if str.Type != VTString || str.String() != `hello` || str.Rat() != nil {
+		t.Fatalf(`expected the string hello; was %v`, str)
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	num := NewValueFromBigRat(big.NewRat(-5, 4))
+	if f, ok := num.Float64(); !ok || f != -1.25 {
+		t.Fatalf(`expected Float64 to return -1.25; was %v, %v`, f, ok)
+	}
+	if num.IsString() || num.Runes() != nil {
+		t.Fatalf(`expected a number not to have runes`)
+	}
+	if f, ok := NewValueFromBigRat(big.NewRat(1, 3)).Float64(); !ok || f != 1.0/3 {
+		t.Fatalf(`expected Float64 to return the nearest float to 1/3; was %v`, f)
+	}
+
+	str := NewValueFromString(`héllo`)
+	if _, ok := str.Float64(); ok {
+		t.Fatalf(`expected a string not to have a float value`)
+	}
+	if !str.IsString() || string(str.Runes()) != `héllo` {
+		t.Fatalf(`expected the string héllo; was %q`, string(str.Runes()))
+	}
+	str.Runes()[0] = 'j'
+	if str.String() != `héllo` {
+		t.Fatalf(`expected Runes to return a copy`)
+	}
+}